@@ -0,0 +1,89 @@
+package document_manager
+
+import "sort"
+
+// The name under which the functions of the Kamailio core are listed.
+// The core has no README, so its functions are documented here.
+const CORE_MODULE = "core"
+
+// Holds the documentation of the functions exported by the Kamailio core.
+// Unlike the documentation of the modules it does not depend on the Kamailio source path
+// and is not removed by Reset.
+var core_functions = FunctionDocumentationMap{Functions: map[string]FunctionDocumentation{}}
+
+func init() {
+	for _, functionDoc := range []FunctionDocumentation{
+		{Name: "setflag", Parameters: "flag", Description: "Sets a flag of the message. The flag is a number between 0 and 31 or a define.\n", Example: "setflag(FLT_ACC);\n"},
+		{Name: "resetflag", Parameters: "flag", Description: "Resets a flag of the message.\n", Example: "resetflag(FLT_ACC);\n"},
+		{Name: "isflagset", Parameters: "flag", Description: "Returns true if the flag of the message is set.\n", Example: "if (isflagset(FLT_ACC)) { ... }\n"},
+		{Name: "setbflag", Parameters: "flag [, branch]", Description: "Sets a flag of a branch, of the first branch if no branch is given.\n", Example: "setbflag(FLB_NATB);\n"},
+		{Name: "resetbflag", Parameters: "flag [, branch]", Description: "Resets a flag of a branch, of the first branch if no branch is given.\n", Example: "resetbflag(FLB_NATB);\n"},
+		{Name: "isbflagset", Parameters: "flag [, branch]", Description: "Returns true if the flag of the branch is set.\n", Example: "if (isbflagset(FLB_NATB)) { ... }\n"},
+		{Name: "forward", Parameters: "[destination]", Description: "Forwards the request statelessly, to the request URI if no destination is given.\n", Example: "forward(\"sip:10.0.0.10:5060\");\n"},
+		{Name: "append_branch", Parameters: "[uri [, q]]", Description: "Adds a new branch for parallel forking, with the current request URI if no URI is given.\n", Example: "append_branch(\"sip:alice@example.com\");\n"},
+		{Name: "log", Parameters: "[level,] message", Description: "Writes the message to the log, at the L_DBG level if no level is given.\n", Example: "log(1, \"request received\\n\");\n"},
+		{Name: "drop", Parameters: "", Description: "Stops the execution of the configuration and drops the message.\n", Example: "drop();\n"},
+		{Name: "force_rport", Parameters: "", Description: "Adds the rport parameter to the first Via header, so that the reply is sent to the source port.\n", Example: "force_rport();\n"},
+		{Name: "add_local_rport", Parameters: "", Description: "Adds the rport parameter to the Via header added by the server.\n", Example: "add_local_rport();\n"},
+		{Name: "force_send_socket", Parameters: "address", Description: "Sends the message from the given listening socket.\n", Example: "force_send_socket(udp:10.0.0.1:5060);\n"},
+		{Name: "force_tcp_alias", Parameters: "[port]", Description: "Adds a TCP alias for the connection of the request, on the given port or the source port.\n", Example: "force_tcp_alias();\n"},
+		{Name: "rewritehost", Parameters: "host", Description: "Replaces the host of the request URI. Also known as sethost.\n", Example: "rewritehost(\"example.com\");\n"},
+		{Name: "sethost", Parameters: "host", Description: "Replaces the host of the request URI. Also known as rewritehost.\n", Example: "sethost(\"example.com\");\n"},
+		{Name: "rewritehostport", Parameters: "hostport", Description: "Replaces the host and port of the request URI. Also known as sethostport.\n", Example: "rewritehostport(\"example.com:5080\");\n"},
+		{Name: "sethostport", Parameters: "hostport", Description: "Replaces the host and port of the request URI. Also known as rewritehostport.\n", Example: "sethostport(\"example.com:5080\");\n"},
+		{Name: "rewriteport", Parameters: "port", Description: "Replaces the port of the request URI. Also known as setport.\n", Example: "rewriteport(\"5080\");\n"},
+		{Name: "setport", Parameters: "port", Description: "Replaces the port of the request URI. Also known as rewriteport.\n", Example: "setport(\"5080\");\n"},
+		{Name: "rewriteuri", Parameters: "uri", Description: "Replaces the request URI. Also known as seturi.\n", Example: "rewriteuri(\"sip:alice@example.com\");\n"},
+		{Name: "seturi", Parameters: "uri", Description: "Replaces the request URI. Also known as rewriteuri.\n", Example: "seturi(\"sip:alice@example.com\");\n"},
+		{Name: "rewriteuser", Parameters: "user", Description: "Replaces the user of the request URI. Also known as setuser.\n", Example: "rewriteuser(\"alice\");\n"},
+		{Name: "setuser", Parameters: "user", Description: "Replaces the user of the request URI. Also known as rewriteuser.\n", Example: "setuser(\"alice\");\n"},
+		{Name: "rewriteuserpass", Parameters: "password", Description: "Replaces the password of the request URI. Also known as setuserpass.\n", Example: "rewriteuserpass(\"secret\");\n"},
+		{Name: "setuserpass", Parameters: "password", Description: "Replaces the password of the request URI. Also known as rewriteuserpass.\n", Example: "setuserpass(\"secret\");\n"},
+		{Name: "revert_uri", Parameters: "", Description: "Restores the request URI to the one of the received request.\n", Example: "revert_uri();\n"},
+		{Name: "prefix", Parameters: "prefix", Description: "Adds the prefix to the user of the request URI.\n", Example: "prefix(\"00\");\n"},
+		{Name: "strip", Parameters: "count", Description: "Removes the first characters of the user of the request URI.\n", Example: "strip(2);\n"},
+		{Name: "strip_tail", Parameters: "count", Description: "Removes the last characters of the user of the request URI.\n", Example: "strip_tail(2);\n"},
+		{Name: "set_advertised_address", Parameters: "address", Description: "Sets the address advertised in the Via and Record-Route headers of the message.\n", Example: "set_advertised_address(\"203.0.113.1\");\n"},
+		{Name: "set_advertised_port", Parameters: "port", Description: "Sets the port advertised in the Via and Record-Route headers of the message.\n", Example: "set_advertised_port(5080);\n"},
+		{Name: "set_forward_no_connect", Parameters: "", Description: "Forwards the message only if a connection to the destination is already open.\n", Example: "set_forward_no_connect();\n"},
+		{Name: "set_reply_close", Parameters: "", Description: "Closes the connection after the reply is sent.\n", Example: "set_reply_close();\n"},
+		{Name: "sleep", Parameters: "seconds", Description: "Stops the execution for the given number of seconds.\n", Example: "sleep(1);\n"},
+		{Name: "usleep", Parameters: "microseconds", Description: "Stops the execution for the given number of microseconds.\n", Example: "usleep(500000);\n"},
+	} {
+		core_functions.AddFunctionDoc(functionDoc, true)
+	}
+}
+
+// Retrieves the documentation of every module exporting a function with the given name,
+// including the Kamailio core, listed as CORE_MODULE.
+//
+// functionName: The name of the function to search for.
+// return: A map of module names to the documentation of the function in that module.
+//
+//	The map is empty if no module exports the function.
+func GetFunctionDocsByName(functionName string) map[string]FunctionDocumentation {
+	functionDocs := make(map[string]FunctionDocumentation)
+	if functionDoc, exists := core_functions.Functions[functionName]; exists {
+		functionDocs[CORE_MODULE] = functionDoc
+	}
+	moduleDocumentationMapInstance.mu.RLock()
+	defer moduleDocumentationMapInstance.mu.RUnlock()
+	for moduleName, moduleDocs := range moduleDocumentationMapInstance.ModuleDocs {
+		if functionDoc, exists := moduleDocs.Functions[moduleName].Functions[functionName]; exists {
+			functionDocs[moduleName] = functionDoc
+		}
+	}
+	return functionDocs
+}
+
+// GetCoreFunctionDocs retrieves the documentation of the functions exported by the Kamailio core.
+//
+// return: A slice of FunctionDocumentation structs, sorted by function name.
+func GetCoreFunctionDocs() []FunctionDocumentation {
+	functionDocs := make([]FunctionDocumentation, 0, len(core_functions.Functions))
+	for _, functionDoc := range core_functions.Functions {
+		functionDocs = append(functionDocs, functionDoc)
+	}
+	sort.Slice(functionDocs, func(i, j int) bool { return functionDocs[i].Name < functionDocs[j].Name })
+	return functionDocs
+}
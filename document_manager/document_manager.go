@@ -3,9 +3,12 @@ package document_manager
 import (
 	"KamaiZen/logger"
 	"KamaiZen/settings"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -30,6 +33,9 @@ const (
 var (
 	_TOC_REGX_PATTERN  *regexp.Regexp = regexp.MustCompile(`^\s*\d+\.\s*(\w+)\s*$`)
 	_FUNC_REGX_PATTERN *regexp.Regexp = regexp.MustCompile(`^\s*\d+\.\d+\.\s*(\w+)\((.*)\)\s*$`)
+	// parameters are followed by their type, e.g. 3.1. fr_timer (integer)
+	_PARAM_REGX_PATTERN   *regexp.Regexp = regexp.MustCompile(`^\s*\d+\.\d+\.\s*(\w+)\s+\((\w[\w\s|/-]*)\)\s*$`)
+	_DEFAULT_REGX_PATTERN *regexp.Regexp = regexp.MustCompile(`(?s)Default value is\s+(.+?)\.(?:\s|$)`)
 )
 
 // brief Parses the provided README content and prints the table of contents.
@@ -149,32 +155,132 @@ func extractFunctionDoc(lines []string) []FunctionDocumentation {
 	return functionDocs
 }
 
+// Parses a slice of strings representing lines of documentation
+// and extracts the documentation of the parameters of the module. It identifies parameter names,
+// types, descriptions, default values and examples from the provided lines.
+//
+// The function expects the documentation to follow a specific format:
+// - Parameter documentation starts with a line matching the pattern `_PARAM_REGX_PATTERN`.
+// - Descriptions are lines following the parameter declaration until an "Example" line is encountered.
+// - The default value is read from the "Default value is ..." sentence of the description.
+// - Examples are lines following the "Example" line. They are contained within ... lines.
+// - The documentation of a parameter ends at the next parameter, function or section.
+//
+// lines: A slice of strings where each string is a line of documentation.
+// return: A slice of ParameterDocumentation structs containing the parsed documentation details.
+func extractParameterDoc(lines []string) []ParameterDocumentation {
+	var parameterDocs []ParameterDocumentation
+	var parameterDoc ParameterDocumentation
+	var inExample bool
+	var exampleLineCount int
+	flush := func() {
+		if parameterDoc.Name != "" {
+			if match := _DEFAULT_REGX_PATTERN.FindStringSubmatch(parameterDoc.Description); match != nil {
+				parameterDoc.Default = strings.Join(strings.Fields(match[1]), " ")
+			}
+			parameterDocs = append(parameterDocs, parameterDoc)
+		}
+		parameterDoc = ParameterDocumentation{}
+		inExample = false
+		exampleLineCount = 0
+	}
+	for _, line := range lines {
+		if match := _PARAM_REGX_PATTERN.FindStringSubmatch(line); match != nil {
+			flush()
+			parameterDoc = ParameterDocumentation{Name: match[1], Type: strings.TrimSpace(match[2])}
+			continue
+		}
+		if _FUNC_REGX_PATTERN.MatchString(line) || _TOC_REGX_PATTERN.MatchString(line) {
+			flush()
+			continue
+		}
+		switch {
+		case parameterDoc.Name == "" || exampleLineCount == _EXAMPLE_BLOCK_SPECIFIER_COUNT:
+			// nothing to collect until the next parameter
+		case inExample && strings.Contains(line, _EXAMPLE_BLOCK_SPECIFIER):
+			exampleLineCount++
+		case inExample:
+			if exampleLineCount > 0 {
+				parameterDoc.Example += line + "\n"
+			}
+		case strings.Contains(line, _EXAMPLE_START):
+			inExample = true
+		default:
+			parameterDoc.Description += line + "\n"
+		}
+	}
+	flush()
+	return parameterDocs
+}
+
+// Reset removes the documentation of all modules, e.g. before it is loaded
+// again from another Kamailio source path.
+func Reset() {
+	moduleDocumentationMapInstance.mu.Lock()
+	defer moduleDocumentationMapInstance.mu.Unlock()
+	moduleDocumentationMapInstance.ModuleDocs = make(map[string]ModuleDocs)
+}
+
+// ErrNoSourcePath is returned by Initialise when no Kamailio source path is configured.
+var ErrNoSourcePath = errors.New("kamailioSourcePath is not set")
+
+// ErrNoModules is returned by Initialise when the Kamailio source path contains no modules.
+var ErrNoModules = errors.New("no modules found")
+
+// ProgressFunc is called by Initialise before each module is indexed.
+//
+// current: The number of the module, starting at 1.
+// total: The number of modules.
+// module: The name of the module.
+type ProgressFunc func(current int, total int, module string)
+
 // Initializes the document manager by reading the README files from the specified
 // Kamailio source path and extracting function documentation from them. It then adds the
 // extracted documentation to the module documentation map.
 //
 // The function expects the settings to provide a valid Kamailio source path.
 //
+// ctx: The context of the indexing; it stops between two modules once the context is cancelled.
 // s: An instance of settings.LSPSettings containing the configuration settings.
+// progress: Called before each module is indexed, may be nil.
 //
 // The function performs the following steps:
 // 1. Reads the directory specified by the Kamailio source path.
 // 2. Iterates over each module directory found in the path.
 // 3. Reads the README file from each module directory.
-// 4. Extracts function documentation from the README file.
+// 4. Extracts function and parameter documentation from the README file.
 // 5. Adds the extracted function documentation to the function documentation map.
-// 6. Adds the function documentation map to the module documentation map.
+// 6. Adds the function documentation map and the parameter documentation to the module documentation map.
 //
-// return: An error if there was an issue reading the directory or file.
-func Initialise(s settings.LSPSettings) error {
+// return: ErrNoSourcePath or ErrNoModules if there is nothing to index, the error of the context
+// if it was cancelled, or an error if there was an issue reading the directory.
+func Initialise(ctx context.Context, s settings.LSPSettings, progress ProgressFunc) error {
+	if s.KamailioSourcePath == "" {
+		return ErrNoSourcePath
+	}
 	path := s.KamailioSourcePath + _MODULES_PATH
-	listOfModules, err := os.ReadDir(path)
+	entries, err := os.ReadDir(path)
 	if err != nil {
 		return err
 	}
+	var listOfModules []os.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() {
+			listOfModules = append(listOfModules, entry)
+		}
+	}
+	if len(listOfModules) == 0 {
+		return fmt.Errorf("%w in %s", ErrNoModules, path)
+	}
 	// Get All Modules
 	logger.Debug("Starting to add docs for modules", listOfModules)
-	for _, module := range listOfModules {
+	for i, module := range listOfModules {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(i+1, len(listOfModules), module.Name())
+		}
 		readme, err := os.ReadFile(path + "/" + module.Name() + _READEME_FILE)
 		if err != nil {
 			logger.Error(err)
@@ -194,6 +300,10 @@ func Initialise(s settings.LSPSettings) error {
 		if err != nil {
 			logger.Error("Skipping -- Error Adding function documentation for module: ", module.Name())
 		}
+		for _, parameterDoc := range extractParameterDoc(strings.Split(string(readme), "\n")) {
+			// the table of contents lists the parameters first, the documented ones overwrite them
+			moduleDocs.AddParameterDoc(parameterDoc, true)
+		}
 		moduleDocumentationMapInstance.AddModuleDocs(module.Name(), moduleDocs, true)
 	}
 	return nil
@@ -225,6 +335,8 @@ func GetFunctionDoc(moduleName string, functionName string) string {
 //
 //	If the function is not found in any module, it returns "Function not found".
 func FindFunctionInAllModules(functionName string) string {
+	moduleDocumentationMapInstance.mu.RLock()
+	defer moduleDocumentationMapInstance.mu.RUnlock()
 	for moduleName, moduleDocs := range moduleDocumentationMapInstance.ModuleDocs {
 		if _, exists := moduleDocs.Functions[moduleName].Functions[functionName]; exists {
 			return "# Module: " + moduleName + "\n\n" + moduleDocs.GetFunctionDocAsString(moduleName, functionName)
@@ -239,6 +351,8 @@ func FindFunctionInAllModules(functionName string) string {
 // return: A slice of strings containing the names of all available modules.
 func GetAllAvailableModules() []string {
 	var modules []string
+	moduleDocumentationMapInstance.mu.RLock()
+	defer moduleDocumentationMapInstance.mu.RUnlock()
 	for moduleName := range moduleDocumentationMapInstance.ModuleDocs {
 		modules = append(modules, moduleName)
 	}
@@ -267,6 +381,8 @@ func GetAllFunctionsInModule(moduleName string) FunctionDocumentationMap {
 //	for all functions across all modules.
 func GetAllAvailableFunctionDocs() []FunctionDocumentation {
 	var functionDocs []FunctionDocumentation
+	moduleDocumentationMapInstance.mu.RLock()
+	defer moduleDocumentationMapInstance.mu.RUnlock()
 	for _, moduleDocs := range moduleDocumentationMapInstance.ModuleDocs {
 		for _, functionDoc := range moduleDocs.Functions {
 			for _, doc := range functionDoc.Functions {
@@ -276,3 +392,36 @@ func GetAllAvailableFunctionDocs() []FunctionDocumentation {
 	}
 	return functionDocs
 }
+
+// Retrieves the documentation for a specific parameter of a module.
+//
+// moduleName: The name of the module the parameter belongs to.
+// parameterName: The name of the parameter, as set with modparam.
+// return: The ParameterDocumentation struct and a boolean indicating whether the parameter was found.
+func GetParameterDoc(moduleName string, parameterName string) (ParameterDocumentation, bool) {
+	moduleDocs, exists := moduleDocumentationMapInstance.GetModuleDocs(moduleName)
+	if !exists {
+		return ParameterDocumentation{}, false
+	}
+	parameterDoc, exists := moduleDocs.Parameters[parameterName]
+	return parameterDoc, exists
+}
+
+// GetAllParametersInModule retrieves the documentation of all parameters of a specific module.
+//
+// moduleName: The name of the module to retrieve parameter documentation for.
+// return: A slice of ParameterDocumentation structs sorted by parameter name,
+//
+//	empty if the module is not found.
+func GetAllParametersInModule(moduleName string) []ParameterDocumentation {
+	moduleDocs, exists := moduleDocumentationMapInstance.GetModuleDocs(moduleName)
+	if !exists {
+		return nil
+	}
+	parameterDocs := make([]ParameterDocumentation, 0, len(moduleDocs.Parameters))
+	for _, parameterDoc := range moduleDocs.Parameters {
+		parameterDocs = append(parameterDocs, parameterDoc)
+	}
+	sort.Slice(parameterDocs, func(i, j int) bool { return parameterDocs[i].Name < parameterDocs[j].Name })
+	return parameterDocs
+}
@@ -4,6 +4,8 @@ import (
 	// "KamaiZen/logger"
 	"errors"
 	"fmt"
+	"strings"
+	"unicode"
 )
 
 // Holds a map of function names to their corresponding documentation.
@@ -57,3 +59,49 @@ type FunctionDocumentation struct {
 func (f FunctionDocumentation) String() string {
 	return fmt.Sprintf("## Function:\n\t%s\n\n## Parameters:\n\t%s\n\n## Description:\n%s\n\n## Example:\n```\n%s\n```", f.Name, f.Parameters, f.Description, f.Example)
 }
+
+// Holds a single parameter of a function, as written in the README.
+// A parameter written between square brackets is optional.
+type FunctionParameter struct {
+	Name     string // the name of the parameter.
+	Optional bool   // whether the parameter may be left out.
+}
+
+// Splits the parameters of the function into a list of names.
+// Parameters written between square brackets, at any depth, are optional, e.g. for
+// "domain, [, flags [, uri]]" domain is mandatory while flags and uri are optional.
+//
+// return: A slice of FunctionParameter structs in the order of the parameters.
+func (f FunctionDocumentation) ParameterList() []FunctionParameter {
+	var parameters []FunctionParameter
+	var name strings.Builder
+	depth, nameDepth := 0, 0
+	flush := func() {
+		if parameter := strings.TrimSpace(name.String()); parameter != "" {
+			parameters = append(parameters, FunctionParameter{Name: parameter, Optional: nameDepth > 0})
+		}
+		name.Reset()
+	}
+	for _, r := range f.Parameters {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			flush()
+		default:
+			if name.Len() == 0 && unicode.IsSpace(r) {
+				continue
+			}
+			if name.Len() == 0 {
+				nameDepth = depth
+			}
+			name.WriteRune(r)
+		}
+	}
+	flush()
+	return parameters
+}
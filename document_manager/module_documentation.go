@@ -3,11 +3,15 @@ package document_manager
 import (
 	"KamaiZen/logger"
 	"errors"
+	"sync"
 )
 
 // Holds the documentation for all modules.
 // It maps module names to their corresponding ModuleDocs structs.
+// The map is written while the documentation is loaded and read by concurrent requests,
+// so every access must hold mu.
 type moduleDocumentationMap struct {
+	mu         sync.RWMutex
 	ModuleDocs map[string]ModuleDocs
 }
 
@@ -18,12 +22,10 @@ type moduleDocumentationMap struct {
 //
 //	whether the module was found. If the module is not found, it returns an empty ModuleDocs struct and false.
 func (m *moduleDocumentationMap) GetModuleDocs(moduleName string) (ModuleDocs, bool) {
-	for key, value := range m.ModuleDocs {
-		if key == moduleName {
-			return value, true
-		}
-	}
-	return ModuleDocs{}, false
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, exists := m.ModuleDocs[moduleName]
+	return value, exists
 }
 
 // AddModuleDocs adds module documentation to the module documentation map.
@@ -35,6 +37,8 @@ func (m *moduleDocumentationMap) GetModuleDocs(moduleName string) (ModuleDocs, b
 // overwrite: A boolean indicating whether to overwrite existing documentation if it exists.
 // return: An error if the module documentation already exists and overwrite is false.
 func (m *moduleDocumentationMap) AddModuleDocs(moduleName string, moduleDocs ModuleDocs, overwrite bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, exists := m.ModuleDocs[moduleName]; exists && !overwrite {
 		return errors.New("Module already exists")
 	}
@@ -43,8 +47,11 @@ func (m *moduleDocumentationMap) AddModuleDocs(moduleName string, moduleDocs Mod
 	return nil
 }
 
+// Holds the documentation of a module: its functions, keyed by the module name,
+// and its parameters, keyed by the parameter name.
 type ModuleDocs struct {
-	Functions map[string]FunctionDocumentationMap
+	Functions  map[string]FunctionDocumentationMap
+	Parameters map[string]ParameterDocumentation
 }
 
 // AddFunctionDoc adds function documentation to the specified module in the ModuleDocs.
@@ -67,6 +74,21 @@ func (m *ModuleDocs) AddFunctionDoc(moduleName string, functionDocs FunctionDocu
 	return nil
 }
 
+// AddParameterDoc adds parameter documentation to the ModuleDocs.
+// If the parameter documentation already exists and overwrite is set to false, it returns an error.
+// If overwrite is set to true, it overwrites the existing parameter documentation.
+//
+// parameterDoc: The ParameterDocumentation struct containing the parameter documentation to add.
+// overwrite: A boolean indicating whether to overwrite existing documentation if it exists.
+// return: An error if the parameter documentation already exists and overwrite is false.
+func (m *ModuleDocs) AddParameterDoc(parameterDoc ParameterDocumentation, overwrite bool) error {
+	if _, exists := m.Parameters[parameterDoc.Name]; exists && !overwrite {
+		return errors.New("Parameter Documentation already exists")
+	}
+	m.Parameters[parameterDoc.Name] = parameterDoc
+	return nil
+}
+
 // GetFunctionDocAsString retrieves the documentation for a specific function within a specified module
 // and returns it as a string.
 //
@@ -77,9 +99,12 @@ func (m *ModuleDocs) GetFunctionDocAsString(moduleName string, functionName stri
 	return m.Functions[moduleName].Functions[functionName].String()
 }
 
-// newModuleDocs initializes and returns a new ModuleDocs instance with an empty Functions map.
+// newModuleDocs initializes and returns a new ModuleDocs instance with empty Functions and Parameters maps.
 //
-// return: A new ModuleDocs instance with an initialized Functions map.
+// return: A new ModuleDocs instance with initialized Functions and Parameters maps.
 func newModuleDocs() ModuleDocs {
-	return ModuleDocs{Functions: make(map[string]FunctionDocumentationMap)}
+	return ModuleDocs{
+		Functions:  make(map[string]FunctionDocumentationMap),
+		Parameters: make(map[string]ParameterDocumentation),
+	}
 }
@@ -0,0 +1,36 @@
+package document_manager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Holds the documentation details for a parameter of a module, set with modparam.
+// It includes the parameter's name, type, default value, description, and example.
+type ParameterDocumentation struct {
+	Name        string // the name of the parameter.
+	Type        string // the type of the parameter, e.g. integer or string.
+	Default     string // the default value of the parameter, as written in the README.
+	Description string // a description of what the parameter does.
+	Example     string // an example modparam line setting the parameter.
+}
+
+// Returns a formatted string representation of the parameter documentation.
+// It includes the parameter name, type, default value, description, and example in a structured format.
+//
+// A string containing the formatted parameter documentation.
+func (p ParameterDocumentation) String() string {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "## Parameter:\n\t%s", p.Name)
+	if p.Type != "" {
+		fmt.Fprintf(&doc, " (%s)", p.Type)
+	}
+	if p.Default != "" {
+		fmt.Fprintf(&doc, "\n\n## Default:\n\t%s", p.Default)
+	}
+	fmt.Fprintf(&doc, "\n\n## Description:\n%s", p.Description)
+	if p.Example != "" {
+		fmt.Fprintf(&doc, "\n\n## Example:\n```\n%s\n```", p.Example)
+	}
+	return doc.String()
+}
@@ -1,6 +1,10 @@
 package kamailio_cfg
 
-import "KamaiZen/logger"
+import (
+	"KamaiZen/logger"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
 
 // Analyzer is a struct that holds the components necessary for analyzing Kamailio configurations.
 // It contains a builder for constructing the AST and a reference to the root AST node.
@@ -28,6 +32,21 @@ func (a *Analyzer) Build(content []byte) {
 	a.ast = a.builder.BuildAST(content)
 }
 
+// Edit applies a change to the analyzer's parse tree ahead of the next Build.
+// Only the edited regions are reparsed when Build is called.
+//
+// Parameters:
+//
+//	edit sitter.EditInput - The byte and point ranges of the change.
+func (a *Analyzer) Edit(edit sitter.EditInput) {
+	a.builder.parser.Edit(edit)
+}
+
+// Reset discards the analyzer's parse tree so that the next Build parses from scratch.
+func (a *Analyzer) Reset() {
+	a.builder.parser.Reset()
+}
+
 // GetAST returns the root AST (Abstract Syntax Tree) node that was built by the analyzer.
 //
 // Returns:
@@ -4,6 +4,7 @@ import (
 	"KamaiZen/logger"
 	"KamaiZen/lsp"
 	"KamaiZen/settings"
+	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
 )
@@ -342,6 +343,58 @@ func (d *DiagnosticVisitor) addInvalidAssignmentExpressionErrors(node *ASTNode,
 	d.diagnostics = append(d.diagnostics, diagnostics...)
 }
 
+// AddTransformationErrors identifies and collects diagnostics for the transformations applied to pseudo-variables
+// in the given AST node. It uses a query executor to find the transformations, including those the parser does not know.
+// Warnings are created for unknown transformations and errors for transformations called with a wrong number of arguments.
+// Pseudo-variables written inside strings are not parsed, so their transformations are not checked.
+//
+// Parameters:
+//
+//	node *ASTNode - The AST node to be checked for transformations.
+//	a *Analyzer - The analyzer used to get the parser and language information.
+//	source_code []byte - The source code of the document.
+func (d *DiagnosticVisitor) AddTransformationErrors(node *ASTNode, a *Analyzer, source_code []byte) {
+	var diagnostics []lsp.Diagnostic
+	var calls []TransformationCall
+	// the transformations the parser does not know are left in ERROR nodes
+	for _, query := range []string{_TRANSFORMATION_QUERY, _ERROR_QUERY} {
+		qe, err := NewQueryExecutor(query, node.Node, a.GetParser().language)
+		if err != nil {
+			logger.Error("Error creating query: ", err)
+			return
+		}
+		for {
+			match, ok := qe.NextMatch()
+			if !ok {
+				break
+			}
+			for _, capture := range match.Captures {
+				if call, found := TransformationCallOf(capture.Node, source_code); found {
+					calls = append(calls, call)
+				}
+			}
+		}
+	}
+	for _, call := range calls {
+		start, end := call.Node.StartPoint(), call.Node.EndPoint()
+		class, _, _ := strings.Cut(call.Name, ".")
+		transformation, found := Transformations[call.Name]
+		switch {
+		case !found && TransformationClasses[class] == "":
+			diagnostics = append(diagnostics,
+				createDiagnostic("Unknown transformation class "+class, start, end, lsp.WARNING))
+		case !found:
+			diagnostics = append(diagnostics,
+				createDiagnostic("Unknown transformation "+call.Name, start, end, lsp.WARNING))
+		default:
+			if message := transformation.Check(call); message != "" {
+				diagnostics = append(diagnostics, createDiagnostic(message, start, end, lsp.ERROR))
+			}
+		}
+	}
+	d.diagnostics = append(d.diagnostics, diagnostics...)
+}
+
 // GetQueryDiagnostics collects various diagnostics for the given AST node.
 // It checks for invalid expressions, deprecated comments, unreachable code, and syntax errors,
 // and adds the corresponding diagnostics to the DiagnosticVisitor.
@@ -357,7 +410,7 @@ func (d *DiagnosticVisitor) GetQueryDiagnostics(node *ASTNode, a *Analyzer) {
 	d.addInvalidAssignmentExpressionErrors(node, a)
 	d.addUnreachableCodeWarnings(node, a)
 	// d.addSyntaxErrors(node, a) // TODO: enable after the false errors are fixed
-	if settings.GlobalSettings.DeprecatedCommentHints {
+	if settings.GetSettings().DeprecatedCommentHints {
 		d.addDeprecatedCommentHints(node, a)
 	}
 }
@@ -58,8 +58,32 @@ func (p *Parser) GetLanguage() *sitter.Language {
 	return p.language
 }
 
+// Edit records a change to the source code on the current parse tree.
+// The edited tree is kept and handed to tree-sitter on the next call to Parse,
+// so unchanged subtrees are reused instead of reparsing the whole document.
+//
+// Parameters:
+//
+//	edit sitter.EditInput - The byte and point ranges of the change.
+func (p *Parser) Edit(edit sitter.EditInput) {
+	if p.tree == nil {
+		return
+	}
+	p.tree.Edit(edit)
+	p.oldTree = p.tree
+}
+
+// Reset discards the current parse tree so that the next call to Parse
+// starts from scratch. It must be called when the source code is replaced
+// without a matching Edit.
+func (p *Parser) Reset() {
+	p.tree = nil
+	p.oldTree = nil
+}
+
 // Parse parses the given source code and constructs the Abstract Syntax Tree (AST).
-// It updates the current and previous parse trees accordingly.
+// If the current tree has been edited, it is used as the old tree and only the
+// changed regions are reparsed.
 //
 // Parameters:
 //
@@ -74,17 +98,12 @@ func (p *Parser) Parse(sourceCode []byte) *sitter.Node {
 		return nil
 	}
 	tree, err := p.parser.ParseCtx(context.Background(), p.oldTree, sourceCode)
+	p.oldTree = nil
 	if err != nil {
 		logger.Error("Error parsing the source code: ", err)
-		p.oldTree = nil
 		return nil
 	}
-	if p.tree == nil {
-		p.tree = tree
-	} else {
-		p.oldTree = p.tree
-		p.tree = tree
-	}
+	p.tree = tree
 	n := p.tree.RootNode()
 	return n
 }
@@ -0,0 +1,29 @@
+package kamailio_cfg_test
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"testing"
+)
+
+func TestIncrementalParseMatchesFullParse(t *testing.T) {
+	text := "debug=3\nrequest_route {\n\troute(A);\n}\nroute[A] {\n\texit;\n}\n"
+	changes := []lsp.TextDocumentContentChangeEvent{
+		{Range: &lsp.Range{Start: lsp.Position{Line: 2, Character: 7}, End: lsp.Position{Line: 2, Character: 8}}, Text: "RELAY"},
+		{Range: &lsp.Range{Start: lsp.Position{Line: 4, Character: 6}, End: lsp.Position{Line: 4, Character: 7}}, Text: "RELAY"},
+		{Range: &lsp.Range{Start: lsp.Position{Line: 5, Character: 6}, End: lsp.Position{Line: 5, Character: 6}}, Text: "\n\tt_relay();"},
+	}
+
+	incremental := kamailio_cfg.NewParser()
+	incremental.Parse([]byte(text))
+	for _, change := range changes {
+		incremental.Edit(change.Edit(text))
+		text = change.Apply(text)
+	}
+	actual := incremental.Parse([]byte(text)).String()
+
+	expected := kamailio_cfg.NewParser().Parse([]byte(text)).String()
+	if actual != expected {
+		t.Fatalf("Expected: %s,\ngot: %s", expected, actual)
+	}
+}
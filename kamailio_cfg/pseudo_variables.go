@@ -0,0 +1,198 @@
+package kamailio_cfg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PseudoVariable describes a class of pseudo-variables, e.g. $ru or $avp(name).
+// Keys lists the names a class such as $snd(key) takes as argument, with their description;
+// classes whose argument is free, such as $avp(name), have no keys.
+type PseudoVariable struct {
+	Name        string
+	Argument    string
+	Description string
+	Writable    bool
+	Module      string
+	Keys        map[string]string
+}
+
+// PseudoVariables holds the pseudo-variables of Kamailio, keyed by their name without the $.
+var PseudoVariables = map[string]PseudoVariable{}
+
+func init() {
+	for _, variable := range []PseudoVariable{
+		{Name: "ru", Description: "The URI of the request (R-URI).", Writable: true, Module: "pv"},
+		{Name: "rU", Description: "The username of the request URI.", Writable: true, Module: "pv"},
+		{Name: "rd", Description: "The domain of the request URI.", Writable: true, Module: "pv"},
+		{Name: "rp", Description: "The port of the request URI.", Writable: true, Module: "pv"},
+		{Name: "rP", Description: "The transport protocol of the request URI.", Module: "pv"},
+		{Name: "rz", Description: "The scheme of the request URI.", Module: "pv"},
+		{Name: "ou", Description: "The request URI of the received request, before any change.", Module: "pv"},
+		{Name: "oU", Description: "The username of the original request URI.", Module: "pv"},
+		{Name: "od", Description: "The domain of the original request URI.", Module: "pv"},
+		{Name: "op", Description: "The port of the original request URI.", Module: "pv"},
+		{Name: "du", Description: "The destination URI, where the request is sent (outbound proxy).", Writable: true, Module: "pv"},
+		{Name: "dd", Description: "The domain of the destination URI.", Writable: true, Module: "pv"},
+		{Name: "dp", Description: "The port of the destination URI.", Writable: true, Module: "pv"},
+		{Name: "dP", Description: "The transport protocol of the destination URI.", Writable: true, Module: "pv"},
+		{Name: "fu", Description: "The URI of the From header.", Writable: true, Module: "pv"},
+		{Name: "fU", Description: "The username of the From URI.", Writable: true, Module: "pv"},
+		{Name: "fd", Description: "The domain of the From URI.", Writable: true, Module: "pv"},
+		{Name: "fn", Description: "The display name of the From header.", Writable: true, Module: "pv"},
+		{Name: "ft", Description: "The tag of the From header.", Module: "pv"},
+		{Name: "tu", Description: "The URI of the To header.", Writable: true, Module: "pv"},
+		{Name: "tU", Description: "The username of the To URI.", Writable: true, Module: "pv"},
+		{Name: "td", Description: "The domain of the To URI.", Writable: true, Module: "pv"},
+		{Name: "tn", Description: "The display name of the To header.", Writable: true, Module: "pv"},
+		{Name: "tt", Description: "The tag of the To header.", Module: "pv"},
+		{Name: "ci", Description: "The body of the Call-ID header.", Module: "pv"},
+		{Name: "cs", Description: "The sequence number of the CSeq header.", Module: "pv"},
+		{Name: "ct", Description: "The body of the Contact header.", Module: "pv"},
+		{Name: "rm", Description: "The method of the request, or of the CSeq header in a reply.", Module: "pv"},
+		{Name: "rs", Description: "The status code of a reply.", Writable: true, Module: "pv"},
+		{Name: "rr", Description: "The reason phrase of a reply.", Writable: true, Module: "pv"},
+		{Name: "rb", Description: "The body of the message.", Writable: true, Module: "pv"},
+		{Name: "rv", Description: "The SIP version of the message.", Module: "pv"},
+		{Name: "ua", Description: "The body of the User-Agent header.", Module: "pv"},
+		{Name: "ml", Description: "The length of the message.", Module: "pv"},
+		{Name: "mb", Description: "The whole message buffer.", Module: "pv"},
+		{Name: "mi", Description: "The internal ID of the message.", Module: "pv"},
+		{Name: "si", Description: "The IP address the message was received from.", Module: "pv"},
+		{Name: "sp", Description: "The port the message was received from.", Module: "pv"},
+		{Name: "Ri", Description: "The local IP address the message was received on.", Module: "pv"},
+		{Name: "Rp", Description: "The local port the message was received on.", Module: "pv"},
+		{Name: "pr", Description: "The transport protocol the message was received over, e.g. udp or tls.", Module: "pv"},
+		{Name: "fs", Description: "The socket the request is forced to be sent from, as proto:ip:port.", Writable: true, Module: "pv"},
+		{Name: "br", Description: "The URI of the first branch.", Writable: true, Module: "pv"},
+		{Name: "bR", Description: "The URIs of all the branches.", Module: "pv"},
+		{Name: "bf", Description: "The flags of the first branch, as a number.", Writable: true, Module: "pv"},
+		{Name: "mf", Description: "The flags of the message, as a number.", Writable: true, Module: "pv"},
+		{Name: "sf", Description: "The script flags, as a number.", Writable: true, Module: "pv"},
+		{Name: "ai", Description: "The URI of the P-Asserted-Identity header.", Module: "pv"},
+		{Name: "pu", Description: "The URI of the P-Preferred-Identity header.", Module: "pv"},
+		{Name: "au", Description: "The username of the Authorization or Proxy-Authorization header.", Module: "pv"},
+		{Name: "ar", Description: "The realm of the Authorization or Proxy-Authorization header.", Module: "pv"},
+		{Name: "rc", Description: "The return code of the last function called, also $retcode.", Module: "core"},
+		{Name: "retcode", Description: "The return code of the last function called, also $rc.", Module: "core"},
+		{Name: "null", Description: "The null value, to reset a variable or compare with.", Writable: true, Module: "pv"},
+		{Name: "Ts", Description: "The current time, as a Unix timestamp, cached for the processing of the message.", Module: "pv"},
+		{Name: "Tf", Description: "The current time, formatted, cached for the processing of the message.", Module: "pv"},
+		{Name: "avp", Argument: "name", Description: "An attribute-value pair, kept with the transaction.", Writable: true, Module: "pv"},
+		{Name: "var", Argument: "name", Description: "A script variable, local to the process.", Writable: true, Module: "pv"},
+		{Name: "xavp", Argument: "name", Description: "An extended AVP, holding a list of named values.", Writable: true, Module: "pv"},
+		{Name: "shv", Argument: "name", Description: "A shared variable, visible to all the processes.", Writable: true, Module: "pv"},
+		{Name: "hdr", Argument: "name", Description: "The body of the first header with the given name.", Module: "pv"},
+		{Name: "hdrc", Argument: "name", Description: "The number of headers with the given name.", Module: "pv"},
+		{Name: "sht", Argument: "table=>key", Description: "An item of a hash table.", Writable: true, Module: "htable"},
+		{Name: "dlg_var", Argument: "name", Description: "A variable stored with the dialog.", Writable: true, Module: "dialog"},
+		{Name: "snd", Argument: "key", Description: "The destination of a message being sent, in onsend_route.", Module: "pv", Keys: map[string]string{
+			"ip":     "The IP address of the destination.",
+			"af":     "The address family of the destination.",
+			"port":   "The port of the destination.",
+			"proto":  "The transport protocol of the destination.",
+			"sproto": "The transport protocol of the destination, as a string.",
+			"buf":    "The buffer of the message being sent.",
+			"len":    "The length of the message being sent.",
+			"sip":    "The local IP address the message is sent from.",
+			"sport":  "The local port the message is sent from.",
+		}},
+		{Name: "rcv", Argument: "key", Description: "The received data, in event_route[core:msg-received].", Module: "pv", Keys: map[string]string{
+			"buf":     "The received buffer.",
+			"len":     "The length of the received buffer.",
+			"proto":   "The transport protocol it was received over.",
+			"sproto":  "The transport protocol it was received over, as a string.",
+			"srcip":   "The IP address it was received from.",
+			"srcport": "The port it was received from.",
+			"rcvip":   "The local IP address it was received on.",
+			"rcvport": "The local port it was received on.",
+			"af":      "The address family it was received over.",
+		}},
+		{Name: "TV", Argument: "key", Description: "The time of day, with microseconds.", Module: "pv", Keys: map[string]string{
+			"s":  "The seconds, cached for the processing of the message.",
+			"u":  "The microseconds, cached for the processing of the message.",
+			"sn": "The seconds, not cached.",
+			"un": "The microseconds, not cached.",
+			"Sn": "The seconds and microseconds as sec.usec, not cached.",
+		}},
+		{Name: "time", Argument: "key", Description: "A part of the broken-down local time.", Module: "pv", Keys: map[string]string{
+			"sec":   "The seconds, 0 to 60.",
+			"min":   "The minutes, 0 to 59.",
+			"hour":  "The hours, 0 to 23.",
+			"mday":  "The day of the month, 1 to 31.",
+			"mon":   "The month, 1 to 12.",
+			"year":  "The year.",
+			"wday":  "The day of the week, 1 to 7, Sunday first.",
+			"yday":  "The day of the year, 1 to 366.",
+			"isdst": "Whether daylight saving time is in effect.",
+		}},
+	} {
+		PseudoVariables[variable.Name] = variable
+	}
+}
+
+// LookupPseudoVariable returns the description of a pseudo-variable written as text,
+// e.g. $ru, $(ru{uri.user}), $avp(s:caller) or $snd(ip).
+//
+// Parameters:
+//
+//	text string - The pseudo-variable, as written in the document.
+//
+// Returns:
+//
+//	PseudoVariable - The description of its class.
+//	string - The key it is written with, e.g. ip for $snd(ip), empty if its class has no keys.
+//	bool - False if the pseudo-variable is not known.
+func LookupPseudoVariable(text string) (PseudoVariable, string, bool) {
+	name := strings.TrimPrefix(strings.TrimPrefix(text, "$"), "(")
+	end := strings.IndexAny(name, "({[)")
+	argument := ""
+	if end >= 0 {
+		if name[end] == '(' {
+			argument = name[end+1:]
+			if close := strings.IndexByte(argument, ')'); close >= 0 {
+				argument = argument[:close]
+			}
+		}
+		name = name[:end]
+	}
+	variable, found := PseudoVariables[name]
+	if !found {
+		return PseudoVariable{}, "", false
+	}
+	if _, known := variable.Keys[argument]; !known {
+		argument = ""
+	}
+	return variable, argument, true
+}
+
+// String returns the documentation of the pseudo-variable, written in markdown.
+//
+// Returns:
+//
+//	string - The name, module, access, description and keys of the pseudo-variable.
+func (p PseudoVariable) String() string {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "## Pseudo-variable:\n\t$%s", p.Name)
+	if p.Argument != "" {
+		fmt.Fprintf(&doc, "(%s)", p.Argument)
+	}
+	access := "read-only"
+	if p.Writable {
+		access = "read/write"
+	}
+	fmt.Fprintf(&doc, "\n\n## Module:\n\t%s\n\n## Access:\n\t%s\n\n## Description:\n%s", p.Module, access, p.Description)
+	if len(p.Keys) > 0 {
+		keys := make([]string, 0, len(p.Keys))
+		for key := range p.Keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		doc.WriteString("\n\n## Keys:\n")
+		for _, key := range keys {
+			fmt.Fprintf(&doc, "- %s: %s\n", key, p.Keys[key])
+		}
+	}
+	return doc.String()
+}
@@ -15,6 +15,7 @@ const (
 	_EXPRESSION_QUERY            = "(expression) @expression_statement"
 	_ASSINGMENT_QUERY            = "(assignment_expression) @assignment_expression"
 	_ASSINGMENT_EXPRESSION_QUERY = "(statement (expression (assignment_expression))) @assignment_expression"
+	_TRANSFORMATION_QUERY        = "(transformation) @transformation"
 )
 
 // QueryExecutor is a struct that encapsulates the execution of tree-sitter queries.
@@ -0,0 +1,355 @@
+package kamailio_cfg
+
+import (
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// SymbolKind is the kind of a name declared in a configuration file.
+// Routes use the keyword of their block, e.g. "route" or "failure_route",
+// since routes of different kinds may share a name.
+type SymbolKind string
+
+const (
+	SYMBOL_KIND_ROUTE  SymbolKind = "route"
+	SYMBOL_KIND_DEFINE SymbolKind = "define"
+	SYMBOL_KIND_AVP    SymbolKind = "avp"
+	SYMBOL_KIND_VAR    SymbolKind = "var"
+	SYMBOL_KIND_XAVP   SymbolKind = "xavp"
+	SYMBOL_KIND_HTABLE SymbolKind = "htable"
+)
+
+// RouteCallbacks maps the functions that take the name of a route as a string
+// to the kind of that route.
+var RouteCallbacks = map[string]SymbolKind{
+	"t_on_failure": "failure_route",
+	"t_on_branch":  "branch_route",
+	"t_on_reply":   "onreply_route",
+}
+
+// pseudoVariableKinds maps the pseudo-variable classes whose names are symbols to their kind.
+var pseudoVariableKinds = map[string]SymbolKind{
+	"avp":  SYMBOL_KIND_AVP,
+	"var":  SYMBOL_KIND_VAR,
+	"xavp": SYMBOL_KIND_XAVP,
+	"sht":  SYMBOL_KIND_HTABLE,
+}
+
+// pseudoVariablePattern matches the pseudo-variables written inside strings, e.g. in xlog("$var(x)").
+// The grammar does not parse them, so their names are found by the pattern.
+var pseudoVariablePattern = regexp.MustCompile(`\$\(?(avp|var|xavp|sht)\((?:[is]:)?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Symbol is an occurrence of a name in a configuration file,
+// either where the name is declared or where it is used.
+// Routes, defines and htables declared with modparam have declarations;
+// variables are never declared, every occurrence is a use.
+type Symbol struct {
+	Kind        SymbolKind
+	Name        string
+	Start       sitter.Point // The start of the name, quotes excluded.
+	End         sitter.Point // The end of the name, quotes excluded.
+	Declaration bool
+}
+
+// Is reports whether both symbols are occurrences of the same name.
+//
+// Parameters:
+//
+//	other Symbol - The symbol to compare with.
+//
+// Returns:
+//
+//	bool - True if the symbols have the same kind and name.
+func (s Symbol) Is(other Symbol) bool {
+	return s.Kind == other.Kind && s.Name == other.Name
+}
+
+// IsRoute reports whether the symbol is the name of a route, where it is declared or called.
+//
+// Returns:
+//
+//	bool - True for every kind of route, false for defines and variables.
+func (s Symbol) IsRoute() bool {
+	switch s.Kind {
+	case SYMBOL_KIND_DEFINE, SYMBOL_KIND_AVP, SYMBOL_KIND_VAR, SYMBOL_KIND_XAVP, SYMBOL_KIND_HTABLE:
+		return false
+	}
+	return true
+}
+
+// Contains reports whether the point is within the name of the symbol, its end included.
+//
+// Parameters:
+//
+//	point sitter.Point - The point, with its column in bytes.
+//
+// Returns:
+//
+//	bool - True if the point is within the name.
+func (s Symbol) Contains(point sitter.Point) bool {
+	return !pointBefore(point, s.Start) && !pointBefore(s.End, point)
+}
+
+// pointBefore reports whether a comes before b.
+func pointBefore(a sitter.Point, b sitter.Point) bool {
+	return a.Row < b.Row || (a.Row == b.Row && a.Column < b.Column)
+}
+
+// SymbolAt returns the symbol at the given point.
+//
+// Parameters:
+//
+//	root *sitter.Node - The root node of the document.
+//	point sitter.Point - The point, with its column in bytes.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	Symbol - The symbol.
+//	bool - False if there is no symbol at the point.
+func SymbolAt(root *sitter.Node, point sitter.Point, source_code []byte) (Symbol, bool) {
+	if root == nil {
+		return Symbol{}, false
+	}
+	node := root.NamedDescendantForPointRange(point, point)
+	candidates := Symbols(node, source_code)
+	if node.Parent() != nil {
+		// a special_name is part of its route_name
+		candidates = append(candidates, symbolsOf(node.Parent(), source_code)...)
+	}
+	for _, symbol := range candidates {
+		if symbol.Contains(point) {
+			return symbol, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Symbols returns every symbol of the node and its descendants, in the order they appear.
+//
+// Parameters:
+//
+//	root *sitter.Node - The node, usually the root node of the document.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	[]Symbol - The declarations and uses of the names.
+func Symbols(root *sitter.Node, source_code []byte) []Symbol {
+	var symbols []Symbol
+	walk(root, func(node *sitter.Node) {
+		symbols = append(symbols, symbolsOf(node, source_code)...)
+	})
+	return symbols
+}
+
+// Includes returns the names of the files included by the document,
+// as written in include_file and import_file.
+//
+// Parameters:
+//
+//	root *sitter.Node - The root node of the document.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	[]string - The names of the included files.
+func Includes(root *sitter.Node, source_code []byte) []string {
+	var files []string
+	walk(root, func(node *sitter.Node) {
+		if node.Type() != IncludeFileNodeType && node.Type() != ImportFileNodeType {
+			return
+		}
+		if name := node.ChildByFieldName("file_name"); name != nil {
+			files = append(files, strings.Trim(name.Content(source_code), `"'`))
+		}
+	})
+	return files
+}
+
+// walk calls f for the node and each of its named descendants, in document order.
+func walk(node *sitter.Node, f func(*sitter.Node)) {
+	if node == nil {
+		return
+	}
+	f(node)
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		walk(node.NamedChild(i), f)
+	}
+}
+
+// symbolsOf returns the symbols whose name is the given node.
+// Names are identifiers, route names and strings; a string may hold several pseudo-variables.
+func symbolsOf(node *sitter.Node, source_code []byte) []Symbol {
+	parent := node.Parent()
+	if parent == nil {
+		return nil
+	}
+	switch node.Type() {
+	case RouteNameNodeType:
+		if symbol, ok := routeDeclaration(parent, node, source_code); ok {
+			return []Symbol{symbol}
+		}
+	case IdentifierNodeType:
+		if symbol, ok := identifierSymbol(parent, node, source_code); ok {
+			return []Symbol{symbol}
+		}
+	case StringNodeType:
+		return stringSymbols(parent, node, source_code)
+	}
+	return nil
+}
+
+// routeDeclaration returns the name of a named routing block, e.g. route[RELAY].
+func routeDeclaration(block *sitter.Node, name *sitter.Node, source_code []byte) (Symbol, bool) {
+	if block.Type() != RoutingBlockNodeType {
+		return Symbol{}, false
+	}
+	keyword := block.ChildByFieldName("route")
+	if keyword == nil {
+		return Symbol{}, false
+	}
+	return nameOf(name, SymbolKind(keyword.Content(source_code)), true, source_code), true
+}
+
+// identifierSymbol returns the symbol an identifier is the name of.
+// Identifiers used as values may be defines; they only match a define of the same name.
+func identifierSymbol(parent *sitter.Node, identifier *sitter.Node, source_code []byte) (Symbol, bool) {
+	switch parent.Type() {
+	case RouteCallNodeType:
+		return nameOf(identifier, SYMBOL_KIND_ROUTE, false, source_code), true
+	case PreprocDefNodeType, PreprocTrydefNodeType, PreprocRedefNodeType:
+		return nameOf(identifier, SYMBOL_KIND_DEFINE, true, source_code), true
+	case PreprocIfdefNodeType, PreprocIfndefNodeType, ModparamNodeType:
+		return nameOf(identifier, SYMBOL_KIND_DEFINE, false, source_code), true
+	case ExpressionNodeType:
+		if call := parent.Parent(); call != nil && call.Type() == CallExpressionNodeType && sameNode(call.ChildByFieldName("function"), parent) {
+			return Symbol{}, false
+		}
+		return nameOf(identifier, SYMBOL_KIND_DEFINE, false, source_code), true
+	case PvarArgumentNodeType:
+		if avp := parent.Parent(); avp != nil && avp.Type() == AvpNodeType {
+			return nameOf(identifier, SYMBOL_KIND_AVP, false, source_code), true
+		}
+	case VarNodeType:
+		return nameOf(identifier, SYMBOL_KIND_VAR, false, source_code), true
+	case XavpValuesNodeType:
+		if sameNode(parent.ChildByFieldName("name"), identifier) {
+			return nameOf(identifier, SYMBOL_KIND_XAVP, false, source_code), true
+		}
+	case HtableNodeType:
+		if sameNode(parent.ChildByFieldName("htable"), identifier) {
+			return nameOf(identifier, SYMBOL_KIND_HTABLE, false, source_code), true
+		}
+	}
+	return Symbol{}, false
+}
+
+// stringSymbols returns the symbols of a string: the route passed to route("NAME") or
+// to a route callback, the htable declared by modparam("htable", "htable", "NAME=>..."),
+// or the pseudo-variables written inside it.
+func stringSymbols(parent *sitter.Node, argument *sitter.Node, source_code []byte) []Symbol {
+	switch parent.Type() {
+	case RouteCallNodeType:
+		return []Symbol{nameOf(argument, SYMBOL_KIND_ROUTE, false, source_code)}
+	case ModparamNodeType:
+		if symbol, ok := htableDeclaration(parent, argument, source_code); ok {
+			return []Symbol{symbol}
+		}
+		return nil
+	}
+	if kind, ok := routeCallback(parent, source_code); ok {
+		return []Symbol{nameOf(argument, kind, false, source_code)}
+	}
+	var symbols []Symbol
+	content := argument.Content(source_code)
+	for _, match := range pseudoVariablePattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[4], match[5]
+		symbols = append(symbols, Symbol{
+			Kind:  pseudoVariableKinds[content[match[2]:match[3]]],
+			Name:  content[start:end],
+			Start: advance(argument.StartPoint(), content[:start]),
+			End:   advance(argument.StartPoint(), content[:end]),
+		})
+	}
+	return symbols
+}
+
+// routeCallback returns the kind of route passed by name to a route callback,
+// if the expression is the first argument of one, e.g. t_on_failure("MANAGE_FAILURE").
+func routeCallback(expression *sitter.Node, source_code []byte) (SymbolKind, bool) {
+	if expression.Type() != ExpressionNodeType {
+		return "", false
+	}
+	arguments := expression.Parent()
+	if arguments == nil || arguments.Type() != ArgumentListNodeType || !sameNode(arguments.NamedChild(0), expression) {
+		return "", false
+	}
+	call := arguments.Parent()
+	if call == nil || call.Type() != CallExpressionNodeType {
+		return "", false
+	}
+	function := call.ChildByFieldName("function")
+	if function == nil {
+		return "", false
+	}
+	kind, found := RouteCallbacks[function.Content(source_code)]
+	return kind, found
+}
+
+// htableDeclaration returns the name of the htable declared by modparam("htable", "htable", "NAME=>...").
+func htableDeclaration(modparam *sitter.Node, value *sitter.Node, source_code []byte) (Symbol, bool) {
+	module := modparam.ChildByFieldName("module_name")
+	parameter := modparam.ChildByFieldName("parameter_name")
+	if module == nil || parameter == nil || !sameNode(modparam.ChildByFieldName("value"), value) ||
+		strings.Trim(module.Content(source_code), `"'`) != "htable" ||
+		strings.Trim(parameter.Content(source_code), `"'`) != "htable" {
+		return Symbol{}, false
+	}
+	symbol := nameOf(value, SYMBOL_KIND_HTABLE, true, source_code)
+	name, _, found := strings.Cut(symbol.Name, "=>")
+	if !found || name == "" {
+		return Symbol{}, false
+	}
+	symbol.Name = name
+	symbol.End = advance(symbol.Start, name)
+	return symbol, true
+}
+
+// nameOf returns a symbol holding the text and position of the node.
+// The quotes of a string are not part of the name.
+func nameOf(node *sitter.Node, kind SymbolKind, declaration bool, source_code []byte) Symbol {
+	symbol := Symbol{
+		Kind:        kind,
+		Name:        node.Content(source_code),
+		Start:       node.StartPoint(),
+		End:         node.EndPoint(),
+		Declaration: declaration,
+	}
+	if node.Type() == StringNodeType && len(symbol.Name) >= 2 && symbol.Start.Row == symbol.End.Row {
+		symbol.Name = symbol.Name[1 : len(symbol.Name)-1]
+		symbol.Start.Column++
+		symbol.End.Column--
+	}
+	return symbol
+}
+
+// sameNode reports whether both nodes are the same node of the tree.
+func sameNode(a *sitter.Node, b *sitter.Node) bool {
+	return a != nil && b != nil && a.Equal(b)
+}
+
+// advance returns the point after the text, starting at the given point.
+func advance(point sitter.Point, text string) sitter.Point {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+	}
+	return point
+}
@@ -0,0 +1,300 @@
+package kamailio_cfg
+
+import (
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Transformation describes a function of a class of transformations, e.g. s.substr in $(var(x){s.substr,0,3}).
+// The last Optional parameters can be left out. A transformation whose last parameter is a separator
+// may take a comma as that parameter, e.g. {s.select,1,,}.
+type Transformation struct {
+	Class       string
+	Name        string
+	Parameters  []string
+	Optional    int
+	Separator   bool
+	Description string
+}
+
+// TransformationClasses holds the classes of transformations, with their description.
+var TransformationClasses = map[string]string{
+	"s":        "String transformations.",
+	"uri":      "Transformations of SIP URIs.",
+	"param":    "Transformations of lists of parameters, e.g. a=1;b=2.",
+	"nameaddr": "Transformations of name-addr values, e.g. \"Alice\" <sip:alice@example.com>.",
+	"tobody":   "Transformations of the body of To and From headers.",
+	"line":     "Transformations of multi-line values.",
+	"urialias": "Transformations of the alias parameter of URIs.",
+	"sql":      "Transformations of values used in SQL queries.",
+	"msrpuri":  "Transformations of MSRP URIs.",
+	"json":     "Transformations of JSON documents, exported by the json module.",
+	"re":       "Regular expression transformations, exported by the textops module.",
+	"val":      "Transformations of the type of values.",
+	"sock":     "Transformations of socket addresses, e.g. udp:10.0.0.1:5060.",
+	"url":      "Transformations of HTTP URLs.",
+}
+
+// Transformations holds the transformations of Kamailio, keyed by their class and name, e.g. s.substr.
+var Transformations = map[string]Transformation{}
+
+func init() {
+	for _, transformation := range []struct {
+		name        string
+		parameters  string // the parameters, separated by commas, the optional ones in brackets
+		description string
+	}{
+		{"s.len", "", "The length of the string."},
+		{"s.int", "", "The string converted to an integer."},
+		{"s.md5", "", "The MD5 hash of the string."},
+		{"s.sha256", "", "The SHA-256 hash of the string."},
+		{"s.sha384", "", "The SHA-384 hash of the string."},
+		{"s.sha512", "", "The SHA-512 hash of the string."},
+		{"s.crc32", "", "The CRC32 checksum of the string."},
+		{"s.substr", "offset,length", "The part of the string starting at offset, of the given length; 0 takes the rest of the string."},
+		{"s.select", "index,separator", "The field at index of the string split by separator; negative indexes count from the end."},
+		{"s.encode.7bit", "", "The string encoded in 7 bits."},
+		{"s.decode.7bit", "", "The string decoded from 7 bits."},
+		{"s.encode.hexa", "", "The string encoded in hexadecimal."},
+		{"s.decode.hexa", "", "The string decoded from hexadecimal."},
+		{"s.encode.base58", "", "The string encoded in base58."},
+		{"s.decode.base58", "", "The string decoded from base58."},
+		{"s.encode.base64", "", "The string encoded in base64."},
+		{"s.decode.base64", "", "The string decoded from base64."},
+		{"s.encode.base64t", "", "The string encoded in base64, without trailing padding."},
+		{"s.decode.base64t", "", "The string decoded from base64, without trailing padding."},
+		{"s.encode.base64url", "", "The string encoded in base64 for URLs."},
+		{"s.decode.base64url", "", "The string decoded from base64 for URLs."},
+		{"s.encode.base64urlt", "", "The string encoded in base64 for URLs, without trailing padding."},
+		{"s.decode.base64urlt", "", "The string decoded from base64 for URLs, without trailing padding."},
+		{"s.escape.common", "", "The string with quotes, backslashes and control characters escaped."},
+		{"s.unescape.common", "", "The string with common escapes undone."},
+		{"s.escape.user", "", "The string escaped for the user part of a URI."},
+		{"s.unescape.user", "", "The string with the escapes of the user part of a URI undone."},
+		{"s.escape.param", "", "The string escaped for a parameter of a URI."},
+		{"s.unescape.param", "", "The string with the escapes of a parameter of a URI undone."},
+		{"s.escape.csv", "", "The string escaped for a CSV field."},
+		{"s.numeric", "", "The digits of the string."},
+		{"s.tolower", "", "The string in lower case."},
+		{"s.toupper", "", "The string in upper case."},
+		{"s.strip", "length", "The string without its first length characters."},
+		{"s.striptail", "length", "The string without its last length characters."},
+		{"s.stripto", "char", "The string from the first occurrence of char."},
+		{"s.prefixes", "[length]", "The comma-separated prefixes of the string, up to length characters."},
+		{"s.prefixes.quoted", "[length]", "The comma-separated quoted prefixes of the string, up to length characters."},
+		{"s.replace", "match,replacement", "The string with the occurrences of match replaced."},
+		{"s.ftime", "format", "The timestamp formatted with strftime."},
+		{"s.trim", "", "The string without leading and trailing whitespace."},
+		{"s.rtrim", "", "The string without trailing whitespace."},
+		{"s.ltrim", "", "The string without leading whitespace."},
+		{"s.rm", "match", "The string with the occurrences of match removed."},
+		{"s.rmws", "", "The string without whitespace."},
+		{"s.rmhws", "", "The string without horizontal whitespace, i.e. spaces and tabs."},
+		{"s.rmhdws", "", "The string with each run of horizontal whitespace replaced by a single space."},
+		{"s.rmhlws", "", "The string without leading and trailing horizontal whitespace."},
+		{"s.corehash", "[size]", "The hash of the string computed by the core, modulo size if given."},
+		{"s.unquote", "", "The string without its enclosing quotes."},
+		{"s.unbracket", "", "The string without its enclosing brackets."},
+		{"s.count", "char", "The number of occurrences of char in the string."},
+		{"s.before", "char", "The part of the string before the first occurrence of char."},
+		{"s.after", "char", "The part of the string after the first occurrence of char."},
+		{"s.rbefore", "char", "The part of the string before the last occurrence of char."},
+		{"s.rafter", "char", "The part of the string after the last occurrence of char."},
+		{"s.fmtlines", "length,count", "The string split in count lines of the given length."},
+		{"s.fmtlinet", "length,count", "The string split in count lines of the given length, indented with a tab."},
+		{"s.urlencode.param", "", "The string encoded for a parameter of a URL."},
+		{"s.urldecode.param", "", "The string decoded from a parameter of a URL."},
+		{"uri.user", "", "The user part of the URI."},
+		{"uri.host", "", "The host part of the URI."},
+		{"uri.passwd", "", "The password of the URI."},
+		{"uri.port", "", "The port of the URI."},
+		{"uri.params", "", "The parameters of the URI."},
+		{"uri.param", "name", "The value of the parameter of the URI with the given name."},
+		{"uri.headers", "", "The headers of the URI."},
+		{"uri.transport", "", "The transport parameter of the URI."},
+		{"uri.ttl", "", "The ttl parameter of the URI."},
+		{"uri.uparam", "", "The user parameter of the URI."},
+		{"uri.maddr", "", "The maddr parameter of the URI."},
+		{"uri.method", "", "The method parameter of the URI."},
+		{"uri.lr", "", "The lr parameter of the URI."},
+		{"uri.r2", "", "The r2 parameter of the URI."},
+		{"uri.scheme", "", "The scheme of the URI."},
+		{"uri.tosocket", "", "The URI converted to a socket, as proto:host:port."},
+		{"uri.duri", "", "The URI as a destination URI, with the host, port and transport."},
+		{"uri.saor", "", "The address of record of the URI, as sip:user@host."},
+		{"uri.suri", "", "The URI with its scheme, user, host and port only."},
+		{"param.value", "name,[delimiter]", "The value of the parameter with the given name."},
+		{"param.valueat", "index,[delimiter]", "The value of the parameter at index."},
+		{"param.in", "name,[delimiter]", "1 if there is a parameter with the given name, 0 otherwise."},
+		{"param.name", "index,[delimiter]", "The name of the parameter at index."},
+		{"param.count", "[delimiter]", "The number of parameters."},
+		{"nameaddr.name", "", "The display name."},
+		{"nameaddr.uri", "", "The URI."},
+		{"nameaddr.len", "", "The length of the value."},
+		{"tobody.uri", "", "The URI of the header."},
+		{"tobody.display", "", "The display name of the header."},
+		{"tobody.tag", "", "The tag parameter of the header."},
+		{"tobody.user", "", "The user part of the URI of the header."},
+		{"tobody.host", "", "The host part of the URI of the header."},
+		{"tobody.params", "", "The parameters of the header."},
+		{"line.count", "", "The number of lines."},
+		{"line.at", "index", "The line at index; negative indexes count from the end."},
+		{"line.sw", "prefix", "The first line starting with prefix."},
+		{"urialias.encode", "", "The address encoded as the alias parameter of a URI."},
+		{"urialias.decode", "", "The address decoded from the alias parameter of a URI."},
+		{"sql.val", "", "The value quoted for SQL, NULL if it is null."},
+		{"sql.val.int", "", "The value for SQL as an integer, 0 if it is null."},
+		{"sql.val.str", "", "The value quoted for SQL, an empty string if it is null."},
+		{"msrpuri.user", "", "The user part of the MSRP URI."},
+		{"msrpuri.host", "", "The host part of the MSRP URI."},
+		{"msrpuri.port", "", "The port of the MSRP URI."},
+		{"msrpuri.transport", "", "The transport of the MSRP URI."},
+		{"msrpuri.session", "", "The session ID of the MSRP URI."},
+		{"msrpuri.proto", "", "The protocol of the MSRP URI."},
+		{"msrpuri.params", "", "The parameters of the MSRP URI."},
+		{"msrpuri.userinfo", "", "The user info of the MSRP URI."},
+		{"json.parse", "key", "The value of the given key of the JSON document."},
+		{"re.subst", "expression", "The value with the substitution expression /regex/replacement/flags applied."},
+		{"val.json", "", "The value escaped for a JSON string."},
+		{"val.jsonqe", "", "The value escaped for a JSON string, with its double quotes escaped too."},
+		{"val.n0", "", "The value, or 0 if it is null."},
+		{"val.ne", "", "The value, or an empty string if it is null."},
+		{"sock.host", "", "The host of the socket address."},
+		{"sock.port", "", "The port of the socket address."},
+		{"sock.proto", "", "The transport protocol of the socket address."},
+		{"sock.touri", "", "The socket address converted to a SIP URI."},
+		{"url.path", "", "The path of the URL."},
+		{"url.querystring", "", "The query string of the URL."},
+	} {
+		class, name, _ := strings.Cut(transformation.name, ".")
+		t := Transformation{Class: class, Name: name, Description: transformation.description}
+		if transformation.parameters != "" {
+			for _, parameter := range strings.Split(transformation.parameters, ",") {
+				if strings.HasPrefix(parameter, "[") {
+					t.Optional++
+				}
+				t.Parameters = append(t.Parameters, strings.Trim(parameter, "[]"))
+			}
+			last := t.Parameters[len(t.Parameters)-1]
+			t.Separator = last == "separator" || last == "delimiter" || last == "char"
+		}
+		Transformations[transformation.name] = t
+	}
+}
+
+// TransformationCall is a transformation applied to a pseudo-variable, e.g. {s.substr,0,3} in $(var(x){s.substr,0,3}).
+// Node is the transformation node, or the ERROR node the parser left for a transformation it does not know.
+type TransformationCall struct {
+	Node      *sitter.Node
+	Name      string
+	Arguments []string
+}
+
+// TransformationCallOf returns the transformation applied by the node, if it is the transformation node
+// of a pseudo-variable or an ERROR node holding a transformation the parser does not know, e.g. {s.crc32}.
+//
+// Parameters:
+//
+//	node *sitter.Node - The node to look at.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	TransformationCall - The transformation, with its arguments.
+//	bool - False if the node is not a transformation.
+func TransformationCallOf(node *sitter.Node, source_code []byte) (TransformationCall, bool) {
+	parent := node.Parent()
+	switch {
+	case node.Type() == TransformationNodeType:
+	case node.Type() == ErrorNodeType && parent != nil && parent.Type() == PseudoVariableExpressionNodeType:
+	default:
+		return TransformationCall{}, false
+	}
+	text := node.Content(source_code)
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return TransformationCall{}, false
+	}
+	call := TransformationCall{Node: node, Name: text[1 : len(text)-1]}
+	if comma := strings.IndexByte(call.Name, ','); comma >= 0 {
+		call.Name, call.Arguments = call.Name[:comma], splitArguments(call.Name[comma+1:])
+	}
+	return call, true
+}
+
+// splitArguments splits the arguments of a transformation on the commas
+// that are not within the parentheses or braces of a nested pseudo-variable.
+func splitArguments(text string) []string {
+	var arguments []string
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				arguments = append(arguments, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(arguments, text[start:])
+}
+
+// Check returns what is wrong with the call of the transformation, empty if nothing is.
+//
+// Parameters:
+//
+//	call TransformationCall - The call of the transformation.
+//
+// Returns:
+//
+//	string - The problem with the number of arguments of the call, empty if there is none.
+func (t Transformation) Check(call TransformationCall) string {
+	count, most := len(call.Arguments), len(t.Parameters)
+	least := most - t.Optional
+	if t.Separator && count > most && most > 0 {
+		// the separator is a comma
+		count = most
+	}
+	switch {
+	case least == most && count != most:
+		return fmt.Sprintf("Transformation %s takes %s, got %d", call.Name, arguments(most), count)
+	case count < least || count > most:
+		return fmt.Sprintf("Transformation %s takes %d to %s, got %d", call.Name, least, arguments(most), count)
+	}
+	return ""
+}
+
+// arguments returns the number of arguments in words, e.g. 1 argument or 2 arguments.
+func arguments(count int) string {
+	if count == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", count)
+}
+
+// Signature returns the transformation as it is written, with its parameters, e.g. {s.substr,offset,length}.
+func (t Transformation) Signature() string {
+	signature := "{" + t.Class + "." + t.Name
+	for i, parameter := range t.Parameters {
+		if i >= len(t.Parameters)-t.Optional {
+			parameter = "[" + parameter + "]"
+		}
+		signature += "," + parameter
+	}
+	return signature + "}"
+}
+
+// String returns the documentation of the transformation, written in markdown.
+//
+// Returns:
+//
+//	string - The signature, class and description of the transformation.
+func (t Transformation) String() string {
+	return fmt.Sprintf("## Transformation:\n\t%s\n\n## Class:\n\t%s: %s\n\n## Description:\n%s",
+		t.Signature(), t.Class, TransformationClasses[t.Class], t.Description)
+}
@@ -21,6 +21,40 @@ const (
 	UnaryExpressionNodeType          = "unary_expression"
 	BinaryExpressionNodeType         = "binary_expression"
 	CaseStatementNodeType            = "case_statement"
+	RoutingBlockNodeType             = "routing_block"
+	PredefRouteNodeType              = "predef_route"
+	RouteCallNodeType                = "route_call"
+	RouteNameNodeType                = "route_name"
+	StringNodeType                   = "string"
+	ArgumentListNodeType             = "argument_list"
+	SpecialNameNodeType              = "special_name"
+	PreprocDefNodeType               = "preproc_def"
+	PreprocTrydefNodeType            = "preproc_trydef"
+	PreprocRedefNodeType             = "preproc_redef"
+	PreprocIfdefNodeType             = "preproc_ifdef"
+	PreprocIfndefNodeType            = "preproc_ifndef"
+	PreprocElseNodeType              = "preproc_else"
+	PreprocSubstNodeType             = "preproc_subst"
+	PreprocSubstdefNodeType          = "preproc_substdef"
+	PreprocSubstdefsNodeType         = "preproc_substdefs"
+	TopLevelItemNodeType             = "top_level_item"
+	LoadmoduleNodeType               = "loadmodule"
+	ModparamNodeType                 = "modparam"
+	PvarArgumentNodeType             = "pvar_argument"
+	AvpNodeType                      = "avp_var"
+	VarNodeType                      = "var_"
+	XavpNodeType                     = "xavp_var"
+	XavpValuesNodeType               = "xavp_values"
+	HtableNodeType                   = "htable"
+	IncludeFileNodeType              = "include_file"
+	ImportFileNodeType               = "import_file"
+	SourceFileNodeType               = "source_file"
+	CommentNodeType                  = "comment"
+	MultilineCommentNodeType         = "multiline_comment"
+	NumberLiteralNodeType            = "number_literal"
+	TransformationNodeType           = "transformation"
+	HdrNodeType                      = "hdr"
+	ErrorNodeType                    = "ERROR"
 )
 
 // UpdateTree updates the given parse tree by applying an edit operation.
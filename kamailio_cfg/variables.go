@@ -4,14 +4,8 @@ import (
 	"KamaiZen/logger"
 )
 
-// keeps track of variables declared int he program
-
-// global variables
-// gloabl variables are avps
-var globalVariables map[string]Variable
-
-// local variables
-var localVariables map[string]Variable
+// keeps track of variables declared in the program
+// global variables are avps, they are collected per document
 
 // Variable struct
 type Variable struct {
@@ -21,25 +15,22 @@ type Variable struct {
 	Identifier string
 }
 
-func InitialiseVariables() {
-	globalVariables = make(map[string]Variable)
-	localVariables = make(map[string]Variable)
-}
-
-func AddGlobalVariable(name string, value string, identifier string) {
-	globalVariables[name] = Variable{name, value, "AVP", identifier}
-}
-
-func AddLocalVariable(name string, value string, scope string, identifier string) {
-	localVariables[name] = Variable{name, value, scope, identifier}
-}
-
-func ExtractGlobalVariables(a *Analyzer, source_code []byte) {
-	// extract global variables
+// ExtractGlobalVariables collects the AVPs assigned in the document held by the analyzer.
+//
+// Parameters:
+//
+//	a *Analyzer - The analyzer holding the document's AST.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	map[string]Variable - The AVPs keyed by their full name, e.g. $avp(x).
+func ExtractGlobalVariables(a *Analyzer, source_code []byte) map[string]Variable {
+	variables := make(map[string]Variable)
 	q, err := NewQueryExecutor(_ASSINGMENT_QUERY, a.ast.Node, a.builder.parser.language)
 	if err != nil {
 		logger.Error("Error creating query executor: ", err)
-		return
+		return variables
 	}
 	for {
 		match, ok := q.NextMatch()
@@ -49,20 +40,24 @@ func ExtractGlobalVariables(a *Analyzer, source_code []byte) {
 		for _, capture := range match.Captures {
 			node := capture.Node
 			variable := node.ChildByFieldName("left")
+			if variable == nil || node.ChildByFieldName("right") == nil {
+				continue
+			}
 			if variable.Type() == "pseudo_variable" || variable.Type() == "pvar_expression" {
 				pc := variable.NamedChild(0)
 				if pc.Type() == "pseudo_content" {
 					avp := pc.NamedChild(0)
-					if avp.Type() == "avp_var" {
+					if avp != nil && avp.Type() == "avp_var" && avp.ChildByFieldName("name") != nil {
 						identifier := avp.ChildByFieldName("name").Child(0).Content(source_code)
 						avp_name := "$avp(" + identifier + ")"
 						value := node.ChildByFieldName("right").Content(source_code)
-						AddGlobalVariable(avp_name, value, identifier)
+						variables[avp_name] = Variable{avp_name, value, "AVP", identifier}
 					}
 				}
 			}
 		}
 	}
+	return variables
 }
 
 func (v *Variable) GetGlobalVariableDocs() string {
@@ -70,7 +65,3 @@ func (v *Variable) GetGlobalVariableDocs() string {
 		"### Value\n\t```\n" + "\t" + v.Value + "\n```\n" +
 		"### Scope\n\t" + v.Scope + "\n"
 }
-
-func GetGlobalVariables() map[string]Variable {
-	return globalVariables
-}
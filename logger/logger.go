@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
 // Log levels
@@ -18,26 +20,34 @@ const (
 
 // global logger
 var (
-	logger   *log.Logger
-	logLevel int
+	logger     *log.Logger
+	loggerOnce sync.Once
+	logLevel   atomic.Int32
 )
 
 // getLogger initializes the logger if it is not already initialized and returns it.
+// It is safe to call from concurrent handlers.
 func getLogger() *log.Logger {
-	if logger == nil {
+	loggerOnce.Do(func() {
 		filename := "/tmp/kamaizen.log"
 		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
 			panic(err)
 		}
 		logger = log.New(file, "[KamaiZen] ", log.Ldate|log.Ltime)
-	}
+	})
 	return logger
 }
 
 // SetLogLevel sets the current log level.
+// It may be called while other goroutines are logging.
 func SetLogLevel(level int) {
-	logLevel = level
+	logLevel.Store(int32(level))
+}
+
+// GetLogLevel returns the current log level.
+func GetLogLevel() int {
+	return int(logLevel.Load())
 }
 
 func writeLog(level string, v ...interface{}) {
@@ -51,55 +61,55 @@ func writeLog(level string, v ...interface{}) {
 
 // Info logs an info message if the current log level is INFO or lower.
 func Info(v ...interface{}) {
-	if logLevel <= INFO {
+	if GetLogLevel() <= INFO {
 		writeLog("INFO", v...)
 	}
 }
 
 // Infof logs a formatted info message if the current log level is INFO or lower.
 func Infof(format string, v ...interface{}) {
-	if logLevel <= INFO {
+	if GetLogLevel() <= INFO {
 		writeLog("INFO", fmt.Sprintf(format, v...))
 	}
 }
 
 // Debug logs a debug message if the current log level is DEBUG.
 func Debug(v ...interface{}) {
-	if logLevel <= DEBUG {
+	if GetLogLevel() <= DEBUG {
 		writeLog("DEBUG", v...)
 	}
 }
 
 // Debugf logs a formatted debug message if the current log level is DEBUG.
 func Debugf(format string, v ...interface{}) {
-	if logLevel <= DEBUG {
+	if GetLogLevel() <= DEBUG {
 		writeLog("DEBUG", fmt.Sprintf(format, v...))
 	}
 }
 
 func Warn(v ...interface{}) {
-	if logLevel <= WARN {
+	if GetLogLevel() <= WARN {
 		writeLog("WARN", v...)
 	}
 }
 
 // Warnf logs a formatted warning message if the current log level is WARN or lower.
 func Warnf(format string, v ...interface{}) {
-	if logLevel <= WARN {
+	if GetLogLevel() <= WARN {
 		writeLog("WARN", fmt.Sprintf(format, v...))
 	}
 }
 
 // Error logs an error message if the current log level is ERROR or lower.
 func Error(v ...interface{}) {
-	if logLevel <= ERROR {
+	if GetLogLevel() <= ERROR {
 		writeLog("ERROR", v...)
 	}
 }
 
 // Errorf logs a formatted error message if the current log level is ERROR or lower.
 func Errorf(format string, v ...interface{}) {
-	if logLevel <= ERROR {
+	if GetLogLevel() <= ERROR {
 		writeLog("ERROR", fmt.Sprintf(format, v...))
 	}
 }
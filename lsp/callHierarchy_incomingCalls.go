@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// CallHierarchyIncomingCallsRequest represents a request for the routing blocks that call an item.
+// It contains the request metadata and the parameters for the callHierarchy/incomingCalls request.
+type CallHierarchyIncomingCallsRequest struct {
+	Request
+	Params CallHierarchyCallsParams `json:"params"`
+}
+
+// CallHierarchyCallsParams contains the parameters for the incoming and outgoing calls requests:
+// the item returned by the PrepareCallHierarchyRequest.
+type CallHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+// CallHierarchyIncomingCall represents a routing block that calls the item.
+// FromRanges are the ranges of the calls within it.
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// CallHierarchyIncomingCallsResponse represents the response to a CallHierarchyIncomingCallsRequest.
+type CallHierarchyIncomingCallsResponse struct {
+	Response
+	Result []CallHierarchyIncomingCall `json:"result"`
+}
+
+// NewCallHierarchyIncomingCallsResponse creates and returns a new CallHierarchyIncomingCallsResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	calls []CallHierarchyIncomingCall - The routing blocks that call the item.
+//
+// Returns:
+//
+//	CallHierarchyIncomingCallsResponse - The initialized response.
+func NewCallHierarchyIncomingCallsResponse(id rpc.ID, calls []CallHierarchyIncomingCall) CallHierarchyIncomingCallsResponse {
+	if calls == nil {
+		calls = []CallHierarchyIncomingCall{}
+	}
+	return CallHierarchyIncomingCallsResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: calls,
+	}
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// CallHierarchyOutgoingCallsRequest represents a request for the routing blocks an item calls.
+// It contains the request metadata and the parameters for the callHierarchy/outgoingCalls request.
+type CallHierarchyOutgoingCallsRequest struct {
+	Request
+	Params CallHierarchyCallsParams `json:"params"`
+}
+
+// CallHierarchyOutgoingCall represents a routing block called by the item.
+// FromRanges are the ranges of the calls within the item.
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// CallHierarchyOutgoingCallsResponse represents the response to a CallHierarchyOutgoingCallsRequest.
+type CallHierarchyOutgoingCallsResponse struct {
+	Response
+	Result []CallHierarchyOutgoingCall `json:"result"`
+}
+
+// NewCallHierarchyOutgoingCallsResponse creates and returns a new CallHierarchyOutgoingCallsResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	calls []CallHierarchyOutgoingCall - The routing blocks called by the item.
+//
+// Returns:
+//
+//	CallHierarchyOutgoingCallsResponse - The initialized response.
+func NewCallHierarchyOutgoingCallsResponse(id rpc.ID, calls []CallHierarchyOutgoingCall) CallHierarchyOutgoingCallsResponse {
+	if calls == nil {
+		calls = []CallHierarchyOutgoingCall{}
+	}
+	return CallHierarchyOutgoingCallsResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: calls,
+	}
+}
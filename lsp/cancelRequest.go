@@ -0,0 +1,15 @@
+package lsp
+
+import "KamaiZen/rpc"
+
+// CancelRequestNotification represents a notification sent to cancel a request that is still in flight.
+type CancelRequestNotification struct {
+	Notification
+	Params CancelParams `json:"params"`
+}
+
+// CancelParams contains the parameters for the CancelRequestNotification.
+// It includes the ID of the request to cancel, which may be a number or a string.
+type CancelParams struct {
+	ID rpc.ID `json:"id"`
+}
@@ -0,0 +1,209 @@
+package lsp
+
+import (
+	"strings"
+	"sync"
+)
+
+// MarkupKind describes the format of a MarkupContent.
+type MarkupKind string
+
+const (
+	MARKUP_KIND_PLAINTEXT MarkupKind = "plaintext"
+	MARKUP_KIND_MARKDOWN  MarkupKind = "markdown"
+)
+
+// ClientCapabilities represents the capabilities the client sends in the initialize request.
+// Only the capabilities the server adapts to are decoded.
+type ClientCapabilities struct {
+	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+	Window       WindowClientCapabilities       `json:"window"`
+	General      GeneralClientCapabilities      `json:"general"`
+}
+
+// WorkspaceClientCapabilities represents the workspace capabilities of the client.
+type WorkspaceClientCapabilities struct {
+	Configuration          bool                          `json:"configuration"`
+	WorkspaceFolders       bool                          `json:"workspaceFolders"`
+	DidChangeConfiguration DynamicRegistrationCapability `json:"didChangeConfiguration"`
+	DidChangeWatchedFiles  DynamicRegistrationCapability `json:"didChangeWatchedFiles"`
+}
+
+// DynamicRegistrationCapability represents a capability the client may register dynamically.
+type DynamicRegistrationCapability struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+// TextDocumentClientCapabilities represents the text document capabilities of the client.
+type TextDocumentClientCapabilities struct {
+	Hover          HoverClientCapabilities          `json:"hover"`
+	Completion     CompletionClientCapabilities     `json:"completion"`
+	Rename         RenameClientCapabilities         `json:"rename"`
+	DocumentSymbol DocumentSymbolClientCapabilities `json:"documentSymbol"`
+	FoldingRange   FoldingRangeClientCapabilities   `json:"foldingRange"`
+	SignatureHelp  SignatureHelpClientCapabilities  `json:"signatureHelp"`
+}
+
+// HoverClientCapabilities represents the hover capabilities of the client.
+// ContentFormat lists the supported formats in the client's order of preference.
+type HoverClientCapabilities struct {
+	ContentFormat []MarkupKind `json:"contentFormat"`
+}
+
+// CompletionClientCapabilities represents the completion capabilities of the client.
+type CompletionClientCapabilities struct {
+	CompletionItem CompletionItemClientCapabilities `json:"completionItem"`
+}
+
+// CompletionItemClientCapabilities represents the completion item capabilities of the client.
+type CompletionItemClientCapabilities struct {
+	SnippetSupport      bool         `json:"snippetSupport"`
+	DocumentationFormat []MarkupKind `json:"documentationFormat"`
+}
+
+// RenameClientCapabilities represents the rename capabilities of the client.
+type RenameClientCapabilities struct {
+	PrepareSupport bool `json:"prepareSupport"`
+}
+
+// DocumentSymbolClientCapabilities represents the document symbol capabilities of the client.
+type DocumentSymbolClientCapabilities struct {
+	HierarchicalDocumentSymbolSupport bool `json:"hierarchicalDocumentSymbolSupport"`
+}
+
+// FoldingRangeClientCapabilities represents the folding range capabilities of the client.
+// RangeLimit is the maximum number of ranges the client wants, 0 if it has no limit.
+type FoldingRangeClientCapabilities struct {
+	RangeLimit int `json:"rangeLimit"`
+}
+
+// SignatureHelpClientCapabilities represents the signature help capabilities of the client.
+type SignatureHelpClientCapabilities struct {
+	SignatureInformation SignatureInformationClientCapabilities `json:"signatureInformation"`
+}
+
+// SignatureInformationClientCapabilities represents the capabilities of the client for the signatures it shows.
+type SignatureInformationClientCapabilities struct {
+	DocumentationFormat []MarkupKind `json:"documentationFormat"`
+}
+
+// WindowClientCapabilities represents the window capabilities of the client.
+type WindowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress"`
+}
+
+// GeneralClientCapabilities represents the general capabilities of the client.
+type GeneralClientCapabilities struct {
+	PositionEncodings []PositionEncodingKind `json:"positionEncodings"`
+}
+
+// preferredMarkup returns the first format of the list the server can produce.
+// Clients that do not send a list only have to support plain text.
+func preferredMarkup(formats []MarkupKind) MarkupKind {
+	for _, format := range formats {
+		if format == MARKUP_KIND_MARKDOWN || format == MARKUP_KIND_PLAINTEXT {
+			return format
+		}
+	}
+	return MARKUP_KIND_PLAINTEXT
+}
+
+// HoverFormat returns the format the contents of a hover are sent in.
+//
+// Returns:
+//
+//	MarkupKind - Markdown if the client prefers it, plain text otherwise.
+func (c ClientCapabilities) HoverFormat() MarkupKind {
+	return preferredMarkup(c.TextDocument.Hover.ContentFormat)
+}
+
+// CompletionDocumentationFormat returns the format the documentation of a completion item is sent in.
+//
+// Returns:
+//
+//	MarkupKind - Markdown if the client prefers it, plain text otherwise.
+func (c ClientCapabilities) CompletionDocumentationFormat() MarkupKind {
+	return preferredMarkup(c.TextDocument.Completion.CompletionItem.DocumentationFormat)
+}
+
+// SignatureDocumentationFormat returns the format the documentation of a signature is sent in.
+//
+// Returns:
+//
+//	MarkupKind - Markdown if the client prefers it, plain text otherwise.
+func (c ClientCapabilities) SignatureDocumentationFormat() MarkupKind {
+	return preferredMarkup(c.TextDocument.SignatureHelp.SignatureInformation.DocumentationFormat)
+}
+
+// SnippetSupport reports whether completion items may be sent as snippets.
+func (c ClientCapabilities) SnippetSupport() bool {
+	return c.TextDocument.Completion.CompletionItem.SnippetSupport
+}
+
+var (
+	client_mu           sync.RWMutex
+	client_capabilities ClientCapabilities
+)
+
+// SetClientCapabilities stores the capabilities of the connected client.
+// It is called once while handling the initialize request.
+//
+// Parameters:
+//
+//	capabilities ClientCapabilities - The capabilities sent by the client.
+func SetClientCapabilities(capabilities ClientCapabilities) {
+	client_mu.Lock()
+	defer client_mu.Unlock()
+	client_capabilities = capabilities
+}
+
+// GetClientCapabilities returns the capabilities of the connected client.
+//
+// Returns:
+//
+//	ClientCapabilities - The capabilities, empty before initialize.
+func GetClientCapabilities() ClientCapabilities {
+	client_mu.RLock()
+	defer client_mu.RUnlock()
+	return client_capabilities
+}
+
+// NewMarkupContent creates a MarkupContent in the given format.
+// Markdown is sent as it is; for plain text the code fences and heading markers are removed.
+//
+// Parameters:
+//
+//	format MarkupKind - The format the client asked for.
+//	value string - The content, written in markdown.
+//
+// Returns:
+//
+//	MarkupContent - The content in the given format.
+func NewMarkupContent(format MarkupKind, value string) MarkupContent {
+	if format == MARKUP_KIND_PLAINTEXT {
+		value = stripMarkdown(value)
+	}
+	return MarkupContent{Kind: format, Value: value}
+}
+
+// stripMarkdown removes the markdown the server writes itself, code fences and
+// heading markers, so the content reads well as plain text. Lines within code fences
+// are kept as they are, e.g. the #!define directives of an example.
+func stripMarkdown(value string) string {
+	lines := strings.Split(value, "\n")
+	plain := lines[:0]
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			line = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		}
+		plain = append(plain, line)
+	}
+	return strings.Join(plain, "\n")
+}
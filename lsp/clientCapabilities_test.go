@@ -0,0 +1,17 @@
+package lsp_test
+
+import (
+	"KamaiZen/lsp"
+	"testing"
+)
+
+func TestNewMarkupContentPlainText(t *testing.T) {
+	markdown := "## Parameter:\n\tdb_mode (integer)\n\n## Example:\n```\n#!define WITH_USRLOCDB\n# ## comment\nmodparam(\"usrloc\", \"db_mode\", 2)\n```\n# Module: usrloc"
+	expected := "Parameter:\n\tdb_mode (integer)\n\nExample:\n#!define WITH_USRLOCDB\n# ## comment\nmodparam(\"usrloc\", \"db_mode\", 2)\nModule: usrloc"
+	if content := lsp.NewMarkupContent(lsp.MARKUP_KIND_PLAINTEXT, markdown); content.Value != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, content.Value)
+	}
+	if content := lsp.NewMarkupContent(lsp.MARKUP_KIND_MARKDOWN, markdown); content.Value != markdown {
+		t.Fatalf("Expected the markdown unchanged, got:\n%s", content.Value)
+	}
+}
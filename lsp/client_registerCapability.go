@@ -0,0 +1,15 @@
+package lsp
+
+// RegistrationParams contains the parameters of the client/registerCapability request,
+// which the server sends to register capabilities the client supports registering dynamically.
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// Registration describes a capability to register.
+// ID identifies the registration, so that it can be unregistered.
+type Registration struct {
+	ID              string `json:"id"`
+	Method          string `json:"method"`
+	RegisterOptions any    `json:"registerOptions,omitempty"`
+}
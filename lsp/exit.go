@@ -0,0 +1,7 @@
+package lsp
+
+// ExitNotification represents a notification from the client asking the server to exit its process.
+// The server exits with code 0 if a ShutdownRequest was received before, and with code 1 otherwise.
+type ExitNotification struct {
+	Notification
+}
@@ -1,6 +1,10 @@
 package lsp
 
-import "KamaiZen/settings"
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+	"encoding/json"
+)
 
 // InitializeRequest represents a request to initialize the language server.
 // It contains the request metadata and the parameters for initialization.
@@ -10,9 +14,45 @@ type InitializeRequest struct {
 }
 
 // InitializeRequestParams contains the parameters for the InitializeRequest.
-// It includes information about the client.
+// It includes information about the client, its capabilities and the workspace it opened.
 type InitializeRequestParams struct {
-	ClientInfo ClientInfo `json:"clientInfo"`
+	ProcessID             *int               `json:"processId"`
+	ClientInfo            ClientInfo         `json:"clientInfo"`
+	RootURI               *DocumentURI       `json:"rootUri"`
+	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders"`
+	InitializationOptions json.RawMessage    `json:"initializationOptions,omitempty"`
+	Capabilities          ClientCapabilities `json:"capabilities"`
+}
+
+// WorkspaceFolder represents a folder opened in the client.
+type WorkspaceFolder struct {
+	URI  DocumentURI `json:"uri"`
+	Name string      `json:"name"`
+}
+
+// Folders returns the workspace folders of the client.
+// Clients that do not support workspace folders only send the root URI, which is used instead.
+//
+// Returns:
+//
+//	[]WorkspaceFolder - The workspace folders, empty if the client opened a single file.
+func (params InitializeRequestParams) Folders() []WorkspaceFolder {
+	if len(params.WorkspaceFolders) > 0 || params.RootURI == nil {
+		return params.WorkspaceFolders
+	}
+	return []WorkspaceFolder{{URI: *params.RootURI}}
+}
+
+// Configuration returns the kamaizen settings sent in initializationOptions.
+// The options may hold the settings directly or under a "kamaizen" section, the same
+// shape the client uses for workspace/configuration.
+//
+// Returns:
+//
+//	ConfigurationObject - The settings, empty if none were sent.
+//	bool - Whether the options held settings.
+func (params InitializeRequestParams) Configuration() (ConfigurationObject, bool) {
+	return parseConfiguration(params.InitializationOptions)
 }
 
 // ClientInfo represents information about the client making the request.
@@ -39,17 +79,45 @@ type InitializeResult struct {
 // ServerCapabilities represents the capabilities of the language server.
 // It includes various features supported by the server.
 type ServerCapabilities struct {
-	// TODO: incremental instead of full
+	PositionEncoding           PositionEncodingKind    `json:"positionEncoding,omitempty"`
 	TextDocumentSync           TextDocumentSyncOptions `json:"textDocumentSync"`
 	HoverProvider              bool                    `json:"hoverProvider"`
 	DefinitionProvider         bool                    `json:"definitionProvider"`
+	ReferencesProvider         bool                    `json:"referencesProvider"`
+	RenameProvider             any                     `json:"renameProvider,omitempty"`
+	DocumentSymbolProvider     bool                    `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider    bool                    `json:"workspaceSymbolProvider"`
+	FoldingRangeProvider       bool                    `json:"foldingRangeProvider"`
+	SemanticTokensProvider     *SemanticTokensOptions  `json:"semanticTokensProvider,omitempty"`
 	DocumentFormattingProvider bool                    `json:"documentFormattingProvider"`
-	CompletionProvider         map[string]any          `json:"completionProvider"`
+	CompletionProvider         *CompletionOptions      `json:"completionProvider,omitempty"`
 	DocumentHighlightProvider  bool                    `json:"documentHighlightProvider"`
+	SignatureHelpProvider      *SignatureHelpOptions   `json:"signatureHelpProvider,omitempty"`
+	CallHierarchyProvider      bool                    `json:"callHierarchyProvider"`
 	// TODO: Add more capabilities
 	// CodeActionProvider bool `json:"codeActionProvider"`
 }
 
+// CompletionOptions represents the completion capabilities of the server.
+type CompletionOptions struct {
+	ResolveProvider   bool     `json:"resolveProvider"`
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// NewCompletionOptions creates and returns the completion capabilities of the server,
+// which completes module and parameter names when a string is opened, functions and
+// pseudo-variables in argument lists, pseudo-variables at $ and transformations at { and .
+//
+// Returns:
+//
+//	CompletionOptions - The capabilities, with the trigger characters.
+func NewCompletionOptions() CompletionOptions {
+	return CompletionOptions{
+		ResolveProvider:   false,
+		TriggerCharacters: []string{`"`, "(", "$", "{", "."},
+	}
+}
+
 // ServerInfo represents information about the language server.
 // It includes the server's name and version.
 type ServerInfo struct {
@@ -58,36 +126,24 @@ type ServerInfo struct {
 }
 
 // NewInitializeResponse creates and returns a new InitializeResponse.
-// It initializes the response with the given ID and sets the server capabilities and information.
+// It initializes the response with the given ID, server capabilities and server information.
 //
 // Parameters:
 //
-//	id int - The ID of the response.
+//	id rpc.ID - The ID of the response.
+//	capabilities ServerCapabilities - The capabilities of the server.
 //
 // Returns:
 //
 //	InitializeResponse - The initialized response.
-func NewInitializeResponse(id int) InitializeResponse {
+func NewInitializeResponse(id rpc.ID, capabilities ServerCapabilities) InitializeResponse {
 	return InitializeResponse{
 		Response: Response{
-			RPC: "2.0",
+			RPC: settings.RPC_VERSION,
 			ID:  id,
 		},
 		Result: InitializeResult{
-			Capabilities: ServerCapabilities{
-				TextDocumentSync: TextDocumentSyncOptions{
-					OpenClose: true,
-					Change:    TEXT_DOCUMENT_SYNC_KIND_FULL,
-					// FIXME: update this to incremental
-					// Change:    TEXT_DOCUMENT_SYNC_KIND_INCREMENTAL,
-				},
-				HoverProvider:      true,
-				DefinitionProvider: true,
-				// FIXME: for now formatter isnt working properly
-				DocumentFormattingProvider: false,
-				CompletionProvider:         map[string]any{"resolveProvider": false},
-				DocumentHighlightProvider:  false,
-			},
+			Capabilities: capabilities,
 			ServerInfo: ServerInfo{
 				Name:    settings.MY_NAME,
 				Version: settings.KAMAIZEN_VERSION,
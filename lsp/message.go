@@ -1,20 +1,54 @@
 package lsp
 
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
 // Request represents a JSON-RPC request message.
 // It contains the JSON-RPC version, the request ID, and the method to be invoked.
+// The ID may be a number or a string.
 type Request struct {
 	RPC    string `json:"jsonrpc"`
-	ID     int    `json:"id"`
+	ID     rpc.ID `json:"id"`
 	Method string `json:"method"`
 }
 
+// OutgoingRequest represents a request the server sends to the client.
+type OutgoingRequest struct {
+	Request
+	Params any `json:"params,omitempty"`
+}
+
+// NewRequest creates and returns a new OutgoingRequest.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the request, unique among the requests sent by the server.
+//	method string - The method to be invoked on the client.
+//	params any - The parameters of the request.
+//
+// Returns:
+//
+//	OutgoingRequest - The initialized request.
+func NewRequest(id rpc.ID, method string, params any) OutgoingRequest {
+	return OutgoingRequest{
+		Request: Request{
+			RPC:    settings.RPC_VERSION,
+			ID:     id,
+			Method: method,
+		},
+		Params: params,
+	}
+}
+
 // Response represents a JSON-RPC response message.
-// It contains the JSON-RPC version and the response ID.
+// It contains the JSON-RPC version, the ID of the request it answers and,
+// if the request failed, the error. Successful responses embed it next to their result.
 type Response struct {
-	RPC string `json:"jsonrpc"`
-	ID  int    `json:"id,omitempty"`
-	// Result
-	// Error
+	RPC   string             `json:"jsonrpc"`
+	ID    rpc.ID             `json:"id"`
+	Error *rpc.ResponseError `json:"error,omitempty"`
 }
 
 // Notification represents a JSON-RPC notification message.
@@ -24,3 +58,21 @@ type Notification struct {
 	RPC    string `json:"jsonrpc"`
 	Method string `json:"method"`
 }
+
+// NewErrorResponse creates and returns a new Response that reports an error instead of a result.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the request that failed.
+//	err *rpc.ResponseError - The error.
+//
+// Returns:
+//
+//	Response - The initialized response.
+func NewErrorResponse(id rpc.ID, err *rpc.ResponseError) Response {
+	return Response{
+		RPC:   settings.RPC_VERSION,
+		ID:    id,
+		Error: err,
+	}
+}
@@ -0,0 +1,155 @@
+package lsp
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// PositionEncodingKind describes how the characters of a Position are counted.
+type PositionEncodingKind string
+
+const (
+	POSITION_ENCODING_UTF8  PositionEncodingKind = "utf-8"
+	POSITION_ENCODING_UTF16 PositionEncodingKind = "utf-16"
+	POSITION_ENCODING_UTF32 PositionEncodingKind = "utf-32"
+)
+
+// positionEncoding is the encoding negotiated with the client during initialize.
+// UTF-16 is the default of the specification and is used until something else is negotiated.
+var positionEncoding atomic.Value
+
+// SetPositionEncoding sets the encoding used to count the characters of positions.
+//
+// Parameters:
+//
+//	encoding PositionEncodingKind - The negotiated encoding.
+func SetPositionEncoding(encoding PositionEncodingKind) {
+	positionEncoding.Store(encoding)
+}
+
+// GetPositionEncoding returns the encoding used to count the characters of positions.
+//
+// Returns:
+//
+//	PositionEncodingKind - The negotiated encoding, UTF-16 if none was negotiated.
+func GetPositionEncoding() PositionEncodingKind {
+	if encoding, ok := positionEncoding.Load().(PositionEncodingKind); ok {
+		return encoding
+	}
+	return POSITION_ENCODING_UTF16
+}
+
+// NegotiatePositionEncoding picks the encoding to use from the ones the client supports.
+// UTF-8 is preferred since tree-sitter columns are byte offsets and need no conversion.
+//
+// Parameters:
+//
+//	supported []PositionEncodingKind - The encodings offered by the client, in its order of preference.
+//
+// Returns:
+//
+//	PositionEncodingKind - The chosen encoding, UTF-16 if the client did not offer any.
+func NegotiatePositionEncoding(supported []PositionEncodingKind) PositionEncodingKind {
+	for _, encoding := range supported {
+		if encoding == POSITION_ENCODING_UTF8 {
+			return encoding
+		}
+	}
+	for _, encoding := range supported {
+		if encoding == POSITION_ENCODING_UTF16 || encoding == POSITION_ENCODING_UTF32 {
+			return encoding
+		}
+	}
+	return POSITION_ENCODING_UTF16
+}
+
+// characterUnits returns the number of units a rune counts for in the given encoding.
+func characterUnits(r rune, size int, encoding PositionEncodingKind) int {
+	switch encoding {
+	case POSITION_ENCODING_UTF8:
+		return size
+	case POSITION_ENCODING_UTF32:
+		return 1
+	}
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// PositionOf converts a tree-sitter point, whose column is measured in bytes,
+// into a position in the negotiated encoding.
+//
+// Parameters:
+//
+//	text string - The text the point refers to.
+//	point sitter.Point - The point to convert.
+//
+// Returns:
+//
+//	Position - The position of the point.
+func PositionOf(text string, point sitter.Point) Position {
+	position := Position{Line: int(point.Row), Character: int(point.Column)}
+	encoding := GetPositionEncoding()
+	if encoding == POSITION_ENCODING_UTF8 {
+		return position
+	}
+	offset := 0
+	for line := 0; line < position.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return position
+		}
+		offset += next + 1
+	}
+	end := offset + position.Character
+	if end > len(text) {
+		end = len(text)
+	}
+	position.Character = LengthOf(text[offset:end])
+	return position
+}
+
+// LengthOf returns the length of the text in the units of the negotiated encoding.
+//
+// Parameters:
+//
+//	text string - The text to measure.
+//
+// Returns:
+//
+//	int - The number of units of the text.
+func LengthOf(text string) int {
+	encoding := GetPositionEncoding()
+	if encoding == POSITION_ENCODING_UTF8 {
+		return len(text)
+	}
+	units := 0
+	for offset := 0; offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += characterUnits(r, size, encoding)
+		offset += size
+	}
+	return units
+}
+
+// RangeOf converts the tree-sitter points of a node into a range in the negotiated encoding.
+//
+// Parameters:
+//
+//	text string - The text the points refer to.
+//	start sitter.Point - The start of the range.
+//	end sitter.Point - The end of the range.
+//
+// Returns:
+//
+//	Range - The range between the points.
+func RangeOf(text string, start sitter.Point, end sitter.Point) Range {
+	return Range{
+		Start: PositionOf(text, start),
+		End:   PositionOf(text, end),
+	}
+}
@@ -0,0 +1,70 @@
+package lsp_test
+
+import (
+	"KamaiZen/lsp"
+	"encoding/json"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+func TestPositionEncodings(t *testing.T) {
+	defer lsp.SetPositionEncoding(lsp.POSITION_ENCODING_UTF16)
+	// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 UTF-16 units
+	text := "x\n$var(é😀)=1;"
+	point := sitter.Point{Row: 1, Column: 11} // before ")"
+	tests := []struct {
+		encoding  lsp.PositionEncodingKind
+		character int
+	}{
+		{lsp.POSITION_ENCODING_UTF8, 11},
+		{lsp.POSITION_ENCODING_UTF16, 8},
+		{lsp.POSITION_ENCODING_UTF32, 7},
+	}
+	for _, test := range tests {
+		lsp.SetPositionEncoding(test.encoding)
+		position := lsp.PositionOf(text, point)
+		if position.Line != 1 || position.Character != test.character {
+			t.Fatalf("%s: Expected: 1:%d,\ngot: %d:%d", test.encoding, test.character, position.Line, position.Character)
+		}
+		if offset := lsp.OffsetAt(text, position); offset != 2+11 {
+			t.Fatalf("%s: Expected: %d,\ngot: %d", test.encoding, 2+11, offset)
+		}
+	}
+}
+
+func TestNegotiatePositionEncoding(t *testing.T) {
+	tests := []struct {
+		supported []lsp.PositionEncodingKind
+		expected  lsp.PositionEncodingKind
+	}{
+		{nil, lsp.POSITION_ENCODING_UTF16},
+		{[]lsp.PositionEncodingKind{"utf-16", "utf-8"}, lsp.POSITION_ENCODING_UTF8},
+		{[]lsp.PositionEncodingKind{"utf-32"}, lsp.POSITION_ENCODING_UTF32},
+	}
+	for _, test := range tests {
+		if actual := lsp.NegotiatePositionEncoding(test.supported); actual != test.expected {
+			t.Fatalf("Expected: %s,\ngot: %s", test.expected, actual)
+		}
+	}
+}
+
+func TestInitializationOptions(t *testing.T) {
+	for _, options := range []string{
+		`{"kamailioSourcePath":"/src/kamailio"}`,
+		`{"kamaizen":{"kamailioSourcePath":"/src/kamailio"}}`,
+	} {
+		var params lsp.InitializeRequestParams
+		if err := json.Unmarshal([]byte(`{"initializationOptions":`+options+`}`), &params); err != nil {
+			t.Fatal(err)
+		}
+		config, found := params.Configuration()
+		if !found || config.KamailioSourcePath != "/src/kamailio" {
+			t.Fatalf("Expected: /src/kamailio,\ngot: %v (%v)", config, found)
+		}
+	}
+	var params lsp.InitializeRequestParams
+	if _, found := params.Configuration(); found {
+		t.Fatal("Expected no configuration without initializationOptions")
+	}
+}
@@ -0,0 +1,38 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// ShutdownRequest represents a request from the client asking the server to shut down.
+// The server stops processing requests but does not exit until it receives an ExitNotification.
+type ShutdownRequest struct {
+	Request
+}
+
+// ShutdownResponse represents the response to a ShutdownRequest.
+// Its result is always null.
+type ShutdownResponse struct {
+	Response
+	Result any `json:"result"`
+}
+
+// NewShutdownResponse creates and returns a new ShutdownResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//
+// Returns:
+//
+//	ShutdownResponse - The initialized response.
+func NewShutdownResponse(id rpc.ID) ShutdownResponse {
+	return ShutdownResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: nil,
+	}
+}
@@ -1,5 +1,10 @@
 package lsp
 
+import (
+	"net/url"
+	"path/filepath"
+)
+
 // TextDocumentItem represents a text document in the language server.
 // It includes the document's URI, language identifier, version, and text content.
 type TextDocumentItem struct {
@@ -47,8 +52,8 @@ type TextDocuemntPositionParams struct {
 // MarkupContent represents content with a specific markup kind.
 // It includes the kind of markup and the content value.
 type MarkupContent struct {
-	Kind  string `json:"kind"`
-	Value string `json:"value"`
+	Kind  MarkupKind `json:"kind"`
+	Value string     `json:"value"`
 }
 
 // Location represents a location within a text document.
@@ -57,3 +62,30 @@ type Location struct {
 	URI   DocumentURI `json:"uri"`
 	Range Range       `json:"range"`
 }
+
+// Path returns the file system path of a file URI.
+//
+// Returns:
+//
+//	string - The path of the file.
+//	bool - False if the URI is not a file URI.
+func (u DocumentURI) Path() (string, bool) {
+	parsed, err := url.Parse(string(u))
+	if err != nil || parsed.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(parsed.Path), true
+}
+
+// NewDocumentURI returns the file URI of the given path.
+//
+// Parameters:
+//
+//	path string - An absolute file system path.
+//
+// Returns:
+//
+//	DocumentURI - The file URI of the path.
+func NewDocumentURI(path string) DocumentURI {
+	return DocumentURI((&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String())
+}
@@ -1,6 +1,9 @@
 package lsp
 
-import "KamaiZen/settings"
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
 
 // CompletionRequest represents a request for code completion suggestions.
 // It contains the request metadata and the parameters for the completion request.
@@ -24,11 +27,11 @@ type CompletionResponse struct {
 }
 
 // CompletionItemKind represents the kind of a completion item.
-// It is an enumeration of various kinds of completion items.
+// It is an enumeration of various kinds of completion items, starting at 1 as in the specification.
 type CompletionItemKind int
 
 const (
-	TEXT_COMPLETION CompletionItemKind = iota
+	TEXT_COMPLETION CompletionItemKind = iota + 1
 	METHOD_COMPLETION
 	FUNCTION_COMPLETION
 	CONSTRUCTOR_COMPLETION
@@ -48,16 +51,45 @@ const (
 	REFERENCE_COMPLETION
 )
 
+// InsertTextFormat tells how the text inserted by a completion item is interpreted.
+type InsertTextFormat int
+
+const (
+	PLAIN_TEXT_FORMAT InsertTextFormat = iota + 1
+	SNIPPET_FORMAT                     // the text is a snippet, with tab stops such as $1 and placeholders such as ${1:name}
+)
+
 // CompletionItem represents a single completion item in the completion response.
 // It includes the label, detail, documentation, and kind of the completion item.
+// The documentation is a string or a MarkupContent, see NewCompletionDocumentation.
+// Items inserting a snippet set InsertTextFormat to SNIPPET_FORMAT and replace the typed text with TextEdit.
 type CompletionItem struct {
-	Label         string             `json:"label"`
-	Detail        string             `json:"detail"`
-	Documentation string             `json:"documentation"`
-	Kind          CompletionItemKind `json:"kind"`
-	// insert kind
-	// detail
-	// add kind
+	Label            string             `json:"label"`
+	Detail           string             `json:"detail"`
+	Documentation    any                `json:"documentation,omitempty"`
+	Kind             CompletionItemKind `json:"kind"`
+	SortText         string             `json:"sortText,omitempty"`
+	InsertText       string             `json:"insertText,omitempty"`
+	InsertTextFormat InsertTextFormat   `json:"insertTextFormat,omitempty"`
+	TextEdit         *TextEdit          `json:"textEdit,omitempty"`
+}
+
+// NewCompletionDocumentation returns the documentation of a completion item in the format
+// preferred by the client: a markdown MarkupContent, or a plain string.
+//
+// Parameters:
+//
+//	value string - The documentation, written in markdown.
+//
+// Returns:
+//
+//	any - The documentation to set on the CompletionItem.
+func NewCompletionDocumentation(value string) any {
+	format := GetClientCapabilities().CompletionDocumentationFormat()
+	if format == MARKUP_KIND_MARKDOWN {
+		return NewMarkupContent(format, value)
+	}
+	return NewMarkupContent(format, value).Value
 }
 
 // NewCompletionResponse creates and returns a new CompletionResponse.
@@ -65,13 +97,13 @@ type CompletionItem struct {
 //
 // Parameters:
 //
-//	id int - The ID of the response.
+//	id rpc.ID - The ID of the response.
 //	items []CompletionItem - The list of completion items.
 //
 // Returns:
 //
 //	CompletionResponse - The initialized response.
-func NewCompletionResponse(id int, items []CompletionItem) CompletionResponse {
+func NewCompletionResponse(id rpc.ID, items []CompletionItem) CompletionResponse {
 	return CompletionResponse{
 		Response: Response{
 			RPC: settings.RPC_VERSION,
@@ -1,6 +1,9 @@
 package lsp
 
-import "KamaiZen/settings"
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
 
 // DefinitionProviderRequest represents a request for definition information.
 // It contains the request metadata and the parameters for the definition request.
@@ -16,17 +19,10 @@ type DefinitionProviderParams struct {
 }
 
 // DefinitionProviderResponse represents the response to a DefinitionProviderRequest.
-// It contains the response metadata and the location of the definition.
+// It contains the response metadata and the location of the definition, null if there is none.
 type DefinitionProviderResponse struct {
 	Response
-	Result Location `json:"result"`
-}
-
-// DefinitionProvider represents a provider for definition information.
-// It includes the contents of the definition and the range within the document.
-type DefinitionProvider struct {
-	Contents MarkupContent `json:"contents"`
-	Range    *Range        `json:"range,omitempty"`
+	Result *Location `json:"result"`
 }
 
 // NewDefinitionProviderResponse creates and returns a new DefinitionProviderResponse.
@@ -34,32 +30,18 @@ type DefinitionProvider struct {
 //
 // Parameters:
 //
-//	id int - The ID of the response.
-//	contents string - The contents of the definition.
+//	id rpc.ID - The ID of the response.
+//	location *Location - The location of the definition, nil if there is none.
 //
 // Returns:
 //
 //	DefinitionProviderResponse - The initialized response.
-func NewDefintionProviderResponse(id int, contents string) DefinitionProviderResponse {
-	// TODO: fill in the contents and range
+func NewDefintionProviderResponse(id rpc.ID, location *Location) DefinitionProviderResponse {
 	return DefinitionProviderResponse{
 		Response: Response{
 			RPC: settings.RPC_VERSION,
 			ID:  id,
 		},
-		Result: Location{
-			// Location is a struct defined in textDocument_hover.go
-			URI: "https://www.kamailio.org/wikidocs/cookbooks/5.8.x/core/",
-			Range: Range{
-				Start: Position{
-					Line:      0,
-					Character: 0,
-				},
-				End: Position{
-					Line:      0,
-					Character: 0,
-				},
-			},
-		},
+		Result: location,
 	}
 }
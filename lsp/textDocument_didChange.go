@@ -1,6 +1,12 @@
 package lsp
 
-import "KamaiZen/logger"
+import (
+	"KamaiZen/logger"
+	"strings"
+	"unicode/utf8"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
 
 // DidChangeTextDocumentNotification represents a notification sent to the server
 // when a text document is changed. It contains the notification metadata and the
@@ -24,6 +30,15 @@ type TextDocumentContentChangeEvent struct {
 	Text  string `json:"text"`
 }
 
+// IsFull reports whether the change event replaces the whole document.
+//
+// Returns:
+//
+//	bool - True if the event carries no range, false otherwise.
+func (change TextDocumentContentChangeEvent) IsFull() bool {
+	return change.Range == nil
+}
+
 // Apply applies the content change event to the given text.
 // It modifies the text based on the range and new content specified in the change event.
 //
@@ -35,13 +50,104 @@ type TextDocumentContentChangeEvent struct {
 //
 //	string - The modified text after applying the change event.
 func (change TextDocumentContentChangeEvent) Apply(text string) string {
-	if change.Range != nil {
-		start := change.Range.Start
-		end := change.Range.End
-		logger.Debug("Change range: ", start, end)
-		text = text[:start.Line] + change.Text + text[end.Line:]
+	if change.IsFull() {
+		return change.Text
+	}
+	start := OffsetAt(text, change.Range.Start)
+	end := OffsetAt(text, change.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	logger.Debug("Change range: ", start, end)
+	return text[:start] + change.Text + text[end:]
+}
+
+// Edit translates the ranged change event into a tree-sitter edit against the given text.
+// The text must be the document content before the change is applied.
+//
+// Parameters:
+//
+//	text string - The original text the change applies to.
+//
+// Returns:
+//
+//	sitter.EditInput - The edit describing the change in bytes and points.
+func (change TextDocumentContentChangeEvent) Edit(text string) sitter.EditInput {
+	start := OffsetAt(text, change.Range.Start)
+	end := OffsetAt(text, change.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	startPoint := PointAt(text, start)
+	newEndPoint := startPoint
+	if lines := strings.Count(change.Text, "\n"); lines > 0 {
+		newEndPoint.Row += uint32(lines)
+		newEndPoint.Column = uint32(len(change.Text) - strings.LastIndex(change.Text, "\n") - 1)
 	} else {
-		text = change.Text
+		newEndPoint.Column += uint32(len(change.Text))
+	}
+	return sitter.EditInput{
+		StartIndex:  uint32(start),
+		OldEndIndex: uint32(end),
+		NewEndIndex: uint32(start + len(change.Text)),
+		StartPoint:  startPoint,
+		OldEndPoint: PointAt(text, end),
+		NewEndPoint: newEndPoint,
+	}
+}
+
+// OffsetAt converts an LSP position into a byte offset within the given text.
+// Characters are counted in the negotiated position encoding, UTF-16 code units by default.
+// Positions past the end of a line or of the text are clamped.
+//
+// Parameters:
+//
+//	text string - The text the position refers to.
+//	position Position - The position to convert.
+//
+// Returns:
+//
+//	int - The byte offset of the position.
+func OffsetAt(text string, position Position) int {
+	offset := 0
+	for line := 0; line < position.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	encoding := GetPositionEncoding()
+	units := 0
+	for offset < len(text) && units < position.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if r == '\n' {
+			break
+		}
+		units += characterUnits(r, size, encoding)
+		offset += size
+	}
+	return offset
+}
+
+// PointAt converts a byte offset within the given text into a tree-sitter point.
+// Tree-sitter columns are measured in bytes.
+//
+// Parameters:
+//
+//	text string - The text the offset refers to.
+//	offset int - The byte offset to convert.
+//
+// Returns:
+//
+//	sitter.Point - The row and byte column of the offset.
+func PointAt(text string, offset int) sitter.Point {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	return sitter.Point{
+		Row:    uint32(strings.Count(before, "\n")),
+		Column: uint32(offset - strings.LastIndex(before, "\n") - 1),
 	}
-	return text
 }
@@ -0,0 +1,70 @@
+package lsp_test
+
+import (
+	"KamaiZen/lsp"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+func rangeChange(startLine, startChar, endLine, endChar int, text string) lsp.TextDocumentContentChangeEvent {
+	return lsp.TextDocumentContentChangeEvent{
+		Range: &lsp.Range{
+			Start: lsp.Position{Line: startLine, Character: startChar},
+			End:   lsp.Position{Line: endLine, Character: endChar},
+		},
+		Text: text,
+	}
+}
+
+func TestApplyFullChange(t *testing.T) {
+	change := lsp.TextDocumentContentChangeEvent{Text: "debug=2\n"}
+	if actual := change.Apply("debug=3\n"); actual != "debug=2\n" {
+		t.Fatalf("Expected: %q,\ngot: %q", "debug=2\n", actual)
+	}
+}
+
+func TestApplyRangedChange(t *testing.T) {
+	text := "debug=3\nroute[A] {\n\texit;\n}\n"
+	tests := []struct {
+		change   lsp.TextDocumentContentChangeEvent
+		expected string
+	}{
+		{rangeChange(0, 6, 0, 7, "4"), "debug=4\nroute[A] {\n\texit;\n}\n"},
+		{rangeChange(1, 6, 1, 7, "RELAY"), "debug=3\nroute[RELAY] {\n\texit;\n}\n"},
+		{rangeChange(2, 1, 2, 6, "drop;\n\texit;"), "debug=3\nroute[A] {\n\tdrop;\n\texit;\n}\n"},
+		{rangeChange(1, 0, 3, 1, ""), "debug=3\n\n"},
+		{rangeChange(4, 0, 4, 0, "# end\n"), "debug=3\nroute[A] {\n\texit;\n}\n# end\n"},
+		{rangeChange(0, 7, 0, 99, ""), "debug=3\nroute[A] {\n\texit;\n}\n"},
+	}
+	for _, test := range tests {
+		if actual := test.change.Apply(text); actual != test.expected {
+			t.Fatalf("Expected: %q,\ngot: %q", test.expected, actual)
+		}
+	}
+}
+
+func TestApplyCountsUTF16(t *testing.T) {
+	// "é" is one UTF-16 unit and two bytes, "😀" is two UTF-16 units and four bytes
+	text := "xlog(\"é😀x\");"
+	actual := rangeChange(0, 9, 0, 10, "y").Apply(text)
+	if expected := "xlog(\"é😀y\");"; actual != expected {
+		t.Fatalf("Expected: %q,\ngot: %q", expected, actual)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	text := "debug=3\nroute[A] {\n\texit;\n}\n"
+	edit := rangeChange(1, 6, 2, 1, "B] {\n\t\t").Edit(text)
+	expected := sitter.EditInput{
+		StartIndex:  14,
+		OldEndIndex: 20,
+		NewEndIndex: 21,
+		StartPoint:  sitter.Point{Row: 1, Column: 6},
+		OldEndPoint: sitter.Point{Row: 2, Column: 1},
+		NewEndPoint: sitter.Point{Row: 2, Column: 2},
+	}
+	if edit != expected {
+		t.Fatalf("Expected: %+v,\ngot: %+v", expected, edit)
+	}
+}
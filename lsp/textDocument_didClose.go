@@ -0,0 +1,15 @@
+package lsp
+
+// DidCloseTextDocumentNotification represents a notification sent to the server
+// when a text document is closed. It contains the notification metadata and the
+// parameters for the close event.
+type DidCloseTextDocumentNotification struct {
+	Notification
+	Params DidCloseTextDocumentParams `json:"params"`
+}
+
+// DidCloseTextDocumentParams contains the parameters for the DidCloseTextDocumentNotification.
+// It includes the identifier of the document that was closed.
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
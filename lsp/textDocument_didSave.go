@@ -0,0 +1,16 @@
+package lsp
+
+// DidSaveTextDocumentNotification represents a notification sent to the server
+// when a text document is saved. It contains the notification metadata and the
+// parameters for the save event.
+type DidSaveTextDocumentNotification struct {
+	Notification
+	Params DidSaveTextDocumentParams `json:"params"`
+}
+
+// DidSaveTextDocumentParams contains the parameters for the DidSaveTextDocumentNotification.
+// It includes the identifier of the saved document and, if the client was asked to, its content.
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Text         *string                `json:"text,omitempty"`
+}
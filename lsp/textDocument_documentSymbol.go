@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// DocumentSymbolRequest represents a request for the symbols of a document, e.g. to show its outline.
+// It contains the request metadata and the parameters for the documentSymbol request.
+type DocumentSymbolRequest struct {
+	Request
+	Params DocumentSymbolParams `json:"params"`
+}
+
+// DocumentSymbolParams contains the parameters for the DocumentSymbolRequest.
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SymbolKind represents the kind of a symbol.
+type SymbolKind int
+
+const (
+	FILE_SYMBOL SymbolKind = iota + 1
+	MODULE_SYMBOL
+	NAMESPACE_SYMBOL
+	PACKAGE_SYMBOL
+	CLASS_SYMBOL
+	METHOD_SYMBOL
+	PROPERTY_SYMBOL
+	FIELD_SYMBOL
+	CONSTRUCTOR_SYMBOL
+	ENUM_SYMBOL
+	INTERFACE_SYMBOL
+	FUNCTION_SYMBOL
+	VARIABLE_SYMBOL
+	CONSTANT_SYMBOL
+	STRING_SYMBOL
+	NUMBER_SYMBOL
+	BOOLEAN_SYMBOL
+	ARRAY_SYMBOL
+	OBJECT_SYMBOL
+	KEY_SYMBOL
+	NULL_SYMBOL
+	ENUM_MEMBER_SYMBOL
+	STRUCT_SYMBOL
+	EVENT_SYMBOL
+	OPERATOR_SYMBOL
+	TYPE_PARAMETER_SYMBOL
+)
+
+// DocumentSymbol represents a symbol of a document and the symbols nested in it.
+// Range covers the whole symbol, SelectionRange the part to reveal, usually its name.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// SymbolInformation represents a symbol for clients that do not support nested symbols.
+// The symbol it is nested in is given by its name.
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// DocumentSymbolResponse represents the response to a DocumentSymbolRequest.
+// Its result is a list of DocumentSymbol, or of SymbolInformation for clients
+// that do not support hierarchical document symbols.
+type DocumentSymbolResponse struct {
+	Response
+	Result any `json:"result"`
+}
+
+// NewDocumentSymbolResponse creates and returns a new DocumentSymbolResponse.
+// The symbols are flattened into SymbolInformation if the client does not support nested symbols.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	uri DocumentURI - The URI of the document.
+//	symbols []DocumentSymbol - The symbols of the document.
+//
+// Returns:
+//
+//	DocumentSymbolResponse - The initialized response.
+func NewDocumentSymbolResponse(id rpc.ID, uri DocumentURI, symbols []DocumentSymbol) DocumentSymbolResponse {
+	response := DocumentSymbolResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+	}
+	if GetClientCapabilities().TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport {
+		if symbols == nil {
+			symbols = []DocumentSymbol{}
+		}
+		response.Result = symbols
+	} else {
+		response.Result = flattenSymbols(uri, symbols, "", []SymbolInformation{})
+	}
+	return response
+}
+
+// flattenSymbols appends the symbols and the symbols nested in them to the list.
+func flattenSymbols(uri DocumentURI, symbols []DocumentSymbol, container string, flat []SymbolInformation) []SymbolInformation {
+	for _, symbol := range symbols {
+		flat = append(flat, SymbolInformation{
+			Name:          symbol.Name,
+			Kind:          symbol.Kind,
+			Location:      Location{URI: uri, Range: symbol.Range},
+			ContainerName: container,
+		})
+		flat = flattenSymbols(uri, symbol.Children, symbol.Name, flat)
+	}
+	return flat
+}
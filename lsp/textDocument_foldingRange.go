@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// FoldingRangeRequest represents a request for the ranges of a document that can be folded.
+// It contains the request metadata and the parameters for the foldingRange request.
+type FoldingRangeRequest struct {
+	Request
+	Params FoldingRangeParams `json:"params"`
+}
+
+// FoldingRangeParams contains the parameters for the FoldingRangeRequest.
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// FoldingRangeKind represents the kind of a folding range, used by clients to fold ranges
+// of a kind on demand. Ranges without a kind are plain code blocks.
+type FoldingRangeKind string
+
+const (
+	FOLDING_RANGE_KIND_COMMENT FoldingRangeKind = "comment"
+	FOLDING_RANGE_KIND_IMPORTS FoldingRangeKind = "imports"
+	FOLDING_RANGE_KIND_REGION  FoldingRangeKind = "region"
+)
+
+// FoldingRange represents a range of lines that can be folded.
+// The start line stays visible when the range is folded, the lines after it up to
+// and including the end line are hidden.
+type FoldingRange struct {
+	StartLine int              `json:"startLine"`
+	EndLine   int              `json:"endLine"`
+	Kind      FoldingRangeKind `json:"kind,omitempty"`
+}
+
+// FoldingRangeResponse represents the response to a FoldingRangeRequest.
+// It contains the response metadata and the folding ranges of the document.
+type FoldingRangeResponse struct {
+	Response
+	Result []FoldingRange `json:"result"`
+}
+
+// NewFoldingRangeResponse creates and returns a new FoldingRangeResponse.
+// The ranges are cut to the number of ranges the client asked for, if it did.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	ranges []FoldingRange - The folding ranges of the document.
+//
+// Returns:
+//
+//	FoldingRangeResponse - The initialized response.
+func NewFoldingRangeResponse(id rpc.ID, ranges []FoldingRange) FoldingRangeResponse {
+	if ranges == nil {
+		ranges = []FoldingRange{}
+	}
+	if limit := GetClientCapabilities().TextDocument.FoldingRange.RangeLimit; limit > 0 && len(ranges) > limit {
+		ranges = ranges[:limit]
+	}
+	return FoldingRangeResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: ranges,
+	}
+}
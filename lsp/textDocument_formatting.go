@@ -1,6 +1,7 @@
 package lsp
 
 import (
+	"KamaiZen/rpc"
 	"KamaiZen/settings"
 
 	sitter "github.com/smacker/go-tree-sitter"
@@ -49,13 +50,13 @@ type TextEdit struct {
 //
 // Parameters:
 //
-//	id int - The ID of the response.
+//	id rpc.ID - The ID of the response.
 //	edits []TextEdit - The list of text edits.
 //
 // Returns:
 //
 //	DocumentFormattingResponse - The initialized response.
-func NewDocumentFormattingResponse(id int, edits []TextEdit) DocumentFormattingResponse {
+func NewDocumentFormattingResponse(id rpc.ID, edits []TextEdit) DocumentFormattingResponse {
 	return DocumentFormattingResponse{
 		Response: Response{
 			RPC: settings.RPC_VERSION,
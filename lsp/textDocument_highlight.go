@@ -1,3 +1,59 @@
 package lsp
 
-// TODO: Implement highlights
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// DocumentHighlightRequest represents a request for the occurrences in a document
+// of the symbol at a position. It contains the request metadata and the parameters
+// for the documentHighlight request.
+type DocumentHighlightRequest struct {
+	Request
+	Params TextDocuemntPositionParams `json:"params"`
+}
+
+// DocumentHighlightKind tells how a symbol is used where it occurs.
+type DocumentHighlightKind int
+
+const (
+	TEXT_HIGHLIGHT DocumentHighlightKind = iota + 1
+	READ_HIGHLIGHT
+	WRITE_HIGHLIGHT
+)
+
+// DocumentHighlight represents an occurrence of a symbol in a document.
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind"`
+}
+
+// DocumentHighlightResponse represents the response to a DocumentHighlightRequest.
+// It contains the response metadata and the occurrences of the symbol.
+type DocumentHighlightResponse struct {
+	Response
+	Result []DocumentHighlight `json:"result"`
+}
+
+// NewDocumentHighlightResponse creates and returns a new DocumentHighlightResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	highlights []DocumentHighlight - The occurrences of the symbol.
+//
+// Returns:
+//
+//	DocumentHighlightResponse - The initialized response.
+func NewDocumentHighlightResponse(id rpc.ID, highlights []DocumentHighlight) DocumentHighlightResponse {
+	if highlights == nil {
+		highlights = []DocumentHighlight{}
+	}
+	return DocumentHighlightResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: highlights,
+	}
+}
@@ -1,6 +1,9 @@
 package lsp
 
-import "KamaiZen/settings"
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
 
 // HoverRequest represents a request for hover information.
 // It contains the request metadata and the parameters for the hover request.
@@ -30,27 +33,25 @@ type Hover struct {
 }
 
 // NewHoverResponse creates and returns a new HoverResponse.
-// It initializes the response with the given ID and sets the hover contents.
+// It initializes the response with the given ID and sets the hover contents
+// in the format preferred by the client.
 //
 // Parameters:
 //
-//	id int - The ID of the response.
-//	contents string - The contents of the hover.
+//	id rpc.ID - The ID of the response.
+//	contents string - The contents of the hover, written in markdown.
 //
 // Returns:
 //
 //	HoverResponse - The initialized response.
-func NewHoverResponse(id int, contents string) HoverResponse {
+func NewHoverResponse(id rpc.ID, contents string) HoverResponse {
 	return HoverResponse{
 		Response: Response{
 			RPC: settings.RPC_VERSION,
 			ID:  id,
 		},
 		Result: &Hover{
-			Contents: MarkupContent{
-				Kind:  "markdown",
-				Value: contents,
-			},
+			Contents: NewMarkupContent(GetClientCapabilities().HoverFormat(), contents),
 		},
 	}
 }
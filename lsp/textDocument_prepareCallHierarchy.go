@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// PrepareCallHierarchyRequest represents a request for the item of the call hierarchy at a position,
+// which the client then asks the incoming and outgoing calls of.
+type PrepareCallHierarchyRequest struct {
+	Request
+	Params TextDocuemntPositionParams `json:"params"`
+}
+
+// CallHierarchyItem represents a routing block in the call hierarchy.
+// The client sends it back as it is to ask for its calls.
+type CallHierarchyItem struct {
+	Name           string      `json:"name"`
+	Kind           SymbolKind  `json:"kind"`
+	Detail         string      `json:"detail,omitempty"`
+	URI            DocumentURI `json:"uri"`
+	Range          Range       `json:"range"`
+	SelectionRange Range       `json:"selectionRange"`
+}
+
+// PrepareCallHierarchyResponse represents the response to a PrepareCallHierarchyRequest.
+// Its result is null if there is no routing block at the position.
+type PrepareCallHierarchyResponse struct {
+	Response
+	Result []CallHierarchyItem `json:"result"`
+}
+
+// NewPrepareCallHierarchyResponse creates and returns a new PrepareCallHierarchyResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	items []CallHierarchyItem - The items at the position, or nil.
+//
+// Returns:
+//
+//	PrepareCallHierarchyResponse - The initialized response.
+func NewPrepareCallHierarchyResponse(id rpc.ID, items []CallHierarchyItem) PrepareCallHierarchyResponse {
+	return PrepareCallHierarchyResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: items,
+	}
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// ReferencesRequest represents a request for the references of the symbol at a position.
+// It contains the request metadata and the parameters for the references request.
+type ReferencesRequest struct {
+	Request
+	Params ReferenceParams `json:"params"`
+}
+
+// ReferenceParams contains the parameters for the ReferencesRequest.
+// It includes the text document position parameters and the context of the request.
+type ReferenceParams struct {
+	TextDocuemntPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+// ReferenceContext tells whether the declaration of the symbol is part of the result.
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// ReferencesResponse represents the response to a ReferencesRequest.
+// It contains the response metadata and the locations of the references.
+type ReferencesResponse struct {
+	Response
+	Result []Location `json:"result"`
+}
+
+// NewReferencesResponse creates and returns a new ReferencesResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	locations []Location - The locations of the references.
+//
+// Returns:
+//
+//	ReferencesResponse - The initialized response.
+func NewReferencesResponse(id rpc.ID, locations []Location) ReferencesResponse {
+	if locations == nil {
+		locations = []Location{}
+	}
+	return ReferencesResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: locations,
+	}
+}
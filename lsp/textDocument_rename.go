@@ -0,0 +1,104 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// RenameRequest represents a request to rename the symbol at a position.
+// It contains the request metadata and the parameters for the rename request.
+type RenameRequest struct {
+	Request
+	Params RenameParams `json:"params"`
+}
+
+// RenameParams contains the parameters for the RenameRequest.
+// It includes the text document position parameters and the new name of the symbol.
+type RenameParams struct {
+	TextDocuemntPositionParams
+	NewName string `json:"newName"`
+}
+
+// WorkspaceEdit represents changes to many documents, keyed by the URI of the document.
+type WorkspaceEdit struct {
+	Changes map[DocumentURI][]TextEdit `json:"changes"`
+}
+
+// RenameResponse represents the response to a RenameRequest.
+// It contains the response metadata and the edits that rename the symbol.
+type RenameResponse struct {
+	Response
+	Result *WorkspaceEdit `json:"result"`
+}
+
+// NewRenameResponse creates and returns a new RenameResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	edit *WorkspaceEdit - The edits that rename the symbol.
+//
+// Returns:
+//
+//	RenameResponse - The initialized response.
+func NewRenameResponse(id rpc.ID, edit *WorkspaceEdit) RenameResponse {
+	return RenameResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: edit,
+	}
+}
+
+// PrepareRenameRequest represents a request to check whether the symbol at a position can be renamed.
+// It contains the request metadata and the parameters for the prepareRename request.
+type PrepareRenameRequest struct {
+	Request
+	Params PrepareRenameParams `json:"params"`
+}
+
+// PrepareRenameParams contains the parameters for the PrepareRenameRequest.
+// It includes the text document position parameters.
+type PrepareRenameParams struct {
+	TextDocuemntPositionParams
+}
+
+// PrepareRenameResult holds the range of the name to rename and the text the client shows to edit.
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+// PrepareRenameResponse represents the response to a PrepareRenameRequest.
+// Its result is null if the symbol at the position can't be renamed.
+type PrepareRenameResponse struct {
+	Response
+	Result *PrepareRenameResult `json:"result"`
+}
+
+// NewPrepareRenameResponse creates and returns a new PrepareRenameResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	result *PrepareRenameResult - The name to rename, nil if nothing can be renamed.
+//
+// Returns:
+//
+//	PrepareRenameResponse - The initialized response.
+func NewPrepareRenameResponse(id rpc.ID, result *PrepareRenameResult) PrepareRenameResponse {
+	return PrepareRenameResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: result,
+	}
+}
+
+// RenameOptions represents the rename capabilities of the server.
+// It may only be sent to clients that support prepareRename.
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
@@ -0,0 +1,285 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+	"sort"
+)
+
+// SemanticTokensRequest represents a request for the semantic tokens of a whole document.
+// It contains the request metadata and the parameters for the semanticTokens/full request.
+type SemanticTokensRequest struct {
+	Request
+	Params SemanticTokensParams `json:"params"`
+}
+
+// SemanticTokensParams contains the parameters for the SemanticTokensRequest.
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokensRangeRequest represents a request for the semantic tokens of a range of a document,
+// usually the visible part of it.
+type SemanticTokensRangeRequest struct {
+	Request
+	Params SemanticTokensRangeParams `json:"params"`
+}
+
+// SemanticTokensRangeParams contains the parameters for the SemanticTokensRangeRequest.
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// SemanticTokensDeltaRequest represents a request for the changes of the semantic tokens of
+// a document since a previous result.
+type SemanticTokensDeltaRequest struct {
+	Request
+	Params SemanticTokensDeltaParams `json:"params"`
+}
+
+// SemanticTokensDeltaParams contains the parameters for the SemanticTokensDeltaRequest.
+// PreviousResultID is the result ID of the tokens the client holds.
+type SemanticTokensDeltaParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId"`
+}
+
+// SemanticTokenType represents the type of a semantic token, as its index in the legend.
+//
+//   - NAMESPACE_TOKEN: the name of a module in loadmodule and modparam.
+//   - FUNCTION_TOKEN: the name of a route, and of a function where it is called.
+//   - MACRO_TOKEN: the name of a define, where it is defined, tested or used.
+//   - VARIABLE_TOKEN: a pseudo-variable, also inside strings.
+//   - PROPERTY_TOKEN: the name of a core or module parameter.
+//   - KEYWORD_TOKEN: a keyword, including the preprocessor directives.
+//   - COMMENT_TOKEN: a comment.
+//   - STRING_TOKEN: a string, apart from the pseudo-variables inside it.
+//   - NUMBER_TOKEN: a number.
+//   - OPERATOR_TOKEN: an operator, or a transformation of a pseudo-variable such as {s.len}.
+type SemanticTokenType int
+
+const (
+	NAMESPACE_TOKEN SemanticTokenType = iota
+	FUNCTION_TOKEN
+	MACRO_TOKEN
+	VARIABLE_TOKEN
+	PROPERTY_TOKEN
+	KEYWORD_TOKEN
+	COMMENT_TOKEN
+	STRING_TOKEN
+	NUMBER_TOKEN
+	OPERATOR_TOKEN
+)
+
+// semantic_token_types holds the names of the token types, in the order of their values.
+var semantic_token_types = []string{
+	"namespace", "function", "macro", "variable", "property",
+	"keyword", "comment", "string", "number", "operator",
+}
+
+// SemanticTokenModifiers is a set of semantic token modifiers, as bits.
+//
+//   - DECLARATION_MODIFIER: the token declares a route or a define.
+//   - INACTIVE_MODIFIER: the token is in an #!ifdef or #!ifndef branch that is left out,
+//     going by the defines of the document.
+type SemanticTokenModifiers uint32
+
+const (
+	DECLARATION_MODIFIER SemanticTokenModifiers = 1 << iota
+	INACTIVE_MODIFIER
+)
+
+// semantic_token_modifiers holds the names of the token modifiers, in the order of their bits.
+var semantic_token_modifiers = []string{"declaration", "inactive"}
+
+// SemanticTokensLegend represents the names of the token types and modifiers
+// that the encoded tokens refer to.
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// SemanticTokensOptions represents the semantic tokens capabilities of the server.
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend      `json:"legend"`
+	Range  bool                      `json:"range"`
+	Full   SemanticTokensFullOptions `json:"full"`
+}
+
+// SemanticTokensFullOptions tells whether the server sends the changes of the tokens of a document.
+type SemanticTokensFullOptions struct {
+	Delta bool `json:"delta"`
+}
+
+// NewSemanticTokensOptions creates and returns the semantic tokens capabilities of the server,
+// which support ranges and deltas.
+//
+// Returns:
+//
+//	SemanticTokensOptions - The capabilities, with the legend of the tokens.
+func NewSemanticTokensOptions() SemanticTokensOptions {
+	return SemanticTokensOptions{
+		Legend: SemanticTokensLegend{
+			TokenTypes:     semantic_token_types,
+			TokenModifiers: semantic_token_modifiers,
+		},
+		Range: true,
+		Full:  SemanticTokensFullOptions{Delta: true},
+	}
+}
+
+// SemanticToken represents a token of a single line of a document.
+// Its character and length are in the units of the negotiated encoding.
+type SemanticToken struct {
+	Line      int
+	Character int
+	Length    int
+	Type      SemanticTokenType
+	Modifiers SemanticTokenModifiers
+}
+
+// SemanticTokens represents the encoded tokens of a document.
+// Each token takes five integers: its line and character relative to the previous token,
+// its length, its type and its modifiers.
+type SemanticTokens struct {
+	ResultID string   `json:"resultId,omitempty"`
+	Data     []uint32 `json:"data"`
+}
+
+// SemanticTokensEdit replaces DeleteCount integers of the previous data, starting at Start, with Data.
+type SemanticTokensEdit struct {
+	Start       int      `json:"start"`
+	DeleteCount int      `json:"deleteCount"`
+	Data        []uint32 `json:"data,omitempty"`
+}
+
+// SemanticTokensDelta represents the changes of the tokens of a document since a previous result.
+type SemanticTokensDelta struct {
+	ResultID string               `json:"resultId,omitempty"`
+	Edits    []SemanticTokensEdit `json:"edits"`
+}
+
+// EncodeSemanticTokens encodes the tokens, sorted by their position, as relative integers.
+//
+// Parameters:
+//
+//	resultID string - The ID a delta request can refer to the tokens by, may be empty.
+//	tokens []SemanticToken - The tokens of a document.
+//
+// Returns:
+//
+//	SemanticTokens - The encoded tokens.
+func EncodeSemanticTokens(resultID string, tokens []SemanticToken) SemanticTokens {
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].Line != tokens[j].Line {
+			return tokens[i].Line < tokens[j].Line
+		}
+		return tokens[i].Character < tokens[j].Character
+	})
+	data := make([]uint32, 0, 5*len(tokens))
+	line, character := 0, 0
+	for _, token := range tokens {
+		if token.Line != line {
+			character = 0
+		}
+		data = append(data,
+			uint32(token.Line-line),
+			uint32(token.Character-character),
+			uint32(token.Length),
+			uint32(token.Type),
+			uint32(token.Modifiers),
+		)
+		line, character = token.Line, token.Character
+	}
+	return SemanticTokens{ResultID: resultID, Data: data}
+}
+
+// DiffSemanticTokens returns the changes from the previous tokens to the current ones,
+// as a single edit of the integers between their common prefix and suffix.
+//
+// Parameters:
+//
+//	previous SemanticTokens - The tokens the client holds.
+//	current SemanticTokens - The tokens of the document.
+//
+// Returns:
+//
+//	SemanticTokensDelta - The changes, with the result ID of the current tokens.
+func DiffSemanticTokens(previous SemanticTokens, current SemanticTokens) SemanticTokensDelta {
+	delta := SemanticTokensDelta{ResultID: current.ResultID, Edits: []SemanticTokensEdit{}}
+	before, after := previous.Data, current.Data
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(before) && prefix == len(after) {
+		return delta
+	}
+	delta.Edits = append(delta.Edits, SemanticTokensEdit{
+		Start:       prefix,
+		DeleteCount: len(before) - prefix - suffix,
+		Data:        after[prefix : len(after)-suffix],
+	})
+	return delta
+}
+
+// SemanticTokensResponse represents the response to a SemanticTokensRequest or a SemanticTokensRangeRequest.
+type SemanticTokensResponse struct {
+	Response
+	Result SemanticTokens `json:"result"`
+}
+
+// NewSemanticTokensResponse creates and returns a new SemanticTokensResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	tokens SemanticTokens - The encoded tokens.
+//
+// Returns:
+//
+//	SemanticTokensResponse - The initialized response.
+func NewSemanticTokensResponse(id rpc.ID, tokens SemanticTokens) SemanticTokensResponse {
+	if tokens.Data == nil {
+		tokens.Data = []uint32{}
+	}
+	return SemanticTokensResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: tokens,
+	}
+}
+
+// SemanticTokensDeltaResponse represents the response to a SemanticTokensDeltaRequest.
+// Its result is a SemanticTokensDelta, or SemanticTokens if the previous result is unknown.
+type SemanticTokensDeltaResponse struct {
+	Response
+	Result any `json:"result"`
+}
+
+// NewSemanticTokensDeltaResponse creates and returns a new SemanticTokensDeltaResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	result any - The SemanticTokensDelta, or the SemanticTokens of the whole document.
+//
+// Returns:
+//
+//	SemanticTokensDeltaResponse - The initialized response.
+func NewSemanticTokensDeltaResponse(id rpc.ID, result any) SemanticTokensDeltaResponse {
+	return SemanticTokensDeltaResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: result,
+	}
+}
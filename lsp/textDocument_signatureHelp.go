@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// SignatureHelpRequest represents a request for the signature of the function called at a position.
+// It contains the request metadata and the parameters for the signatureHelp request.
+type SignatureHelpRequest struct {
+	Request
+	Params SignatureHelpParams `json:"params"`
+}
+
+// SignatureHelpParams contains the parameters for the SignatureHelpRequest.
+// It includes the text document position parameters and the context of the request.
+type SignatureHelpParams struct {
+	TextDocuemntPositionParams
+	Context *SignatureHelpContext `json:"context,omitempty"`
+}
+
+// SignatureHelpTriggerKind tells what triggered a signature help request.
+type SignatureHelpTriggerKind int
+
+const (
+	SIGNATURE_HELP_INVOKED SignatureHelpTriggerKind = iota + 1
+	SIGNATURE_HELP_TRIGGER_CHARACTER
+	SIGNATURE_HELP_CONTENT_CHANGE
+)
+
+// SignatureHelpContext represents what triggered a signature help request,
+// and whether the signature help was already shown.
+type SignatureHelpContext struct {
+	TriggerKind      SignatureHelpTriggerKind `json:"triggerKind"`
+	TriggerCharacter string                   `json:"triggerCharacter,omitempty"`
+	IsRetrigger      bool                     `json:"isRetrigger"`
+}
+
+// SignatureHelpOptions represents the signature help capabilities of the server.
+type SignatureHelpOptions struct {
+	TriggerCharacters   []string `json:"triggerCharacters,omitempty"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+// NewSignatureHelpOptions creates and returns the signature help capabilities of the server,
+// which shows the signature when an argument list is opened and moves on at each comma.
+//
+// Returns:
+//
+//	SignatureHelpOptions - The capabilities, with the trigger characters.
+func NewSignatureHelpOptions() SignatureHelpOptions {
+	return SignatureHelpOptions{
+		TriggerCharacters:   []string{"(", ","},
+		RetriggerCharacters: []string{")"},
+	}
+}
+
+// SignatureHelp represents the signatures of the function called at a position.
+// ActiveSignature and ActiveParameter are indexes in Signatures and in the parameters of the active signature.
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// SignatureInformation represents a signature of a function: its label, e.g. t_relay([host], [port]),
+// its documentation and its parameters.
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation *MarkupContent         `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+// ParameterInformation represents a parameter of a signature.
+// Its label is the part of the label of the signature that names the parameter.
+type ParameterInformation struct {
+	Label string `json:"label"`
+}
+
+// SignatureHelpResponse represents the response to a SignatureHelpRequest.
+// Its result is null if there is no function called at the position.
+type SignatureHelpResponse struct {
+	Response
+	Result *SignatureHelp `json:"result"`
+}
+
+// NewSignatureHelpResponse creates and returns a new SignatureHelpResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	help *SignatureHelp - The signatures of the function, or nil.
+//
+// Returns:
+//
+//	SignatureHelpResponse - The initialized response.
+func NewSignatureHelpResponse(id rpc.ID, help *SignatureHelp) SignatureHelpResponse {
+	if help != nil && len(help.Signatures) == 0 {
+		help = nil
+	}
+	return SignatureHelpResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: help,
+	}
+}
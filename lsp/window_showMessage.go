@@ -0,0 +1,48 @@
+package lsp
+
+import "KamaiZen/settings"
+
+// MessageType represents the severity of a message shown to the user.
+type MessageType int
+
+const (
+	MESSAGE_TYPE_ERROR MessageType = iota + 1
+	MESSAGE_TYPE_WARNING
+	MESSAGE_TYPE_INFO
+	MESSAGE_TYPE_LOG
+)
+
+// ShowMessageNotification represents a notification asking the client to show a message to the user.
+type ShowMessageNotification struct {
+	Notification
+	Params ShowMessageParams `json:"params"`
+}
+
+// ShowMessageParams contains the parameters for the ShowMessageNotification.
+type ShowMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+}
+
+// NewShowMessageNotification creates and returns a new ShowMessageNotification.
+//
+// Parameters:
+//
+//	messageType MessageType - The severity of the message.
+//	message string - The message.
+//
+// Returns:
+//
+//	ShowMessageNotification - The initialized notification.
+func NewShowMessageNotification(messageType MessageType, message string) ShowMessageNotification {
+	return ShowMessageNotification{
+		Notification: Notification{
+			RPC:    settings.RPC_VERSION,
+			Method: "window/showMessage",
+		},
+		Params: ShowMessageParams{
+			Type:    messageType,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// ProgressToken identifies a progress reported by the server, it is a number or a string.
+type ProgressToken = rpc.ID
+
+// WorkDoneProgressCreateParams contains the parameters of the window/workDoneProgress/create request,
+// which asks the client to create a progress the server can then report on.
+type WorkDoneProgressCreateParams struct {
+	Token ProgressToken `json:"token"`
+}
+
+// WorkDoneProgressCancelNotification represents a notification sent by the client when the user
+// cancels a progress created by the server.
+type WorkDoneProgressCancelNotification struct {
+	Notification
+	Params WorkDoneProgressCancelParams `json:"params"`
+}
+
+// WorkDoneProgressCancelParams contains the parameters for the WorkDoneProgressCancelNotification.
+type WorkDoneProgressCancelParams struct {
+	Token ProgressToken `json:"token"`
+}
+
+// ProgressNotification represents a $/progress notification.
+// Its value is a WorkDoneProgressBegin, WorkDoneProgressReport or WorkDoneProgressEnd.
+type ProgressNotification struct {
+	Notification
+	Params ProgressParams `json:"params"`
+}
+
+// ProgressParams contains the parameters for the ProgressNotification.
+type ProgressParams struct {
+	Token ProgressToken `json:"token"`
+	Value any           `json:"value"`
+}
+
+// WorkDoneProgressBegin starts a progress.
+type WorkDoneProgressBegin struct {
+	Kind        string `json:"kind"`
+	Title       string `json:"title"`
+	Cancellable bool   `json:"cancellable"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *int   `json:"percentage,omitempty"`
+}
+
+// WorkDoneProgressReport reports how far a progress has come.
+type WorkDoneProgressReport struct {
+	Kind        string `json:"kind"`
+	Cancellable bool   `json:"cancellable"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *int   `json:"percentage,omitempty"`
+}
+
+// WorkDoneProgressEnd ends a progress.
+type WorkDoneProgressEnd struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewWorkDoneProgressBegin creates and returns a new WorkDoneProgressBegin.
+//
+// Parameters:
+//
+//	title string - The title of the progress.
+//	cancellable bool - Whether the user may cancel the progress.
+//
+// Returns:
+//
+//	WorkDoneProgressBegin - The initialized value.
+func NewWorkDoneProgressBegin(title string, cancellable bool) WorkDoneProgressBegin {
+	percentage := 0
+	return WorkDoneProgressBegin{Kind: "begin", Title: title, Cancellable: cancellable, Percentage: &percentage}
+}
+
+// NewWorkDoneProgressReport creates and returns a new WorkDoneProgressReport.
+//
+// Parameters:
+//
+//	message string - The message shown next to the title.
+//	percentage int - How far the progress has come, from 0 to 100.
+//	cancellable bool - Whether the user may still cancel the progress.
+//
+// Returns:
+//
+//	WorkDoneProgressReport - The initialized value.
+func NewWorkDoneProgressReport(message string, percentage int, cancellable bool) WorkDoneProgressReport {
+	return WorkDoneProgressReport{Kind: "report", Message: message, Percentage: &percentage, Cancellable: cancellable}
+}
+
+// NewWorkDoneProgressEnd creates and returns a new WorkDoneProgressEnd.
+//
+// Parameters:
+//
+//	message string - The final message of the progress.
+//
+// Returns:
+//
+//	WorkDoneProgressEnd - The initialized value.
+func NewWorkDoneProgressEnd(message string) WorkDoneProgressEnd {
+	return WorkDoneProgressEnd{Kind: "end", Message: message}
+}
+
+// NewProgressNotification creates and returns a new ProgressNotification.
+//
+// Parameters:
+//
+//	token ProgressToken - The token of the progress.
+//	value any - The begin, report or end value.
+//
+// Returns:
+//
+//	ProgressNotification - The initialized notification.
+func NewProgressNotification(token ProgressToken, value any) ProgressNotification {
+	return ProgressNotification{
+		Notification: Notification{
+			RPC:    settings.RPC_VERSION,
+			Method: "$/progress",
+		},
+		Params: ProgressParams{
+			Token: token,
+			Value: value,
+		},
+	}
+}
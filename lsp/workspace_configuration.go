@@ -1,5 +1,7 @@
 package lsp
 
+import "encoding/json"
+
 type ConfigurationParams struct {
 	Items []ConfigurationItem `json:"items"`
 }
@@ -47,12 +49,30 @@ type WorkspaceConfigurationResponse struct {
 	Result []ConfigurationObject `json:"result"`
 }
 
-func NewWorkspaceConfigurationRequest(id int, params ConfigurationParams) WorkspaceConfigurationRequest {
-	return WorkspaceConfigurationRequest{
-		Request: Request{
-			ID:     id,
-			Method: "workspace/configuration",
-		},
-		Params: params,
+// parseConfiguration reads the kamaizen settings from settings sent by the client,
+// either under a "kamaizen" section or as the settings object itself.
+//
+// Parameters:
+//
+//	raw json.RawMessage - The settings sent by the client.
+//
+// Returns:
+//
+//	ConfigurationObject - The settings, empty if none were found.
+//	bool - Whether kamaizen settings were found.
+func parseConfiguration(raw json.RawMessage) (ConfigurationObject, bool) {
+	var config ConfigurationObject
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return config, false
+	}
+	if section, found := fields["kamaizen"]; found {
+		return config, json.Unmarshal(section, &config) == nil
+	}
+	for _, key := range []string{"kamailioSourcePath", "logLevel", "enableDeprecatedCommentHint"} {
+		if _, found := fields[key]; found {
+			return config, json.Unmarshal(raw, &config) == nil
+		}
 	}
+	return config, false
 }
@@ -0,0 +1,25 @@
+package lsp
+
+import "encoding/json"
+
+// DidChangeConfigurationNotification represents a notification sent by the client when its settings change.
+type DidChangeConfigurationNotification struct {
+	Notification
+	Params DidChangeConfigurationParams `json:"params"`
+}
+
+// DidChangeConfigurationParams contains the parameters for the DidChangeConfigurationNotification.
+// Clients that support workspace/configuration often send no settings and expect the server to pull them.
+type DidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+// Configuration returns the kamaizen settings sent with the notification.
+//
+// Returns:
+//
+//	ConfigurationObject - The settings, empty if none were sent.
+//	bool - Whether the notification held kamaizen settings.
+func (params DidChangeConfigurationParams) Configuration() (ConfigurationObject, bool) {
+	return parseConfiguration(params.Settings)
+}
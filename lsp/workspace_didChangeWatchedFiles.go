@@ -0,0 +1,38 @@
+package lsp
+
+// DidChangeWatchedFilesNotification represents the notification the client sends
+// when files watched by the server are created, changed or deleted.
+type DidChangeWatchedFilesNotification struct {
+	Notification
+	Params DidChangeWatchedFilesParams `json:"params"`
+}
+
+// DidChangeWatchedFilesParams contains the parameters for the DidChangeWatchedFilesNotification.
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+// FileChangeType describes how a watched file changed.
+type FileChangeType int
+
+const (
+	FILE_CREATED FileChangeType = iota + 1
+	FILE_CHANGED
+	FILE_DELETED
+)
+
+// FileEvent describes a change to a watched file.
+type FileEvent struct {
+	URI  DocumentURI    `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+// DidChangeWatchedFilesRegistrationOptions holds the files the client should watch for the server.
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+// FileSystemWatcher describes the files to watch by a glob pattern, e.g. **/*.cfg.
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
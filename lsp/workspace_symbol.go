@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+)
+
+// WorkspaceSymbolRequest represents a request to search the symbols of the workspace.
+// It contains the request metadata and the parameters for the workspace/symbol request.
+type WorkspaceSymbolRequest struct {
+	Request
+	Params WorkspaceSymbolParams `json:"params"`
+}
+
+// WorkspaceSymbolParams contains the parameters for the WorkspaceSymbolRequest.
+// The query is matched fuzzily against the names of the symbols; an empty query matches every symbol.
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// WorkspaceSymbolResponse represents the response to a WorkspaceSymbolRequest.
+// It contains the response metadata and the symbols that match the query.
+type WorkspaceSymbolResponse struct {
+	Response
+	Result []SymbolInformation `json:"result"`
+}
+
+// NewWorkspaceSymbolResponse creates and returns a new WorkspaceSymbolResponse.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	symbols []SymbolInformation - The symbols that match the query.
+//
+// Returns:
+//
+//	WorkspaceSymbolResponse - The initialized response.
+func NewWorkspaceSymbolResponse(id rpc.ID, symbols []SymbolInformation) WorkspaceSymbolResponse {
+	if symbols == nil {
+		symbols = []SymbolInformation{}
+	}
+	return WorkspaceSymbolResponse{
+		Response: Response{
+			RPC: settings.RPC_VERSION,
+			ID:  id,
+		},
+		Result: symbols,
+	}
+}
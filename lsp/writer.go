@@ -4,7 +4,6 @@ import (
 	"KamaiZen/logger"
 	"KamaiZen/rpc"
 	"io"
-	"os"
 	"sync"
 )
 
@@ -14,13 +13,31 @@ const buffered_channel_size = 24
 
 var writer_channel = make(chan []byte, buffered_channel_size)
 
+// writer_mu guards writer_closed, senders hold it for reading while they send
+// so that the channel is never closed under them.
+var (
+	writer_mu     sync.RWMutex
+	writer_closed bool
+)
+
 // WriteResponse encodes the given response and sends it to the writer channel.
+// Responses that can not be encoded and responses written after Stop are dropped.
 //
 // Parameters:
 //
 //	response interface{} - The response to be encoded and written.
 func WriteResponse(response interface{}) {
-	reply := rpc.EncodeMessage(response)
+	reply, err := rpc.EncodeMessage(response)
+	if err != nil {
+		logger.Error("Error encoding message: ", err)
+		return
+	}
+	writer_mu.RLock()
+	defer writer_mu.RUnlock()
+	if writer_closed {
+		logger.Error("Writer stopped, dropping message: ", reply)
+		return
+	}
 	writer_channel <- []byte(reply)
 }
 
@@ -30,11 +47,14 @@ func WriteResponse(response interface{}) {
 //
 //	message []byte - The message to be written.
 func Write(message []byte) {
-	writer.Write(message)
+	if _, err := writer.Write(message); err != nil {
+		logger.Error("Error writing message: ", err)
+	}
 }
 
 // Start starts the writer goroutine that listens for messages on the writer channel
-// and writes them to the writer. It signals the wait group when done.
+// and writes them to the writer. It returns once Stop has been called and every
+// queued message has been written, and signals the wait group when done.
 //
 // Parameters:
 //
@@ -42,15 +62,37 @@ func Write(message []byte) {
 func Start(wg *sync.WaitGroup) {
 	defer wg.Done()
 	logger.Info("Starting writer")
-	for {
-		select {
-		case message := <-writer_channel:
-			Write(message)
-		}
+	writer_mu.RLock()
+	messages := writer_channel
+	writer_mu.RUnlock()
+	for message := range messages {
+		Write(message)
 	}
+	logger.Info("Writer stopped")
 }
 
-// Initialise initializes the writer to use os.Stdout.
-func Initialise() {
-	writer = os.Stdout
+// Stop closes the writer channel. Messages already queued are still written by Start,
+// messages written afterwards are dropped.
+func Stop() {
+	writer_mu.Lock()
+	defer writer_mu.Unlock()
+	if !writer_closed {
+		writer_closed = true
+		close(writer_channel)
+	}
+}
+
+// Initialise initializes the writer to use the given io.Writer, usually os.Stdout
+// or the connection of a client. It must be called before Start, and may be called
+// again after Stop to start a new session.
+//
+// Parameters:
+//
+//	w io.Writer - The writer the messages are written to.
+func Initialise(w io.Writer) {
+	writer_mu.Lock()
+	defer writer_mu.Unlock()
+	writer = w
+	writer_channel = make(chan []byte, buffered_channel_size)
+	writer_closed = false
 }
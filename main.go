@@ -2,26 +2,64 @@ package main
 
 import (
 	"KamaiZen/logger"
-	"KamaiZen/lsp"
 	"KamaiZen/server"
-	"KamaiZen/state_manager"
-	"sync"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-func initialise() {
-	logger.Info("Starting KamaiZen")
-	state_manager.InitializeState()
-	lsp.Initialise()
+// listener returns the listener on the address given by --listen or --pipe,
+// nil if the client talks over stdio.
+func listener(listen string, pipe string) (net.Listener, error) {
+	if listen != "" && pipe != "" {
+		return nil, fmt.Errorf("--listen and --pipe can not be used together")
+	}
+	if listen == "" && pipe == "" {
+		return nil, nil
+	}
+	network, address := "unix", pipe
+	if listen != "" {
+		var err error
+		if network, address, err = server.ParseListenAddress(listen); err != nil {
+			return nil, err
+		}
+	}
+	return server.Listen(network, address)
 }
 
 func main() {
-	initialise()
-	defer logger.Info("KamaiZen stopped")
-	server := server.GetServerInstance()
+	listen := flag.String("listen", "", "listen for clients on `tcp://host:port` instead of stdio")
+	pipe := flag.String("pipe", "", "listen for clients on the unix socket at `path` instead of stdio")
+	flag.Bool("stdio", true, "communicate over stdin and stdout (default)")
+	flag.Parse()
+
+	logger.Info("Starting KamaiZen")
+	l, err := listener(*listen, *pipe)
+	if err != nil {
+		logger.Error("Error listening for clients: ", err)
+		fmt.Fprintln(os.Stderr, "KamaiZen:", err)
+		os.Exit(2)
+	}
+	if l == nil {
+		code := server.Serve(os.Stdin, os.Stdout)
+		logger.Info("KamaiZen stopped")
+		os.Exit(code)
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go server.StartServer(&wg)
-	go lsp.Start(&wg)
-	wg.Wait()
+	// a listening server keeps running across sessions until it is interrupted
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		l.Close()
+	}()
+	if err := server.ServeListener(l); err != nil {
+		logger.Error("Error accepting clients: ", err)
+		fmt.Fprintln(os.Stderr, "KamaiZen:", err)
+		os.Exit(1)
+	}
+	logger.Info("KamaiZen stopped")
 }
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ID represents the ID of a JSON-RPC request, which is either a number or a string.
+// The zero value is the null ID, used when replying to a message whose ID could not be read.
+// IDs are comparable and can be used as map keys.
+type ID struct {
+	value any // nil, int64 or string
+}
+
+// NewIntID creates and returns a numeric ID.
+func NewIntID(n int64) ID {
+	return ID{value: n}
+}
+
+// NewStringID creates and returns a string ID.
+func NewStringID(s string) ID {
+	return ID{value: s}
+}
+
+// IsNull reports whether the ID is the null ID.
+func (id ID) IsNull() bool {
+	return id.value == nil
+}
+
+// String returns the ID as it would appear in a log line.
+func (id ID) String() string {
+	switch v := id.value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return strconv.Quote(v)
+	}
+	return "null"
+}
+
+// MarshalJSON encodes the ID as a JSON number, string or null.
+func (id ID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.value)
+}
+
+// UnmarshalJSON decodes a JSON number, string or null into the ID.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		id.value = nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		id.value = s
+	default:
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid request id %s: %w", data, err)
+		}
+		id.value = n
+	}
+	return nil
+}
+
+// ErrorCode is the code of a JSON-RPC error.
+type ErrorCode int
+
+// Error codes defined by JSON-RPC 2.0 and the language server protocol.
+const (
+	ParseError           ErrorCode = -32700
+	InvalidRequest       ErrorCode = -32600
+	MethodNotFound       ErrorCode = -32601
+	InvalidParams        ErrorCode = -32602
+	InternalError        ErrorCode = -32603
+	ServerNotInitialized ErrorCode = -32002
+	UnknownErrorCode     ErrorCode = -32001
+	RequestFailed        ErrorCode = -32803
+	ServerCancelled      ErrorCode = -32802
+	ContentModified      ErrorCode = -32801
+	RequestCancelled     ErrorCode = -32800
+)
+
+// ResponseError represents the error member of a JSON-RPC response.
+// It implements the error interface so handlers can return it directly.
+type ResponseError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Data    any       `json:"data,omitempty"`
+}
+
+// NewError creates and returns a new ResponseError with the given code and message.
+func NewError(code ErrorCode, message string) *ResponseError {
+	return &ResponseError{Code: code, Message: message}
+}
+
+// Errorf creates and returns a new ResponseError with the given code and a formatted message.
+func Errorf(code ErrorCode, format string, args ...any) *ResponseError {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+// Error returns the error message together with its code.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// AsResponseError converts any error into a ResponseError.
+// Errors that are not already a ResponseError are reported as InternalError.
+func AsResponseError(err error) *ResponseError {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) {
+		return responseError
+	}
+	return NewError(InternalError, err.Error())
+}
@@ -8,33 +8,51 @@ import (
 	"strconv"
 )
 
-func EncodeMessage(message any) string {
+const _CONTENT_LENGTH_HEADER = "Content-Length"
+
+// EncodeMessage marshals the message and prefixes it with its Content-Length header.
+// It returns an error if the message can not be marshalled.
+func EncodeMessage(message any) (string, error) {
 	content, err := json.Marshal(message)
 	if err != nil {
-		// TODO: handle this more gracefully
-		panic(err)
+		return "", err
 	}
-	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content), nil
 }
 
 type BaseMessage struct {
 	Method string `json:"method"`
 }
 
+// contentLength reads the value of the Content-Length header from the header part of a message.
+// Other headers, such as Content-Type, are ignored.
+func contentLength(header []byte) (int, error) {
+	for _, line := range bytes.Split(header, []byte{'\r', '\n'}) {
+		name, value, found := bytes.Cut(line, []byte{':'})
+		if !found || !bytes.EqualFold(bytes.TrimSpace(name), []byte(_CONTENT_LENGTH_HEADER)) {
+			continue
+		}
+		return strconv.Atoi(string(bytes.TrimSpace(value)))
+	}
+	return 0, errors.New("Did not find the Content-Length header")
+}
+
 func DecodeMessage(msg []byte) (string, []byte, error) {
 	header, content, found := bytes.Cut(msg, []byte{'\r', '\n', '\r', '\n'})
 	if !found {
 		return "", nil, errors.New("Did not find the separator")
 	}
-	contentLengthBytes := bytes.Split(header, []byte{' '})[1]
-	contentLength, err := strconv.Atoi(string(contentLengthBytes))
+	contentLength, err := contentLength(header)
 	if err != nil {
 		return "", nil, err
 	}
+	if len(content) < contentLength {
+		return "", nil, errors.New("Message is shorter than its Content-Length")
+	}
 
 	var baseMessage BaseMessage
 	if err := json.Unmarshal(content[:contentLength], &baseMessage); err != nil {
-		return "", nil, err
+		return "", content[:contentLength], NewError(ParseError, err.Error())
 	}
 	return baseMessage.Method, content[:contentLength], nil
 }
@@ -44,8 +62,7 @@ func Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if !found {
 		return 0, nil, nil
 	}
-	contentLengthBytes := bytes.Split(header, []byte{' '})[1]
-	contentLength, err := strconv.Atoi(string(contentLengthBytes))
+	contentLength, err := contentLength(header)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -2,6 +2,7 @@ package rpc_test
 
 import (
 	"KamaiZen/rpc"
+	"encoding/json"
 	"testing"
 )
 
@@ -12,7 +13,10 @@ type EncodingExample struct {
 func TestEncode(t *testing.T) {
 	expected := "Content-Length: 18\r\n\r\n{\"Method\":\"hello\"}"
 	value := EncodingExample{Method: "hello"}
-	actual := rpc.EncodeMessage(value)
+	actual, err := rpc.EncodeMessage(value)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
 	if actual != expected {
 		t.Fatalf("Expected: %s,\ngot: %s", expected, actual)
 	}
@@ -33,3 +37,52 @@ func TestDecode(t *testing.T) {
 		t.Fatalf("Expected: hello,\ngot: %s", method)
 	}
 }
+
+func TestEncodeError(t *testing.T) {
+	if _, err := rpc.EncodeMessage(make(chan int)); err == nil {
+		t.Fatal("Expected an error for a value that can not be marshalled")
+	}
+}
+
+func TestDecodeWithContentType(t *testing.T) {
+	value := []byte("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\nContent-Length: 18\r\n\r\n{\"method\":\"hello\"}")
+	method, content, err := rpc.DecodeMessage(value)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if method != "hello" || len(content) != 18 {
+		t.Fatalf("Expected: hello with 18 bytes,\ngot: %s with %d bytes", method, len(content))
+	}
+}
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		json string
+		id   rpc.ID
+	}{
+		{`1`, rpc.NewIntID(1)},
+		{`0`, rpc.NewIntID(0)},
+		{`"abc"`, rpc.NewStringID("abc")},
+		{`null`, rpc.ID{}},
+	}
+	for _, test := range tests {
+		var id rpc.ID
+		if err := json.Unmarshal([]byte(test.json), &id); err != nil {
+			t.Fatalf("Error unmarshalling %s: %s", test.json, err)
+		}
+		if id != test.id {
+			t.Fatalf("Expected: %v,\ngot: %v", test.id, id)
+		}
+		encoded, err := json.Marshal(id)
+		if err != nil {
+			t.Fatalf("Error marshalling %v: %s", id, err)
+		}
+		if string(encoded) != test.json {
+			t.Fatalf("Expected: %s,\ngot: %s", test.json, encoded)
+		}
+	}
+	var id rpc.ID
+	if err := json.Unmarshal([]byte(`1.5`), &id); err == nil {
+		t.Fatal("Expected an error for a fractional ID")
+	}
+}
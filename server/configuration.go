@@ -0,0 +1,163 @@
+package server
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+	"KamaiZen/state_manager"
+	"context"
+	"encoding/json"
+)
+
+// handleDidChangeConfiguration handles the 'workspace/didChangeConfiguration' notification.
+// If the client supports workspace/configuration the settings are pulled again,
+// otherwise the settings sent with the notification are applied.
+// contents: The contents of the notification as a byte slice.
+func handleDidChangeConfiguration(ctx context.Context, contents []byte) error {
+	var notification lsp.DidChangeConfigurationNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling didChangeConfiguration notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	server := GetServerInstance()
+	if lsp.GetClientCapabilities().Workspace.Configuration {
+		logger.Debug("Configuration changed, pulling the kamaizen section")
+		server.pullConfiguration(server.reconfigure, nil)
+		return nil
+	}
+	config, found := notification.Params.Configuration()
+	if !found {
+		logger.Debug("Configuration changed without kamaizen settings")
+		return nil
+	}
+	server.reconfigure(config)
+	return nil
+}
+
+// pullConfiguration requests the kamaizen section of the client's settings.
+//
+// Parameters:
+//
+//	apply func(lsp.ConfigurationObject) - Called with the settings once they arrive.
+//	failed func() - Called instead if the client could not send them, may be nil.
+func (s *Server) pullConfiguration(apply func(lsp.ConfigurationObject), failed func()) {
+	params := lsp.ConfigurationParams{
+		Items: []lsp.ConfigurationItem{
+			{
+				Section: "kamaizen",
+			},
+		},
+	}
+	s.Call(MethodConfiguration, params, func(ctx context.Context, contents []byte) error {
+		var response lsp.WorkspaceConfigurationResponse
+		error := json.Unmarshal(contents, &response)
+		switch {
+		case error != nil:
+			logger.Error("Error unmarshalling workspace configuration response: ", error)
+		case response.Error != nil:
+			logger.Error("Client failed to send the configuration: ", response.Error)
+		case len(response.Result) == 0:
+			logger.Error("Client sent an empty configuration")
+		default:
+			apply(response.Result[0])
+			return nil
+		}
+		if failed != nil {
+			failed()
+		}
+		return nil
+	})
+}
+
+// initialise stores what the client sent with the initialize request.
+//
+// Parameters:
+//
+//	folders []lsp.WorkspaceFolder - The workspace folders opened in the client.
+//	options lsp.ConfigurationObject - The settings sent in initializationOptions.
+func (s *Server) initialise(folders []lsp.WorkspaceFolder, options lsp.ConfigurationObject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workspaceFolders = folders
+	s.initializationOptions = options
+}
+
+// WorkspaceFolders returns the workspace folders opened in the client.
+//
+// Returns:
+//
+//	[]lsp.WorkspaceFolder - The workspace folders, empty if the client opened a single file.
+func (s *Server) WorkspaceFolders() []lsp.WorkspaceFolder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workspaceFolders
+}
+
+// InitializationOptions returns the settings the client sent with the initialize request.
+//
+// Returns:
+//
+//	lsp.ConfigurationObject - The settings, empty if none were sent.
+func (s *Server) InitializationOptions() lsp.ConfigurationObject {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initializationOptions
+}
+
+// reconfigure applies settings the client changed after initialize.
+// The module documentation is loaded again if the Kamailio source path changed, and the
+// diagnostics of all open documents are refreshed since they depend on the settings.
+//
+// Parameters:
+//
+//	config lsp.ConfigurationObject - The new settings of the client.
+func (s *Server) reconfigure(config lsp.ConfigurationObject) {
+	previous := settings.GetSettings()
+	current := applySettings(config)
+	logger.Info("Configuration changed")
+	if current.KamailioSourcePath != previous.KamailioSourcePath {
+		s.loadKamailioDocs(current)
+	}
+	s.refreshDiagnostics()
+}
+
+// applySettings makes the settings of the client the settings in use.
+//
+// Parameters:
+//
+//	config lsp.ConfigurationObject - The settings of the client.
+//
+// Returns:
+//
+//	settings.LSPSettings - The settings in use.
+func applySettings(config lsp.ConfigurationObject) settings.LSPSettings {
+	lspSettings := settings.NewLSPSettings(config.KamailioSourcePath, "", config.Loglevel, config.EnableDeprecatedCommentHint)
+	logger.SetLogLevel(lspSettings.LogLevel)
+	return lspSettings
+}
+
+// refreshDiagnostics analyses every open document again and republishes its diagnostics.
+// Each document is refreshed on its own queue so that it does not race with its changes.
+// Documents whose queue is busy are refreshed too: a didOpen that is still queued or running
+// may analyse the document with the previous settings.
+func (s *Server) refreshDiagnostics() {
+	state := state_manager.GetState()
+	uris := make(map[lsp.DocumentURI]bool)
+	// the busy queues are read first, a didOpen that finishes in between is in the snapshot
+	for _, key := range s.scheduler.Keys() {
+		if key != "" {
+			uris[lsp.DocumentURI(key)] = true
+		}
+	}
+	for uri := range state.Snapshot().Documents {
+		uris[uri] = true
+	}
+	for uri := range uris {
+		s.scheduler.Schedule(string(uri), func() {
+			if diagnostics, open := state.RefreshDocument(uri); open {
+				publishDiagnostics(uri, diagnostics)
+			}
+		}, false)
+	}
+}
@@ -3,223 +3,521 @@ package server
 import (
 	"KamaiZen/logger"
 	"KamaiZen/lsp"
+	"KamaiZen/rpc"
 	"KamaiZen/settings"
 	"KamaiZen/state_manager"
+	"context"
 	"encoding/json"
+	"sync"
 )
 
 const (
-	MethodInitialize            = "initialize"
-	MethodInitialized           = "initialized"
-	MethodDidOpen               = "textDocument/didOpen"
-	MethodDidChange             = "textDocument/didChange"
-	MethodHover                 = "textDocument/hover"
-	MethodDefinition            = "textDocument/definition"
-	MethodFormatting            = "textDocument/formatting"
-	MethodCompletion            = "textDocument/completion"
-	MethodConfiguration         = "workspace/Configuration"
-	MethodConfigurationResponse = ""
+	MethodInitialize             = "initialize"
+	MethodInitialized            = "initialized"
+	MethodShutdown               = "shutdown"
+	MethodExit                   = "exit"
+	MethodCancelRequest          = "$/cancelRequest"
+	MethodDidOpen                = "textDocument/didOpen"
+	MethodDidChange              = "textDocument/didChange"
+	MethodDidClose               = "textDocument/didClose"
+	MethodDidSave                = "textDocument/didSave"
+	MethodHover                  = "textDocument/hover"
+	MethodDefinition             = "textDocument/definition"
+	MethodReferences             = "textDocument/references"
+	MethodPrepareRename          = "textDocument/prepareRename"
+	MethodRename                 = "textDocument/rename"
+	MethodDocumentSymbol         = "textDocument/documentSymbol"
+	MethodFoldingRange           = "textDocument/foldingRange"
+	MethodDocumentHighlight      = "textDocument/documentHighlight"
+	MethodSignatureHelp          = "textDocument/signatureHelp"
+	MethodPrepareCallHierarchy   = "textDocument/prepareCallHierarchy"
+	MethodIncomingCalls          = "callHierarchy/incomingCalls"
+	MethodOutgoingCalls          = "callHierarchy/outgoingCalls"
+	MethodSemanticTokensFull     = "textDocument/semanticTokens/full"
+	MethodSemanticTokensDelta    = "textDocument/semanticTokens/full/delta"
+	MethodSemanticTokensRange    = "textDocument/semanticTokens/range"
+	MethodWorkspaceSymbol        = "workspace/symbol"
+	MethodDidChangeWatchedFiles  = "workspace/didChangeWatchedFiles"
+	MethodRegisterCapability     = "client/registerCapability"
+	MethodFormatting             = "textDocument/formatting"
+	MethodCompletion             = "textDocument/completion"
+	MethodConfiguration          = "workspace/configuration"
+	MethodDidChangeConfiguration = "workspace/didChangeConfiguration"
+	MethodWorkDoneProgressCreate = "window/workDoneProgress/create"
+	MethodWorkDoneProgressCancel = "window/workDoneProgress/cancel"
 )
 
+// Handler handles a single incoming message.
+// The context of a request is cancelled when the client sends $/cancelRequest for it
+// or when the server exits; long running handlers should check it.
+// A request handler either writes its response and returns nil, or returns an error
+// which is sent to the client as the error of the response, see rpc.AsResponseError.
+type Handler func(ctx context.Context, contents []byte) error
+
 // EventManager manages event handlers for different methods.
+// Handlers may be registered while messages are being dispatched.
 type EventManager struct {
-	handlers map[string]func(contents []byte)
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// messageKey holds the fields of an incoming message that decide how it is scheduled.
+type messageKey struct {
+	ID     *rpc.ID `json:"id"`
+	Params struct {
+		TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	} `json:"params"`
 }
 
 // NewEventManager creates and returns a new EventManager instance.
 func NewEventManager() *EventManager {
 	return &EventManager{
-		handlers: make(map[string]func(contents []byte)),
+		handlers: make(map[string]Handler),
 	}
 }
 
 // RegisterHandler registers a handler function for a specific method.
 // method: The name of the method for which the handler is being registered.
-// handler: The function to handle the event. It takes an state_manager.State, the contents as a byte slice, and a channel for state_manager.State.
-func (em *EventManager) RegisterHandler(method string, handler func(contents []byte)) {
+// handler: The function to handle the event. It takes the context of the message and the contents as a byte slice.
+func (em *EventManager) RegisterHandler(method string, handler Handler) {
 	logger.Infof("Registering handler for method: %s", method)
+	em.mu.Lock()
+	defer em.mu.Unlock()
 	em.handlers[method] = handler
 }
 
+// Has reports whether a handler is registered for the given method.
+// method: The name of the method.
+func (em *EventManager) Has(method string) bool {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	_, found := em.handlers[method]
+	return found
+}
+
 // Dispatch calls the registered handler for the given method.
+// ctx: The context of the message, cancelled if the request is cancelled.
 // method: The name of the method for which the handler is being dispatched.
-// state: The current state of the state_manager.
 // contents: The contents to be passed to the handler as a byte slice.
-// analyser_channel: A channel for state_manager.State to communicate with the handler.
-func (em *EventManager) Dispatch(method string, contents []byte) {
-	if handler, found := em.handlers[method]; found {
-		handler(contents)
-	} else {
-		logger.Errorf("No handler found for method: %s", method)
-		logger.Error("Contents: ", string(contents))
+// Returns the error of the handler, or a MethodNotFound error if no handler is registered.
+func (em *EventManager) Dispatch(ctx context.Context, method string, contents []byte) error {
+	em.mu.RLock()
+	handler, found := em.handlers[method]
+	em.mu.RUnlock()
+	if !found {
+		return rpc.Errorf(rpc.MethodNotFound, "Method not found: %s", method)
 	}
+	return handler(ctx, contents)
 }
 
 // handleInitialized handles the 'initialized' notification.
-// state: The current state of the state_manager.
+// Once the client is ready for requests from the server, the settings are pulled from it
+// if it supports workspace/configuration, and the Kamailio module documentation is loaded.
 // contents: The contents of the notification as a byte slice.
-// analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleInitialized(contents []byte) {
+func handleInitialized(ctx context.Context, contents []byte) error {
 	var notification lsp.InitializedNotification
 	logger.Info("Received initialized notification ", string(contents))
 	if error := json.Unmarshal(contents, &notification); error != nil {
 		logger.Error("Error unmarshalling initialized notfication: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	server := GetServerInstance()
+	server.indexWorkspace()
+	if !lsp.GetClientCapabilities().Workspace.Configuration {
+		server.loadKamailioDocs(settings.GetSettings())
+		return nil
 	}
-	logger.Infof("Received initialized notification with %v", notification)
+	server.pullConfiguration(func(config lsp.ConfigurationObject) {
+		server.loadKamailioDocs(applySettings(config))
+	}, func() {
+		server.loadKamailioDocs(settings.GetSettings())
+	})
+	return nil
 }
 
 // handleInitialize handles the 'initialize' request.
-// state: The current state of the state_manager.
+// The capabilities of the client are stored so that replies can be adapted to them, and the
+// settings sent in initializationOptions are applied until the client's settings are pulled.
+// The request is answered right away; the module documentation is loaded after 'initialized'.
 // contents: The contents of the request as a byte slice.
-// analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleInitialize(contents []byte) {
+func handleInitialize(ctx context.Context, contents []byte) error {
 	var request lsp.InitializeRequest
 	logger.Info("Received initialize request ", string(contents))
 	if error := json.Unmarshal(contents, &request); error != nil {
 		logger.Error("Error unmarshalling initialize request: ", error)
-		return
-	}
-	logger.Infof("Connected to %s with version %s", request.Params.ClientInfo.Name, request.Params.ClientInfo.Version)
-	logger.Debug("Sending workspace configuration request")
-	config_request := lsp.NewWorkspaceConfigurationRequest(request.ID, lsp.ConfigurationParams{
-		Items: []lsp.ConfigurationItem{
-			{
-				Section: "kamaizen",
-			},
-		},
-	})
-	logger.Debug("Sent workspace configuration request", config_request)
-	lsp.WriteResponse(config_request)
-}
-
-func handleWorkspaceConfiguration(contents []byte) {
-	var response lsp.WorkspaceConfigurationResponse
-	if error := json.Unmarshal(contents, &response); error != nil {
-		logger.Error("Error unmarshalling workspace configuration response: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
-	var initialize_response lsp.InitializeResponse
-	initialize_response = lsp.NewInitializeResponse(response.ID)
-	lsp.WriteResponse(initialize_response)
+	params := request.Params
+	logger.Infof("Connected to %s with version %s", params.ClientInfo.Name, params.ClientInfo.Version)
+	lsp.SetClientCapabilities(params.Capabilities)
+	lsp.SetPositionEncoding(lsp.NegotiatePositionEncoding(params.Capabilities.General.PositionEncodings))
+	server := GetServerInstance()
+	config, _ := params.Configuration()
+	server.initialise(params.Folders(), config)
+	applySettings(config)
+	reply(ctx, request.ID, lsp.NewInitializeResponse(request.ID, server.capabilities()))
 	logger.Debug("Sent initialize response")
-	GetServerInstance().addKamailioMethods(settings.NewLSPSettings(response.Result[0].KamailioSourcePath, "", response.Result[0].Loglevel, response.Result[0].EnableDeprecatedCommentHint))
+	return nil
 }
 
 // handleDidOpen handles the 'didOpen' notification.
 // state: The current state of the state_manager.
 // contents: The contents of the notification as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleDidOpen(contents []byte) {
+func handleDidOpen(ctx context.Context, contents []byte) error {
 	var notification lsp.DidOpenTextDocumentNotification
 	if error := json.Unmarshal(contents, &notification); error != nil {
 		logger.Error("Error unmarshalling didOpen notification: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
 	logger.Info("Opened document with URI: ", notification.Params.TextDocument.URI)
-	dignostics := state_manager.GetState().OpenDocument(notification.Params.TextDocument.URI, notification.Params.TextDocument.Text)
-	if len(dignostics) > 0 {
-		lsp.WriteResponse(lsp.NewPublishDiagnosticNotification(notification.Params.TextDocument.URI, dignostics))
-	}
+	dignostics := state_manager.GetState().OpenDocument(notification.Params.TextDocument.URI, notification.Params.TextDocument.Text, notification.Params.TextDocument.Version)
+	publishDiagnostics(notification.Params.TextDocument.URI, dignostics)
+	return nil
 }
 
 // handleMessage handles incoming messages and dispatches them to the appropriate handler.
-// state: The current state of the state_manager.
+// ctx: The context of the message.
 // method: The name of the method for which the handler is being dispatched.
 // contents: The contents of the message as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
 // eventManager: The EventManager instance to use for dispatching the message.
-func handleMessage(method string, contents []byte, eventManager *EventManager) {
+// Returns the error of the handler, see EventManager.Dispatch.
+func handleMessage(ctx context.Context, method string, contents []byte, eventManager *EventManager) error {
 	logger.Info("Received message with method: ", method)
-	eventManager.Dispatch(method, contents)
+	return eventManager.Dispatch(ctx, method, contents)
 }
 
 // handleDidChange handles the 'didChange' notification.
 // state: The current state of the state_manager.
 // contents: The contents of the notification as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleDidChange(contents []byte) {
+func handleDidChange(ctx context.Context, contents []byte) error {
 	var notification lsp.DidChangeTextDocumentNotification
 	state := state_manager.GetState()
 	if error := json.Unmarshal(contents, &notification); error != nil {
 		logger.Error("Error unmarshalling didChange notification: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
-	for _, change := range notification.Params.ContentChanges {
-		diagnostics := state.UpdateDocument(notification.Params.TextDocument.URI, change.Text)
-		if len(diagnostics) > 0 {
-			logger.Debug("Sending diagnostics for document with URI: ", notification.Params.TextDocument.URI)
-			lsp.WriteResponse(lsp.NewPublishDiagnosticNotification(notification.Params.TextDocument.URI, diagnostics))
-		} else {
-			// clear diagnostics
-			logger.Debug("Clearing diagnostics for document with URI: ", notification.Params.TextDocument.URI)
-			lsp.WriteResponse(lsp.NewPublishDiagnosticNotification(notification.Params.TextDocument.URI, []lsp.Diagnostic{}))
-		}
+	uri := notification.Params.TextDocument.URI
+	diagnostics := state.ChangeDocument(uri, notification.Params.ContentChanges, notification.Params.TextDocument.Version)
+	publishDiagnostics(uri, diagnostics)
+	return nil
+}
 
+// handleDidClose handles the 'didClose' notification.
+// The document is evicted from the state and its diagnostics are cleared.
+// contents: The contents of the notification as a byte slice.
+func handleDidClose(ctx context.Context, contents []byte) error {
+	var notification lsp.DidCloseTextDocumentNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling didClose notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
+	uri := notification.Params.TextDocument.URI
+	logger.Info("Closed document with URI: ", uri)
+	state_manager.GetState().CloseDocument(uri)
+	publishDiagnostics(uri, nil)
+	return nil
+}
+
+// handleDidSave handles the 'didSave' notification.
+// The document is analysed again and its diagnostics are republished.
+// contents: The contents of the notification as a byte slice.
+func handleDidSave(ctx context.Context, contents []byte) error {
+	var notification lsp.DidSaveTextDocumentNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling didSave notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	uri := notification.Params.TextDocument.URI
+	logger.Info("Saved document with URI: ", uri)
+	diagnostics := state_manager.GetState().SaveDocument(uri, notification.Params.Text)
+	publishDiagnostics(uri, diagnostics)
+	return nil
+}
+
+// publishDiagnostics sends the diagnostics of the document with the given URI to the client.
+// An empty list clears the diagnostics shown by the client.
+// uri: The URI of the document.
+// diagnostics: The diagnostics of the document.
+func publishDiagnostics(uri lsp.DocumentURI, diagnostics []lsp.Diagnostic) {
+	if len(diagnostics) == 0 {
+		logger.Debug("Clearing diagnostics for document with URI: ", uri)
+		diagnostics = []lsp.Diagnostic{}
+	} else {
+		logger.Debug("Sending diagnostics for document with URI: ", uri)
+	}
+	lsp.WriteResponse(lsp.NewPublishDiagnosticNotification(uri, diagnostics))
+}
+
+// reply writes the response to a request.
+// If the request was cancelled while it was handled, the client is told so instead.
+// ctx: The context of the request.
+// id: The ID of the request.
+// response: The response to write.
+func reply(ctx context.Context, id rpc.ID, response any) {
+	if ctx.Err() != nil {
+		logger.Info("Request cancelled: ", id)
+		lsp.WriteResponse(lsp.NewErrorResponse(id, rpc.NewError(rpc.RequestCancelled, "Request cancelled")))
+		return
+	}
+	lsp.WriteResponse(response)
 }
 
 // handleHover handles the 'hover' request.
 // state: The current state of the state_manager.
 // contents: The contents of the request as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleHover(contents []byte) {
+func handleHover(ctx context.Context, contents []byte) error {
 	var request lsp.HoverRequest
 	if error := json.Unmarshal(contents, &request); error != nil {
 		logger.Error("Error unmarshalling hover request: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
 	logger.Debug("Hover request for document with URI: ", request.Params.TextDocument.URI)
 	logger.Debug("Position: ", request.Params.Position)
-	response := state_manager.GetState().Hover(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	GetServerInstance().docs.wait(ctx)
+	response := state_manager.GetState().Snapshot().Hover(request.ID, request.Params.TextDocument.URI, request.Params.Position)
 	logger.Infof("Sent hover response %v", response)
-	lsp.WriteResponse(response)
+	reply(ctx, request.ID, response)
+	return nil
 }
 
 // handleDefinition handles the 'definition' request.
 // state: The current state of the state_manager.
 // contents: The contents of the request as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleDefinition(contents []byte) {
+func handleDefinition(ctx context.Context, contents []byte) error {
 	var request lsp.DefinitionProviderRequest
 	if error := json.Unmarshal(contents, &request); error != nil {
 		logger.Error("Error unmarshalling definition request: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
 	logger.Debug("Definition request for document with URI: ", request.Params.TextDocument.URI)
 	logger.Debug("Position: ", request.Params.Position)
-	response := state_manager.GetState().Definition(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	response := state_manager.GetState().Snapshot().Definition(request.ID, request.Params.TextDocument.URI, request.Params.Position)
 	logger.Debug("Sent definition response %v", response)
-	lsp.WriteResponse(response)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleReferences handles the 'references' request.
+// contents: The contents of the request as a byte slice.
+func handleReferences(ctx context.Context, contents []byte) error {
+	var request lsp.ReferencesRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling references request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("References request for document with URI: ", request.Params.TextDocument.URI)
+	logger.Debug("Position: ", request.Params.Position)
+	response := state_manager.GetState().Snapshot().References(request.ID, request.Params.TextDocument.URI, request.Params.Position, request.Params.Context.IncludeDeclaration)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handlePrepareRename handles the 'prepareRename' request.
+// contents: The contents of the request as a byte slice.
+func handlePrepareRename(ctx context.Context, contents []byte) error {
+	var request lsp.PrepareRenameRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling prepareRename request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("PrepareRename request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().PrepareRename(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleRename handles the 'rename' request.
+// contents: The contents of the request as a byte slice.
+func handleRename(ctx context.Context, contents []byte) error {
+	var request lsp.RenameRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling rename request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("Rename request for document with URI: ", request.Params.TextDocument.URI, " to ", request.Params.NewName)
+	response, error := state_manager.GetState().Snapshot().Rename(request.ID, request.Params.TextDocument.URI, request.Params.Position, request.Params.NewName)
+	if error != nil {
+		return error
+	}
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleDocumentSymbol handles the 'documentSymbol' request.
+// contents: The contents of the request as a byte slice.
+func handleDocumentSymbol(ctx context.Context, contents []byte) error {
+	var request lsp.DocumentSymbolRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling documentSymbol request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("DocumentSymbol request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().DocumentSymbol(request.ID, request.Params.TextDocument.URI)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleDocumentHighlight handles the 'documentHighlight' request.
+// contents: The contents of the request as a byte slice.
+func handleDocumentHighlight(ctx context.Context, contents []byte) error {
+	var request lsp.DocumentHighlightRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling documentHighlight request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("DocumentHighlight request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().DocumentHighlight(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleSignatureHelp handles the 'signatureHelp' request.
+// contents: The contents of the request as a byte slice.
+func handleSignatureHelp(ctx context.Context, contents []byte) error {
+	var request lsp.SignatureHelpRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling signatureHelp request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("SignatureHelp request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().SignatureHelp(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handlePrepareCallHierarchy handles the 'prepareCallHierarchy' request.
+// contents: The contents of the request as a byte slice.
+func handlePrepareCallHierarchy(ctx context.Context, contents []byte) error {
+	var request lsp.PrepareCallHierarchyRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling prepareCallHierarchy request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("PrepareCallHierarchy request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().PrepareCallHierarchy(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleIncomingCalls handles the 'callHierarchy/incomingCalls' request.
+// contents: The contents of the request as a byte slice.
+func handleIncomingCalls(ctx context.Context, contents []byte) error {
+	var request lsp.CallHierarchyIncomingCallsRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling incomingCalls request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("IncomingCalls request for: ", request.Params.Item.Name)
+	response := state_manager.GetState().Snapshot().IncomingCalls(request.ID, request.Params.Item)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleOutgoingCalls handles the 'callHierarchy/outgoingCalls' request.
+// contents: The contents of the request as a byte slice.
+func handleOutgoingCalls(ctx context.Context, contents []byte) error {
+	var request lsp.CallHierarchyOutgoingCallsRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling outgoingCalls request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("OutgoingCalls request for: ", request.Params.Item.Name)
+	response := state_manager.GetState().Snapshot().OutgoingCalls(request.ID, request.Params.Item)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleFoldingRange handles the 'foldingRange' request.
+// contents: The contents of the request as a byte slice.
+func handleFoldingRange(ctx context.Context, contents []byte) error {
+	var request lsp.FoldingRangeRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling foldingRange request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("FoldingRange request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().FoldingRange(request.ID, request.Params.TextDocument.URI)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleSemanticTokensFull handles the 'semanticTokens/full' request.
+// contents: The contents of the request as a byte slice.
+func handleSemanticTokensFull(ctx context.Context, contents []byte) error {
+	var request lsp.SemanticTokensRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling semanticTokens request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("SemanticTokens request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().SemanticTokensFull(request.ID, request.Params.TextDocument.URI)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleSemanticTokensDelta handles the 'semanticTokens/full/delta' request.
+// contents: The contents of the request as a byte slice.
+func handleSemanticTokensDelta(ctx context.Context, contents []byte) error {
+	var request lsp.SemanticTokensDeltaRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling semanticTokens delta request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("SemanticTokens delta request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().SemanticTokensDelta(request.ID, request.Params.TextDocument.URI, request.Params.PreviousResultID)
+	reply(ctx, request.ID, response)
+	return nil
+}
+
+// handleSemanticTokensRange handles the 'semanticTokens/range' request.
+// contents: The contents of the request as a byte slice.
+func handleSemanticTokensRange(ctx context.Context, contents []byte) error {
+	var request lsp.SemanticTokensRangeRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling semanticTokens range request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("SemanticTokens range request for document with URI: ", request.Params.TextDocument.URI)
+	response := state_manager.GetState().Snapshot().SemanticTokensRange(request.ID, request.Params.TextDocument.URI, request.Params.Range)
+	reply(ctx, request.ID, response)
+	return nil
 }
 
 // handleFormatting handles the 'formatting' request.
 // state: The current state of the state_manager.
 // contents: The contents of the request as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleFormatting(contents []byte) {
+func handleFormatting(ctx context.Context, contents []byte) error {
 	var request lsp.DocumentFormattingRequest
 	if error := json.Unmarshal(contents, &request); error != nil {
 		logger.Error("Error unmarshalling formatting request: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
 	logger.Debug("Formatting request for document with URI: ", request.Params.TextDocument.URI)
-	response := state_manager.GetState().Formatting(request.ID, request.Params.TextDocument.URI, request.Params.Options)
+	response := state_manager.GetState().Snapshot().Formatting(request.ID, request.Params.TextDocument.URI, request.Params.Options)
 	logger.Debug("Sent formatting response %v", response)
-	lsp.WriteResponse(response)
+	reply(ctx, request.ID, response)
+	return nil
 }
 
 // handleCompletion handles the 'completion' request.
 // state: The current state of the state_manager.
 // contents: The contents of the request as a byte slice.
 // analyser_channel: A channel for state_manager.State to communicate with the handler.
-func handleCompletion(contents []byte) {
+func handleCompletion(ctx context.Context, contents []byte) error {
 	var request lsp.CompletionRequest
 	if error := json.Unmarshal(contents, &request); error != nil {
 		logger.Error("Error unmarshalling completion request: ", error)
-		return
+		return rpc.NewError(rpc.InvalidParams, error.Error())
 	}
 	logger.Debug("Completion request for document with URI: ", request.Params.TextDocument.URI)
-	response := state_manager.GetState().TextDocumentCompletion(request.ID, request.Params.TextDocument.URI, request.Params.Position)
-	lsp.WriteResponse(response)
+	GetServerInstance().docs.wait(ctx)
+	response := state_manager.GetState().Snapshot().TextDocumentCompletion(request.ID, request.Params.TextDocument.URI, request.Params.Position)
+	reply(ctx, request.ID, response)
+	return nil
 }
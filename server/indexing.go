@@ -0,0 +1,252 @@
+package server
+
+import (
+	"KamaiZen/document_manager"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// docs_wait_timeout bounds how long a request waits for the module documentation.
+// If indexing takes longer, the request is answered with the documentation loaded so far.
+const docs_wait_timeout = 10 * time.Second
+
+// docsIndex tracks the indexing of the Kamailio module documentation, which runs in the background.
+// Requests that need the documentation wait for it instead of answering with nothing.
+type docsIndex struct {
+	mu     sync.Mutex
+	ready  chan struct{}
+	cancel context.CancelFunc
+	token  *lsp.ProgressToken
+	wg     sync.WaitGroup
+}
+
+// newDocsIndex creates and returns a new docsIndex that is not ready,
+// the documentation is indexed once the client's settings are known.
+func newDocsIndex() *docsIndex {
+	return &docsIndex{ready: make(chan struct{})}
+}
+
+// begin stops the indexing that is still running, marks the documentation as loading
+// and returns the context of the new indexing. Calls to begin must not overlap.
+func (d *docsIndex) begin() context.Context {
+	d.stop()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.ready:
+		d.ready = make(chan struct{})
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.token = nil
+	d.wg.Add(1)
+	return ctx
+}
+
+// done marks the documentation as loaded and releases the waiting requests.
+func (d *docsIndex) done() {
+	d.mu.Lock()
+	select {
+	case <-d.ready:
+	default:
+		close(d.ready)
+	}
+	d.mu.Unlock()
+	d.wg.Done()
+}
+
+// stop cancels the indexing that is running, if any, and waits for it to return.
+func (d *docsIndex) stop() {
+	d.mu.Lock()
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// setToken records the token of the progress that reports the indexing.
+// token: The token of the progress.
+func (d *docsIndex) setToken(token lsp.ProgressToken) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.token = &token
+}
+
+// cancelProgress cancels the indexing if it is reported by the progress with the given token.
+// token: The token of the progress the user cancelled.
+func (d *docsIndex) cancelProgress(token lsp.ProgressToken) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.token != nil && *d.token == token && d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// wait blocks until the documentation is loaded, the context is cancelled or docs_wait_timeout passed.
+// ctx: The context of the request that needs the documentation.
+func (d *docsIndex) wait(ctx context.Context) {
+	d.mu.Lock()
+	ready := d.ready
+	d.mu.Unlock()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+	case <-time.After(docs_wait_timeout):
+		logger.Warn("Answering before the module documentation is loaded")
+	}
+}
+
+// loadKamailioDocs indexes the documentation of the Kamailio modules from the source tree
+// in the background, replacing the documentation indexed before. An indexing that is still
+// running is cancelled first.
+//
+// Parameters:
+//
+//	settings settings.LSPSettings - The settings holding the path of the Kamailio source.
+func (s *Server) loadKamailioDocs(settings settings.LSPSettings) {
+	ctx := s.docs.begin()
+	go func() {
+		defer s.docs.done()
+		s.indexKamailioDocs(ctx, settings)
+	}()
+}
+
+// indexKamailioDocs indexes the documentation of the Kamailio modules and reports its progress.
+// If there is nothing to index, the user is warned since hover and completion won't work.
+//
+// Parameters:
+//
+//	ctx context.Context - The context of the indexing, cancelled to stop it.
+//	settings settings.LSPSettings - The settings holding the path of the Kamailio source.
+func (s *Server) indexKamailioDocs(ctx context.Context, settings settings.LSPSettings) {
+	logger.Info("Kamailio src detected at: ", settings.KamailioSourcePath)
+	document_manager.Reset()
+	progress := s.createProgress(ctx, "Indexing Kamailio modules")
+	if progress != nil {
+		s.docs.setToken(progress.token)
+	}
+	indexed := 0
+	error := document_manager.Initialise(ctx, settings, func(current int, total int, module string) {
+		indexed = current
+		progress.report(fmt.Sprintf("module %d of %d: %s", current, total, module), current*100/total)
+	})
+	switch {
+	case error == nil:
+		logger.Infof("Indexed %d modules", indexed)
+		progress.end(fmt.Sprintf("Indexed %d modules", indexed))
+	case errors.Is(error, context.Canceled):
+		logger.Info("Indexing cancelled after ", indexed, " modules")
+		progress.end("Cancelled")
+	case errors.Is(error, document_manager.ErrNoSourcePath):
+		progress.end("")
+		showWarning("KamaiZen: kamailioSourcePath is not set, module documentation is not available")
+	case errors.Is(error, document_manager.ErrNoModules), errors.Is(error, fs.ErrNotExist):
+		progress.end("")
+		showWarning(fmt.Sprintf("KamaiZen: no Kamailio modules found in kamailioSourcePath %s", settings.KamailioSourcePath))
+	default:
+		progress.end("")
+		showWarning(fmt.Sprintf("KamaiZen: error indexing Kamailio modules: %s", error))
+	}
+	if error != nil {
+		logger.Error("Error loading Kamailio module documentation: ", error)
+	}
+}
+
+// showWarning shows a warning to the user.
+// message: The message to show.
+func showWarning(message string) {
+	lsp.WriteResponse(lsp.NewShowMessageNotification(lsp.MESSAGE_TYPE_WARNING, message))
+}
+
+// progress_create_timeout bounds how long the server waits for the client to create a progress.
+const progress_create_timeout = 5 * time.Second
+
+// workDoneProgress reports the progress of a long running task to the client.
+// A nil *workDoneProgress reports nothing, so callers need not check whether the client supports it.
+type workDoneProgress struct {
+	token lsp.ProgressToken
+}
+
+// createProgress asks the client to create a progress and begins it.
+//
+// Parameters:
+//
+//	ctx context.Context - The context of the task.
+//	title string - The title of the progress.
+//
+// Returns:
+//
+//	*workDoneProgress - The progress, nil if the client does not support it or did not create it.
+func (s *Server) createProgress(ctx context.Context, title string) *workDoneProgress {
+	if !lsp.GetClientCapabilities().Window.WorkDoneProgress {
+		return nil
+	}
+	token := s.nextRequestID()
+	created := make(chan bool, 1)
+	s.Call(MethodWorkDoneProgressCreate, lsp.WorkDoneProgressCreateParams{Token: token}, func(ctx context.Context, contents []byte) error {
+		var response lsp.Response
+		if error := json.Unmarshal(contents, &response); error != nil {
+			created <- false
+			return error
+		}
+		created <- response.Error == nil
+		return nil
+	})
+	select {
+	case ok := <-created:
+		if !ok {
+			logger.Error("Client failed to create progress: ", title)
+			return nil
+		}
+	case <-ctx.Done():
+		return nil
+	case <-time.After(progress_create_timeout):
+		logger.Error("Client did not create progress: ", title)
+		return nil
+	}
+	lsp.WriteResponse(lsp.NewProgressNotification(token, lsp.NewWorkDoneProgressBegin(title, true)))
+	return &workDoneProgress{token: token}
+}
+
+// report reports how far the task has come.
+// message: The message shown next to the title.
+// percentage: How far the task has come, from 0 to 100.
+func (p *workDoneProgress) report(message string, percentage int) {
+	if p == nil {
+		return
+	}
+	lsp.WriteResponse(lsp.NewProgressNotification(p.token, lsp.NewWorkDoneProgressReport(message, percentage, true)))
+}
+
+// end ends the progress.
+// message: The final message of the progress.
+func (p *workDoneProgress) end(message string) {
+	if p == nil {
+		return
+	}
+	lsp.WriteResponse(lsp.NewProgressNotification(p.token, lsp.NewWorkDoneProgressEnd(message)))
+}
+
+// handleWorkDoneProgressCancel handles the 'window/workDoneProgress/cancel' notification.
+// contents: The contents of the notification as a byte slice.
+func handleWorkDoneProgressCancel(ctx context.Context, contents []byte) error {
+	var notification lsp.WorkDoneProgressCancelNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling workDoneProgress/cancel notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Info("Progress cancelled by the user: ", notification.Params.Token)
+	GetServerInstance().docs.cancelProgress(notification.Params.Token)
+	return nil
+}
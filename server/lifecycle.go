@@ -0,0 +1,96 @@
+package server
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// requestTracker keeps the cancel functions of the requests that are in flight, keyed by their ID.
+type requestTracker struct {
+	mu      sync.Mutex
+	cancels map[rpc.ID]context.CancelFunc
+}
+
+// newRequestTracker creates and returns a new requestTracker without any requests.
+func newRequestTracker() *requestTracker {
+	return &requestTracker{
+		cancels: make(map[rpc.ID]context.CancelFunc),
+	}
+}
+
+// track registers a request and returns its context together with a function
+// that must be called once the request has been answered.
+// id: The ID of the request.
+func (r *requestTracker) track(id rpc.ID) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel cancels the request with the given ID, if it is still in flight.
+// id: The ID of the request.
+func (r *requestTracker) cancel(id rpc.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, found := r.cancels[id]; found {
+		cancel()
+	}
+}
+
+// cancelAll cancels every request that is still in flight.
+func (r *requestTracker) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+}
+
+// handleShutdown handles the 'shutdown' request.
+// The server answers with a null result and rejects every request that follows.
+// contents: The contents of the request as a byte slice.
+func handleShutdown(ctx context.Context, contents []byte) error {
+	var request lsp.ShutdownRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling shutdown request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Info("Received shutdown request")
+	GetServerInstance().shutdown.Store(true)
+	lsp.WriteResponse(lsp.NewShutdownResponse(request.ID))
+	return nil
+}
+
+// handleExit handles the 'exit' notification.
+// The read loop stops after this message, see StartServer.
+// contents: The contents of the notification as a byte slice.
+func handleExit(ctx context.Context, contents []byte) error {
+	logger.Info("Received exit notification, exit code ", GetServerInstance().ExitCode())
+	return nil
+}
+
+// handleCancelRequest handles the '$/cancelRequest' notification.
+// Cancellation is cooperative: the request's context is cancelled and its handler
+// answers with a RequestCancelled error instead of its result.
+// contents: The contents of the notification as a byte slice.
+func handleCancelRequest(ctx context.Context, contents []byte) error {
+	var notification lsp.CancelRequestNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling cancel notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("Cancelling request: ", notification.Params.ID)
+	GetServerInstance().requests.cancel(notification.Params.ID)
+	return nil
+}
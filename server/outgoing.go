@@ -0,0 +1,89 @@
+package server
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// pendingCalls keeps the handlers of the requests the server sent to the client,
+// keyed by the ID of the request, until their response arrives.
+type pendingCalls struct {
+	mu       sync.Mutex
+	handlers map[rpc.ID]Handler
+}
+
+// newPendingCalls creates and returns a new pendingCalls without any requests.
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{
+		handlers: make(map[rpc.ID]Handler),
+	}
+}
+
+// add registers the handler of the response to the request with the given ID.
+// id: The ID of the request.
+// handler: The handler of the response.
+func (p *pendingCalls) add(id rpc.ID, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[id] = handler
+}
+
+// take removes and returns the handler of the response to the request with the given ID.
+// id: The ID of the request.
+func (p *pendingCalls) take(id rpc.ID) (Handler, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	handler, found := p.handlers[id]
+	delete(p.handlers, id)
+	return handler, found
+}
+
+// Call sends a request to the client. The handler is called with the contents of the
+// response once it arrives; it runs on the queue of workspace-wide messages.
+// Responses are matched by ID, so any number of requests may be outstanding.
+//
+// Parameters:
+//
+//	method string - The method of the request.
+//	params any - The parameters of the request.
+//	handler Handler - The handler of the response, nil if the response is not needed.
+func (s *Server) Call(method string, params any, handler Handler) {
+	id := s.nextRequestID()
+	if handler != nil {
+		s.calls.add(id, handler)
+	}
+	logger.Debug("Sending request ", method, " with ID ", id)
+	lsp.WriteResponse(lsp.NewRequest(id, method, params))
+}
+
+// nextRequestID returns a new ID for a request sent by the server to the client.
+//
+// Returns:
+//
+//	rpc.ID - The ID of the request.
+func (s *Server) nextRequestID() rpc.ID {
+	return rpc.NewStringID(fmt.Sprintf("kamaizen/%d", s.lastID.Add(1)))
+}
+
+// handleResponse hands the response to a request sent by the server to its handler.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the response.
+//	contents []byte - The contents of the response.
+func (s *Server) handleResponse(id rpc.ID, contents []byte) {
+	handler, found := s.calls.take(id)
+	if !found {
+		logger.Debug("Ignoring response to unknown request: ", id)
+		return
+	}
+	s.scheduler.Schedule("", func() {
+		if error := handler(context.Background(), contents); error != nil {
+			logger.Error("Error handling response to request ", id, ": ", error)
+		}
+	}, false)
+}
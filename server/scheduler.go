@@ -0,0 +1,102 @@
+package server
+
+import (
+	"sync"
+)
+
+// Scheduler runs message handlers without blocking the reader of the input stream.
+// Jobs are queued by key, usually the URI of the document a message refers to.
+// Jobs with the same key start in the order they were scheduled, jobs with
+// different keys run concurrently.
+type Scheduler struct {
+	mu     sync.Mutex
+	queues map[string][]func()
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates and returns a new Scheduler instance.
+//
+// Returns:
+//
+//	*Scheduler - A new scheduler without any queued jobs.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		queues: make(map[string][]func()),
+	}
+}
+
+// Schedule queues a job under the given key.
+// A synchronous job must finish before the next job with the same key starts; this is
+// used for notifications that change state, such as didChange. An asynchronous job only
+// has to be started in order and then runs in its own goroutine; this is used for requests,
+// which read an immutable snapshot and may take a long time.
+//
+// Parameters:
+//
+//	key string - The key of the queue, an empty key is the queue for workspace-wide messages.
+//	job func() - The job to run.
+//	async bool - Whether the job may run concurrently with later jobs of the same key.
+func (s *Scheduler) Schedule(key string, job func(), async bool) {
+	s.wg.Add(1)
+	run := func() {
+		defer s.wg.Done()
+		job()
+	}
+	if async {
+		run = func() {
+			go func() {
+				defer s.wg.Done()
+				job()
+			}()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue, running := s.queues[key]
+	s.queues[key] = append(queue, run)
+	if !running {
+		go s.drain(key)
+	}
+}
+
+// drain runs the queued jobs of the given key until the queue is empty.
+//
+// Parameters:
+//
+//	key string - The key of the queue.
+func (s *Scheduler) drain(key string) {
+	for {
+		s.mu.Lock()
+		queue := s.queues[key]
+		if len(queue) == 0 {
+			delete(s.queues, key)
+			s.mu.Unlock()
+			return
+		}
+		job := queue[0]
+		s.queues[key] = queue[1:]
+		s.mu.Unlock()
+		job()
+	}
+}
+
+// Keys returns the keys whose queue has jobs waiting or a job running.
+//
+// Returns:
+//
+//	[]string - The keys of the busy queues.
+func (s *Scheduler) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.queues))
+	for key := range s.queues {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Wait blocks until every scheduled job has finished.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
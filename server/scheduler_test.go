@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"KamaiZen/server"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleKeepsOrderPerKey(t *testing.T) {
+	scheduler := server.NewScheduler()
+	var mu sync.Mutex
+	var order []int
+	for i := 0; i < 50; i++ {
+		scheduler.Schedule("file:///kamailio.cfg", func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, i)
+		}, false)
+	}
+	scheduler.Wait()
+	for i, actual := range order {
+		if actual != i {
+			t.Fatalf("Expected: %d,\ngot: %d", i, actual)
+		}
+	}
+}
+
+func TestScheduleRunsKeysConcurrently(t *testing.T) {
+	scheduler := server.NewScheduler()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	scheduler.Schedule("file:///slow.cfg", func() { <-release }, false)
+	scheduler.Schedule("file:///fast.cfg", func() { close(done) }, false)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Job for another key was blocked by a slow job")
+	}
+	close(release)
+	scheduler.Wait()
+}
+
+func TestScheduleAsyncDoesNotBlockQueue(t *testing.T) {
+	scheduler := server.NewScheduler()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	scheduler.Schedule("file:///kamailio.cfg", func() { <-release }, true)
+	scheduler.Schedule("file:///kamailio.cfg", func() { close(done) }, false)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Notification was blocked by a slow request")
+	}
+	close(release)
+	scheduler.Wait()
+}
+
+func TestKeysOfBusyQueues(t *testing.T) {
+	scheduler := server.NewScheduler()
+	release := make(chan struct{})
+	scheduler.Schedule("file:///kamailio.cfg", func() { <-release }, false)
+	if keys := scheduler.Keys(); len(keys) != 1 || keys[0] != "file:///kamailio.cfg" {
+		t.Fatalf("Expected the queue of the running job, got: %v", keys)
+	}
+	close(release)
+	scheduler.Wait()
+	// the queue is removed once its last job has returned
+	deadline := time.Now().Add(time.Second)
+	for len(scheduler.Keys()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no busy queue, got: %v", scheduler.Keys())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
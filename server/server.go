@@ -1,17 +1,42 @@
 package server
 
 import (
-	"KamaiZen/document_manager"
 	"KamaiZen/logger"
+	"KamaiZen/lsp"
 	"KamaiZen/rpc"
-	"KamaiZen/settings"
+	"KamaiZen/state_manager"
 	"bufio"
-	"os"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"sync"
+	"sync/atomic"
 )
 
+// max_message_size is the size of the largest message the server accepts.
+// didOpen carries the whole document, so it is much larger than bufio's default.
+const max_message_size = 64 * 1024 * 1024
+
 type Server struct {
 	eventManager *EventManager
+	scheduler    *Scheduler
+	requests     *requestTracker
+	calls        *pendingCalls
+	docs         *docsIndex
+	initialized  atomic.Bool
+	shutdown     atomic.Bool
+	lastID       atomic.Int64
+
+	// ctx is cancelled when the server stops; background tracks the work it must wait for
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+
+	mu                    sync.RWMutex
+	workspaceFolders      []lsp.WorkspaceFolder
+	initializationOptions lsp.ConfigurationObject
 }
 
 // create a single instance of the server
@@ -20,62 +45,294 @@ var serverInstance *Server
 // GetServerInstance returns the single instance of the server.
 func GetServerInstance() *Server {
 	if serverInstance == nil {
-		serverInstance = &Server{
-			eventManager: NewEventManager(),
-		}
+		serverInstance = newServer()
 	}
 	return serverInstance
 }
 
+// newServer creates and returns a new Server that has not received any message yet.
+func newServer() *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		eventManager: NewEventManager(),
+		scheduler:    NewScheduler(),
+		requests:     newRequestTracker(),
+		calls:        newPendingCalls(),
+		docs:         newDocsIndex(),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Serve runs a language server session that reads messages from the reader and writes
+// messages to the writer, using the same framing as rpc.Split. It blocks until the client
+// sends the 'exit' notification or the reader reaches its end.
+// The reader and writer may be stdio, a network connection, or pipes of an embedding program.
+// Each call starts from a fresh server and state, but sessions must not overlap.
+//
+// Parameters:
+//
+//	reader io.Reader - The stream of messages from the client.
+//	writer io.Writer - The stream of messages to the client.
+//
+// Returns:
+//
+//	int - The exit code, see ExitCode.
+func Serve(reader io.Reader, writer io.Writer) int {
+	state_manager.InitializeState()
+	lsp.Initialise(writer)
+	serverInstance = newServer()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go serverInstance.StartServer(&wg, reader)
+	go lsp.Start(&wg)
+	wg.Wait()
+	return serverInstance.ExitCode()
+}
+
 // StartServer starts the language server and listens for incoming messages from the client.
-// It initializes the event manager, registers handlers for various methods, and processes incoming messages.
+// It initializes the event manager, registers handlers for various methods, and hands incoming
+// messages to the scheduler so that the input stream is never blocked by a slow handler.
+// It returns after the 'exit' notification or the end of the input, once the server has stopped.
 //
 // Parameters:
 //
 //	wg *sync.WaitGroup - The wait group to signal when the server is done.
-//	analyser_channel chan state_manager.State - The channel for communicating with the state manager.
-func (s *Server) StartServer(wg *sync.WaitGroup) {
+//	reader io.Reader - The stream of messages from the client.
+func (s *Server) StartServer(wg *sync.WaitGroup, reader io.Reader) {
 	defer wg.Done()
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := bufio.NewScanner(reader)
 	logger.Info("Starting server")
 	scanner.Split(rpc.Split)
+	scanner.Buffer(make([]byte, 0, 64*1024), max_message_size)
 
 	// Initialize EventManager and register handlers
 	s.RegisterDefaultHandlers()
 
 	for scanner.Scan() {
-		msg := scanner.Bytes()
+		// the scanner reuses its buffer, and handlers keep the message after the next Scan
+		msg := bytes.Clone(scanner.Bytes())
 		method, contents, error := rpc.DecodeMessage(msg)
 		if error != nil {
 			logger.Error("Error decoding message: ", error)
+			var parseError *rpc.ResponseError
+			if errors.As(error, &parseError) {
+				// the ID of a message that is not valid JSON can't be read, so the reply has a null ID
+				lsp.WriteResponse(lsp.NewErrorResponse(rpc.ID{}, parseError))
+			}
 			continue
 		}
-		handleMessage(method, contents, s.eventManager)
+		s.schedule(method, contents)
+		if method == MethodExit {
+			break
+		}
+	}
+	if error := scanner.Err(); error != nil {
+		logger.Error("Error reading messages: ", error)
 	}
+	s.StopServer()
+}
+
+// schedule queues the message on the scheduler.
+// Messages about a document are queued under its URI so that they are handled in order.
+// Requests only wait for the messages before them to start and then run concurrently,
+// notifications and responses must finish before the next message of their queue.
+// Requests that fail are answered with an error; notifications never are.
+//
+// Parameters:
+//
+//	method string - The method of the message.
+//	contents []byte - The contents of the message.
+func (s *Server) schedule(method string, contents []byte) {
+	var key messageKey
+	if error := json.Unmarshal(contents, &key); error != nil {
+		logger.Error("Error unmarshalling message: ", error)
+		if key.ID != nil && method != "" {
+			lsp.WriteResponse(lsp.NewErrorResponse(*key.ID, rpc.NewError(rpc.InvalidRequest, error.Error())))
+		}
+		return
+	}
+	if method == MethodCancelRequest {
+		// cancellation must not wait behind the request it cancels
+		s.notify(context.Background(), method, contents)
+		return
+	}
+	if key.ID != nil && method == "" {
+		s.handleResponse(*key.ID, contents)
+		return
+	}
+	isRequest := key.ID != nil
+	if !isRequest {
+		if !s.initialized.Load() && method != MethodExit {
+			logger.Debug("Dropping notification before initialize: ", method)
+			return
+		}
+		s.scheduler.Schedule(string(key.Params.TextDocument.URI), func() {
+			s.notify(context.Background(), method, contents)
+		}, false)
+		return
+	}
+
+	id := *key.ID
+	if s.shutdown.Load() {
+		logger.Error("Received request after shutdown: ", method)
+		lsp.WriteResponse(lsp.NewErrorResponse(id, rpc.NewError(rpc.InvalidRequest, "Server is shutting down")))
+		return
+	}
+	if method == MethodInitialize {
+		s.initialized.Store(true)
+	} else if !s.initialized.Load() {
+		logger.Error("Received request before initialize: ", method)
+		lsp.WriteResponse(lsp.NewErrorResponse(id, rpc.NewError(rpc.ServerNotInitialized, "Server is not initialized")))
+		return
+	}
+	ctx, done := s.requests.track(id)
+	s.scheduler.Schedule(string(key.Params.TextDocument.URI), func() {
+		defer done()
+		if ctx.Err() != nil {
+			reply(ctx, id, nil)
+			return
+		}
+		if error := handleMessage(ctx, method, contents, s.eventManager); error != nil {
+			logger.Error("Error handling request ", method, ": ", error)
+			lsp.WriteResponse(lsp.NewErrorResponse(id, rpc.AsResponseError(error)))
+		}
+	}, method != MethodInitialize) // 'initialized' relies on what initialize stores
+}
+
+// notify handles a notification.
+// Errors are only logged, and unknown methods are ignored.
+//
+// Parameters:
+//
+//	ctx context.Context - The context of the message.
+//	method string - The method of the message.
+//	contents []byte - The contents of the message.
+func (s *Server) notify(ctx context.Context, method string, contents []byte) {
+	error := handleMessage(ctx, method, contents, s.eventManager)
+	if error == nil {
+		return
+	}
+	if rpc.AsResponseError(error).Code == rpc.MethodNotFound {
+		logger.Debug("Ignoring notification: ", method)
+		return
+	}
+	logger.Error("Error handling notification ", method, ": ", error)
 }
 
 func (s *Server) RegisterDefaultHandlers() {
 	s.RegisterHandler(MethodInitialize, handleInitialize)
 	s.RegisterHandler(MethodInitialized, handleInitialized)
+	s.RegisterHandler(MethodShutdown, handleShutdown)
+	s.RegisterHandler(MethodExit, handleExit)
+	s.RegisterHandler(MethodCancelRequest, handleCancelRequest)
 	s.RegisterHandler(MethodDidOpen, handleDidOpen)
 	s.RegisterHandler(MethodDidChange, handleDidChange)
+	s.RegisterHandler(MethodDidClose, handleDidClose)
+	s.RegisterHandler(MethodDidSave, handleDidSave)
+	s.RegisterHandler(MethodHover, handleHover)
 	s.RegisterHandler(MethodDefinition, handleDefinition)
+	s.RegisterHandler(MethodReferences, handleReferences)
+	s.RegisterHandler(MethodPrepareRename, handlePrepareRename)
+	s.RegisterHandler(MethodRename, handleRename)
+	s.RegisterHandler(MethodDocumentSymbol, handleDocumentSymbol)
+	s.RegisterHandler(MethodWorkspaceSymbol, handleWorkspaceSymbol)
+	s.RegisterHandler(MethodDocumentHighlight, handleDocumentHighlight)
+	s.RegisterHandler(MethodSignatureHelp, handleSignatureHelp)
+	s.RegisterHandler(MethodPrepareCallHierarchy, handlePrepareCallHierarchy)
+	s.RegisterHandler(MethodIncomingCalls, handleIncomingCalls)
+	s.RegisterHandler(MethodOutgoingCalls, handleOutgoingCalls)
+	s.RegisterHandler(MethodFoldingRange, handleFoldingRange)
+	s.RegisterHandler(MethodSemanticTokensFull, handleSemanticTokensFull)
+	s.RegisterHandler(MethodSemanticTokensDelta, handleSemanticTokensDelta)
+	s.RegisterHandler(MethodSemanticTokensRange, handleSemanticTokensRange)
+	s.RegisterHandler(MethodDidChangeWatchedFiles, handleDidChangeWatchedFiles)
+	s.RegisterHandler(MethodCompletion, handleCompletion)
 	s.RegisterHandler(MethodFormatting, handleFormatting)
-	s.RegisterHandler(MethodConfigurationResponse, handleWorkspaceConfiguration)
+	s.RegisterHandler(MethodDidChangeConfiguration, handleDidChangeConfiguration)
+	s.RegisterHandler(MethodWorkDoneProgressCancel, handleWorkDoneProgressCancel)
 }
 
+// StopServer stops the server gracefully.
+// In-flight requests are cancelled, every scheduled handler is waited for and the
+// writer is stopped once all pending responses have been queued, so that nothing
+// written by a handler is lost.
 func (s *Server) StopServer() {
 	logger.Info("Stopping server")
+	s.requests.cancelAll()
+	s.docs.stop()
+	s.cancel()
+	s.background.Wait()
+	s.scheduler.Wait()
+	lsp.Stop()
 }
 
-func (s *Server) RegisterHandler(method string, handler func(contents []byte)) {
+// ExitCode returns the code the process should exit with.
+// It is 0 if the client asked the server to shut down before exiting, and 1 otherwise.
+//
+// Returns:
+//
+//	int - The exit code.
+func (s *Server) ExitCode() int {
+	if s.shutdown.Load() {
+		return 0
+	}
+	return 1
+}
+
+func (s *Server) RegisterHandler(method string, handler Handler) {
 	s.eventManager.RegisterHandler(method, handler)
 }
 
-func (s *Server) addKamailioMethods(settings settings.LSPSettings) {
-	logger.Info("Kamailio src detected at: ", settings.KamailioSourcePath)
-	logger.Info("Adding Hover and Completion methods")
-	document_manager.Initialise(settings)
-	s.RegisterHandler(MethodHover, handleHover)
-	s.RegisterHandler(MethodCompletion, handleCompletion)
+// capabilities returns the capabilities of the server, derived from the registered handlers.
+//
+// Returns:
+//
+//	lsp.ServerCapabilities - The capabilities of the server.
+func (s *Server) capabilities() lsp.ServerCapabilities {
+	em := s.eventManager
+	capabilities := lsp.ServerCapabilities{
+		PositionEncoding: lsp.GetPositionEncoding(),
+		TextDocumentSync: lsp.TextDocumentSyncOptions{
+			OpenClose: em.Has(MethodDidOpen) && em.Has(MethodDidClose),
+			Change:    lsp.TEXT_DOCUMENT_SYNC_KIND_NONE,
+		},
+		HoverProvider:              em.Has(MethodHover),
+		DefinitionProvider:         em.Has(MethodDefinition),
+		ReferencesProvider:         em.Has(MethodReferences),
+		DocumentSymbolProvider:     em.Has(MethodDocumentSymbol),
+		WorkspaceSymbolProvider:    em.Has(MethodWorkspaceSymbol),
+		FoldingRangeProvider:       em.Has(MethodFoldingRange),
+		DocumentFormattingProvider: em.Has(MethodFormatting),
+		DocumentHighlightProvider:  em.Has(MethodDocumentHighlight),
+		CallHierarchyProvider:      em.Has(MethodPrepareCallHierarchy) && em.Has(MethodIncomingCalls) && em.Has(MethodOutgoingCalls),
+	}
+	if em.Has(MethodDidChange) {
+		capabilities.TextDocumentSync.Change = lsp.TEXT_DOCUMENT_SYNC_KIND_INCREMENTAL
+	}
+	if em.Has(MethodDidSave) {
+		capabilities.TextDocumentSync.Save = &lsp.SaveOptions{IncludeText: false}
+	}
+	if em.Has(MethodRename) {
+		capabilities.RenameProvider = true
+		if em.Has(MethodPrepareRename) && lsp.GetClientCapabilities().TextDocument.Rename.PrepareSupport {
+			capabilities.RenameProvider = lsp.RenameOptions{PrepareProvider: true}
+		}
+	}
+	if em.Has(MethodSemanticTokensFull) {
+		options := lsp.NewSemanticTokensOptions()
+		options.Range = em.Has(MethodSemanticTokensRange)
+		options.Full.Delta = em.Has(MethodSemanticTokensDelta)
+		capabilities.SemanticTokensProvider = &options
+	}
+	if em.Has(MethodSignatureHelp) {
+		options := lsp.NewSignatureHelpOptions()
+		capabilities.SignatureHelpProvider = &options
+	}
+	if em.Has(MethodCompletion) {
+		options := lsp.NewCompletionOptions()
+		capabilities.CompletionProvider = &options
+	}
+	return capabilities
 }
@@ -0,0 +1,260 @@
+package server_test
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/server"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// client drives a server that runs in-process over pipes.
+type client struct {
+	t       *testing.T
+	writer  io.Writer
+	scanner *bufio.Scanner
+}
+
+func (c *client) send(message string) {
+	encoded, err := rpc.EncodeMessage(json.RawMessage(message))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := io.WriteString(c.writer, encoded); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) receive() map[string]any {
+	if !c.scanner.Scan() {
+		c.t.Fatalf("Expected a message, got: %v", c.scanner.Err())
+	}
+	_, content, err := rpc.DecodeMessage(c.scanner.Bytes())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var message map[string]any
+	if err := json.Unmarshal(content, &message); err != nil {
+		c.t.Fatal(err)
+	}
+	return message
+}
+
+// serve starts a server in-process and returns a client connected to it,
+// together with a channel that receives the exit code of the server.
+func serve(t *testing.T) (*client, chan int) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	exitCode := make(chan int)
+	go func() {
+		exitCode <- server.Serve(serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+	scanner := bufio.NewScanner(clientReader)
+	scanner.Split(rpc.Split)
+	return &client{t: t, writer: clientWriter, scanner: scanner}, exitCode
+}
+
+// receiveResponse returns the response to the request with the given ID,
+// skipping the notifications the server sends in the meantime.
+func (c *client) receiveResponse(id any) map[string]any {
+	for {
+		message := c.receive()
+		if _, notification := message["method"]; notification && message["id"] == nil {
+			continue
+		}
+		if message["id"] != id {
+			c.t.Fatalf("Expected the response to %v, got: %v", id, message)
+		}
+		return message
+	}
+}
+
+// exit shuts the server down and checks that it exits cleanly.
+func (c *client) exit(exitCode chan int) {
+	c.send(`{"jsonrpc":"2.0","id":"bye","method":"shutdown"}`)
+	if message := c.receiveResponse("bye"); message["error"] != nil {
+		c.t.Fatalf("Expected the shutdown result, got: %v", message)
+	}
+	c.send(`{"jsonrpc":"2.0","method":"exit"}`)
+	if code := <-exitCode; code != 0 {
+		c.t.Fatalf("Expected: 0,\ngot: %d", code)
+	}
+}
+
+func TestServeInProcess(t *testing.T) {
+	c, exitCode := serve(t)
+
+	c.send(`{"jsonrpc":"2.0","id":"early","method":"textDocument/hover","params":{}}`)
+	if message := c.receive(); message["id"] != "early" || message["error"] == nil {
+		t.Fatalf("Expected an error for a request before initialize, got: %v", message)
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"configuration":true}}}}`)
+	if message := c.receive(); message["id"] != 1.0 || message["result"] == nil {
+		t.Fatalf("Expected the initialize result, got: %v", message)
+	}
+	c.send(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	request := c.receive()
+	id, _ := request["id"].(string)
+	if request["method"] != "workspace/configuration" || id == "" {
+		t.Fatalf("Expected a configuration request with its own ID, got: %v", request)
+	}
+
+	// a hover that arrives while the settings are pulled waits for the documentation
+	c.send(`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///kamailio.cfg"},"position":{"line":0,"character":0}}}`)
+	c.send(`{"jsonrpc":"2.0","id":"` + id + `","result":[{"kamailioSourcePath":"/nonexistent"}]}`)
+	if message := c.receive(); message["method"] != "window/showMessage" {
+		t.Fatalf("Expected a warning about the missing modules, got: %v", message)
+	}
+	if message := c.receive(); message["id"] != 2.0 || message["error"] != nil {
+		t.Fatalf("Expected the hover result, got: %v", message)
+	}
+
+	c.exit(exitCode)
+}
+
+func TestParseError(t *testing.T) {
+	c, exitCode := serve(t)
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize",`
+	if _, err := io.WriteString(c.writer, fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)); err != nil {
+		t.Fatal(err)
+	}
+	message := c.receive()
+	responseError, _ := message["error"].(map[string]any)
+	if id, found := message["id"]; !found || id != nil || responseError == nil || responseError["code"] != float64(rpc.ParseError) {
+		t.Fatalf("Expected a parse error with a null ID, got: %v", message)
+	}
+	// the server goes on with the next message
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"kamaizen":{"logLevel":1}}}}`)
+	if message := c.receive(); message["id"] != 1.0 || message["result"] == nil {
+		t.Fatalf("Expected the initialize result, got: %v", message)
+	}
+	c.exit(exitCode)
+}
+
+func TestInitializeNegotiatesCapabilities(t *testing.T) {
+	// the client does not support workspace/configuration, so initializationOptions are used
+	c, exitCode := serve(t)
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{
+		"capabilities":{"general":{"positionEncodings":["utf-16","utf-8"]}},
+		"initializationOptions":{"kamaizen":{"kamailioSourcePath":"/nonexistent"}}}}`)
+	message := c.receive()
+	result, _ := message["result"].(map[string]any)
+	if result == nil {
+		t.Fatalf("Expected the initialize result without a configuration request, got: %v", message)
+	}
+	capabilities := result["capabilities"].(map[string]any)
+	if capabilities["positionEncoding"] != "utf-8" {
+		t.Fatalf("Expected: utf-8,\ngot: %v", capabilities["positionEncoding"])
+	}
+	if capabilities["documentFormattingProvider"] != true || capabilities["hoverProvider"] != true {
+		t.Fatalf("Expected hover and formatting, got: %v", capabilities)
+	}
+	completion := capabilities["completionProvider"].(map[string]any)
+	if triggers := fmt.Sprint(completion["triggerCharacters"]); triggers != `[" ( $ { .]` {
+		t.Fatalf("Expected the completion trigger characters, got: %v", triggers)
+	}
+	c.exit(exitCode)
+}
+
+func TestDidChangeConfigurationRefreshesDiagnostics(t *testing.T) {
+	c, exitCode := serve(t)
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"kamaizen":{"logLevel":1}}}}`)
+	c.receive()
+	c.send(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///kamailio.cfg","languageId":"kamailio","version":1,"text":"# old style comment\n"}}}`)
+	c.send(`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":{"kamaizen":{"logLevel":1,"enableDeprecatedCommentHint":true}}}}`)
+	// didOpen publishes with the settings it saw, the refresh after it with the new ones
+	var diagnostics []any
+	for i := 0; i < 2; i++ {
+		message := c.receive()
+		params, _ := message["params"].(map[string]any)
+		if message["method"] != "textDocument/publishDiagnostics" || params == nil {
+			t.Fatalf("Expected diagnostics, got: %v", message)
+		}
+		diagnostics = params["diagnostics"].([]any)
+	}
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected the deprecated comment hint, got: %v", diagnostics)
+	}
+	c.exit(exitCode)
+}
+
+func TestIndexingReportsProgress(t *testing.T) {
+	source := t.TempDir()
+	for _, module := range []string{"dispatcher", "tm"} {
+		if err := os.MkdirAll(filepath.Join(source, "src", "modules", module), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	options, _ := json.Marshal(map[string]any{"kamaizen": map[string]any{"kamailioSourcePath": source}})
+
+	c, exitCode := serve(t)
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"window":{"workDoneProgress":true}},"initializationOptions":` + string(options) + `}}`)
+	c.receive()
+	c.send(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	request := c.receive()
+	params, _ := request["params"].(map[string]any)
+	if request["method"] != "window/workDoneProgress/create" || params == nil {
+		t.Fatalf("Expected a progress to be created, got: %v", request)
+	}
+	id, _ := json.Marshal(request["id"])
+	c.send(`{"jsonrpc":"2.0","id":` + string(id) + `,"result":null}`)
+
+	expected := []string{"begin", "report", "report", "end"}
+	for _, kind := range expected {
+		message := c.receive()
+		progress, _ := message["params"].(map[string]any)
+		if message["method"] != "$/progress" || progress["token"] != params["token"] {
+			t.Fatalf("Expected progress for token %v, got: %v", params["token"], message)
+		}
+		if value := progress["value"].(map[string]any); value["kind"] != kind {
+			t.Fatalf("Expected: %s,\ngot: %v", kind, value)
+		}
+	}
+	c.exit(exitCode)
+}
+
+func TestWorkspaceSymbolsOfUnopenedFiles(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "routing.cfg"), []byte("route[RELAY] {\n\texit;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := json.Marshal(folder)
+
+	c, exitCode := serve(t)
+	// initialized is sent before the result of initialize arrives, it must still see the folders
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file://` + string(root[1:len(root)-1]) + `",
+		"capabilities":{"workspace":{"didChangeWatchedFiles":{"dynamicRegistration":true}}}}}`)
+	c.send(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	if message := c.receive(); message["id"] != 1.0 {
+		t.Fatalf("Expected the initialize result, got: %v", message)
+	}
+	message := c.receive()
+	for message["method"] == "window/showMessage" {
+		message = c.receive()
+	}
+	if message["method"] != "client/registerCapability" {
+		t.Fatalf("Expected the file watcher to be registered, got: %v", message)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.send(`{"jsonrpc":"2.0","id":2,"method":"workspace/symbol","params":{"query":"relay"}}`)
+		// the warning about the missing Kamailio sources may come at any point
+		message := c.receiveResponse(2.0)
+		if symbols, _ := message["result"].([]any); len(symbols) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected route[RELAY] to be indexed, got: %v", message)
+		}
+		// the workspace is indexed in the background
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.exit(exitCode)
+}
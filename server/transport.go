@@ -0,0 +1,158 @@
+package server
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParseListenAddress splits a listen address such as tcp://127.0.0.1:9257 or unix:///tmp/kamaizen.sock
+// into the network and address expected by net.Listen.
+//
+// Parameters:
+//
+//	address string - The listen address.
+//
+// Returns:
+//
+//	string - The network, "tcp" or "unix".
+//	string - The address within the network.
+//	error - An error if the address is not a tcp or unix URL.
+func ParseListenAddress(address string) (string, string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("missing host:port in %s", address)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("missing socket path in %s", address)
+		}
+		return "unix", u.Path, nil
+	}
+	return "", "", fmt.Errorf("unsupported listen address %s, expected tcp://host:port or unix:///path", address)
+}
+
+// Listen listens on the given network and address for clients.
+// A stale socket file left behind by a previous server is replaced, but any other file at the
+// path of a unix socket is left alone and reported as an error. The socket file is removed
+// again when the listener is closed.
+//
+// Parameters:
+//
+//	network string - The network, "tcp" or "unix".
+//	address string - The address to listen on, host:port or the path of the socket.
+//
+// Returns:
+//
+//	net.Listener - The listener, to pass to ServeListener.
+//	error - An error if the path is taken by another file or the server can not listen.
+func Listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		info, err := os.Lstat(address)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		case info.Mode()&os.ModeSocket == 0:
+			return nil, fmt.Errorf("%s exists and is not a socket", address)
+		default:
+			if err := os.Remove(address); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// refuse_timeout bounds how long a refused client is waited for to send its first request.
+const refuse_timeout = 10 * time.Second
+
+// ServeListener accepts clients on the listener and runs a session for each of them, see Serve.
+// Sessions share the state of the server, so one client is served at a time: a client that
+// connects while another one is served gets an error in reply to its first request and is
+// disconnected, so that its editor reports it instead of waiting.
+// It returns once the listener is closed, e.g. when the process is asked to stop, and the
+// session in progress has ended.
+//
+// Parameters:
+//
+//	listener net.Listener - The listener returned by Listen.
+//
+// Returns:
+//
+//	error - The error that stopped the listener, nil if it was closed.
+func ServeListener(listener net.Listener) error {
+	logger.Infof("Waiting for clients on %s %s", listener.Addr().Network(), listener.Addr())
+	var busy atomic.Bool
+	var sessions sync.WaitGroup
+	defer sessions.Wait()
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !busy.CompareAndSwap(false, true) {
+			go refuse(conn)
+			continue
+		}
+		sessions.Add(1)
+		go func() {
+			defer sessions.Done()
+			logger.Info("Client connected from ", conn.RemoteAddr())
+			code := Serve(conn, conn)
+			// the session has stopped before its client sees the connection close and reconnects
+			busy.Store(false)
+			conn.Close()
+			logger.Infof("Client disconnected with exit code %d", code)
+		}()
+	}
+}
+
+// refuse answers the first request of a client that connects while another client is served
+// with an error, and closes the connection.
+//
+// Parameters:
+//
+//	conn net.Conn - The connection of the refused client.
+func refuse(conn net.Conn) {
+	defer conn.Close()
+	logger.Error("Refusing client from ", conn.RemoteAddr(), ": another client is connected")
+	conn.SetDeadline(time.Now().Add(refuse_timeout))
+	scanner := bufio.NewScanner(conn)
+	scanner.Split(rpc.Split)
+	scanner.Buffer(make([]byte, 0, 64*1024), max_message_size)
+	if !scanner.Scan() {
+		return
+	}
+	var key messageKey
+	_, contents, error := rpc.DecodeMessage(scanner.Bytes())
+	if error != nil || json.Unmarshal(contents, &key) != nil || key.ID == nil {
+		return
+	}
+	response, error := rpc.EncodeMessage(lsp.NewErrorResponse(*key.ID, rpc.NewError(rpc.RequestFailed, "KamaiZen is serving another client")))
+	if error != nil {
+		logger.Error("Error encoding the reply to a refused client: ", error)
+		return
+	}
+	io.WriteString(conn, response)
+}
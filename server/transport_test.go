@@ -0,0 +1,160 @@
+package server_test
+
+import (
+	"KamaiZen/rpc"
+	"KamaiZen/server"
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		host    string
+	}{
+		{"tcp://127.0.0.1:9257", "tcp", "127.0.0.1:9257"},
+		{"tcp://:9257", "tcp", ":9257"},
+		{"unix:///tmp/kamaizen.sock", "unix", "/tmp/kamaizen.sock"},
+	}
+	for _, test := range tests {
+		network, host, err := server.ParseListenAddress(test.address)
+		if err != nil {
+			t.Fatalf("Error parsing %s: %s", test.address, err)
+		}
+		if network != test.network || host != test.host {
+			t.Fatalf("Expected: %s %s,\ngot: %s %s", test.network, test.host, network, host)
+		}
+	}
+	for _, address := range []string{"127.0.0.1:9257", "udp://127.0.0.1:9257", "tcp://"} {
+		if _, _, err := server.ParseListenAddress(address); err == nil {
+			t.Fatalf("Expected an error for %s", address)
+		}
+	}
+}
+
+// dial connects to the server listening on the unix socket and returns a client for it.
+func dial(t *testing.T, path string) *client {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Error connecting to %s: %s", path, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	scanner := bufio.NewScanner(conn)
+	scanner.Split(rpc.Split)
+	return &client{t: t, writer: conn, scanner: scanner}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kamaizen.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// leave the socket file behind, as a server that crashed would
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := server.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got: %s", err)
+	}
+	stopped := make(chan error)
+	go func() { stopped <- server.ServeListener(listener) }()
+
+	for session := 0; session < 2; session++ {
+		c := dial(t, path)
+		c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"kamaizen":{"logLevel":1}}}}`)
+		if message := c.receive(); message["id"] != 1.0 || message["error"] != nil {
+			t.Fatalf("Expected the initialize result in session %d, got: %v", session, message)
+		}
+		c.send(`{"jsonrpc":"2.0","id":"bye","method":"shutdown"}`)
+		if message := c.receive(); message["id"] != "bye" || message["error"] != nil {
+			t.Fatalf("Expected the shutdown result in session %d, got: %v", session, message)
+		}
+		c.send(`{"jsonrpc":"2.0","method":"exit"}`)
+		if c.scanner.Scan() {
+			t.Fatalf("Expected the session %d to end, got: %s", session, c.scanner.Text())
+		}
+	}
+
+	listener.Close()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Expected the server to stop, got: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the server to stop once the listener is closed")
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the socket file to be removed, got: %v", err)
+	}
+}
+
+func TestListenRefusesSecondClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kamaizen.sock")
+	listener, err := server.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan error)
+	go func() { stopped <- server.ServeListener(listener) }()
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"initializationOptions":{"kamaizen":{"logLevel":1}}}}`
+
+	first := dial(t, path)
+	first.send(initialize)
+	if message := first.receive(); message["id"] != 1.0 || message["error"] != nil {
+		t.Fatalf("Expected the initialize result, got: %v", message)
+	}
+
+	second := dial(t, path)
+	second.send(initialize)
+	message := second.receive()
+	responseError, _ := message["error"].(map[string]any)
+	if message["id"] != 1.0 || responseError == nil || responseError["code"] != float64(rpc.RequestFailed) {
+		t.Fatalf("Expected the second client to be refused, got: %v", message)
+	}
+	if second.scanner.Scan() {
+		t.Fatalf("Expected the second client to be disconnected, got: %s", second.scanner.Text())
+	}
+
+	// the first client is still served
+	first.send(`{"jsonrpc":"2.0","id":"bye","method":"shutdown"}`)
+	if message := first.receiveResponse("bye"); message["error"] != nil {
+		t.Fatalf("Expected the shutdown result, got: %v", message)
+	}
+	first.send(`{"jsonrpc":"2.0","method":"exit"}`)
+	if first.scanner.Scan() {
+		t.Fatalf("Expected the session to end, got: %s", first.scanner.Text())
+	}
+
+	listener.Close()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Expected the server to stop, got: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the server to stop once the listener is closed")
+	}
+}
+
+func TestListenRefusesOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kamailio.cfg")
+	if err := os.WriteFile(path, []byte("request_route {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if listener, err := server.Listen("unix", path); err == nil {
+		listener.Close()
+		t.Fatalf("Expected an error listening on %s", path)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "request_route {}\n" {
+		t.Fatalf("Expected %s to be left alone, got: %q (%v)", path, content, err)
+	}
+}
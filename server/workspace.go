@@ -0,0 +1,94 @@
+package server
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// config_file_pattern is the glob of the files the client watches for the workspace index.
+const config_file_pattern = "**/*.cfg"
+
+// indexWorkspace indexes the configurations of the workspace folders in the background,
+// so that workspace/symbol finds symbols of files that are not open. The files are then
+// kept up to date on save and, if the client supports it, by watching them.
+func (s *Server) indexWorkspace() {
+	var folders []string
+	for _, folder := range s.WorkspaceFolders() {
+		if path, ok := folder.URI.Path(); ok {
+			folders = append(folders, path)
+		}
+	}
+	if len(folders) == 0 {
+		return
+	}
+	s.registerFileWatcher()
+	index := state_manager.GetState().Index()
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		if error := index.Build(s.ctx, folders); error != nil && !errors.Is(error, context.Canceled) {
+			logger.Error("Error indexing the workspace: ", error)
+		}
+	}()
+}
+
+// registerFileWatcher asks the client to report changes to the configurations of the workspace,
+// if it supports registering workspace/didChangeWatchedFiles.
+func (s *Server) registerFileWatcher() {
+	if !lsp.GetClientCapabilities().Workspace.DidChangeWatchedFiles.DynamicRegistration {
+		return
+	}
+	s.Call(MethodRegisterCapability, lsp.RegistrationParams{
+		Registrations: []lsp.Registration{{
+			ID:     "kamaizen/watchedFiles",
+			Method: MethodDidChangeWatchedFiles,
+			RegisterOptions: lsp.DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []lsp.FileSystemWatcher{{GlobPattern: config_file_pattern}},
+			},
+		}},
+	}, nil)
+}
+
+// handleDidChangeWatchedFiles handles the 'workspace/didChangeWatchedFiles' notification.
+// Changed configurations are indexed again and deleted ones are removed from the index.
+// contents: The contents of the notification as a byte slice.
+func handleDidChangeWatchedFiles(ctx context.Context, contents []byte) error {
+	var notification lsp.DidChangeWatchedFilesNotification
+	if error := json.Unmarshal(contents, &notification); error != nil {
+		logger.Error("Error unmarshalling didChangeWatchedFiles notification: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	index := state_manager.GetState().Index()
+	for _, change := range notification.Params.Changes {
+		path, ok := change.URI.Path()
+		if !ok || !state_manager.IsConfigFile(path) {
+			continue
+		}
+		logger.Debug("Watched file changed: ", change.URI)
+		if change.Type == lsp.FILE_DELETED {
+			index.Remove(change.URI)
+		} else {
+			index.Load(change.URI)
+		}
+	}
+	return nil
+}
+
+// handleWorkspaceSymbol handles the 'workspace/symbol' request.
+// contents: The contents of the request as a byte slice.
+func handleWorkspaceSymbol(ctx context.Context, contents []byte) error {
+	var request lsp.WorkspaceSymbolRequest
+	if error := json.Unmarshal(contents, &request); error != nil {
+		logger.Error("Error unmarshalling workspace/symbol request: ", error)
+		return rpc.NewError(rpc.InvalidParams, error.Error())
+	}
+	logger.Debug("Workspace symbol request: ", request.Params.Query)
+	response := state_manager.GetState().WorkspaceSymbol(request.ID, request.Params.Query)
+	reply(ctx, request.ID, response)
+	return nil
+}
@@ -1,12 +1,19 @@
 package settings
 
+import "sync"
+
 type LSPSettings struct {
 	KamailioSourcePath     string `json:"kamailioSourcePath"`
 	LogLevel               int    `json:"logLevel"`
 	DeprecatedCommentHints bool   `json:"deprecatedCommentHints"`
 }
 
-var GlobalSettings LSPSettings
+// GlobalSettings holds the settings in use. They are replaced when the client changes its
+// configuration while handlers read them, so access them through GetSettings.
+var (
+	GlobalSettings LSPSettings
+	settings_mu    sync.RWMutex
+)
 
 // NewLSPSettings creates and returns a new instance of LSPSettings.
 // It initializes the settings with the given Kamailio source path, root directory, and log level.
@@ -22,6 +29,8 @@ var GlobalSettings LSPSettings
 //
 //	LSPSettings - The initialized settings.
 func NewLSPSettings(kamailioSourcePath string, rootDir string, log_level int, dch bool) LSPSettings {
+	settings_mu.Lock()
+	defer settings_mu.Unlock()
 	GlobalSettings = LSPSettings{
 		KamailioSourcePath:     kamailioSourcePath,
 		LogLevel:               log_level,
@@ -30,6 +39,17 @@ func NewLSPSettings(kamailioSourcePath string, rootDir string, log_level int, dc
 	return GlobalSettings
 }
 
+// GetSettings returns the settings in use.
+//
+// Returns:
+//
+//	LSPSettings - The current settings.
+func GetSettings() LSPSettings {
+	settings_mu.RLock()
+	defer settings_mu.RUnlock()
+	return GlobalSettings
+}
+
 const RPC_VERSION = "2.0"
 const KAMAIZEN_VERSION = "0.0.1"
 const MY_NAME = "KamaiZen"
@@ -6,6 +6,7 @@ import (
 	"KamaiZen/logger"
 	"KamaiZen/lsp"
 	"log"
+	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
 )
@@ -61,16 +62,18 @@ func (s *StateTree) TraverseNode(uri lsp.DocumentURI, node *sitter.Node, logger
 	}
 }
 
-// GetNodeDocsAtPosition retrieves the documentation for the node at the given position in the source code.
+// GetNodeDocsAtPosition retrieves the documentation for the node at the given position in the document.
 // Parameters:
-// - uri: The URI of the document.
+// - document: The document to look up the node in.
 // - position: The position within the document.
-// - source_code: The source code as a byte slice.
 // Returns:
 // - The documentation string for the node at the specified position.
-func GetNodeDocsAtPosition(uri lsp.DocumentURI, position lsp.Position, source_code []byte) string {
-	node := GetState().Analyzer.GetAST().Node
-	nodeAtPosition := getNodeAtPosition(node, position)
+func GetNodeDocsAtPosition(document *Document, position lsp.Position) string {
+	source_code := document.Source()
+	if docs, found := getPseudoVariableDocs(document, position); found {
+		return docs
+	}
+	nodeAtPosition := document.NodeAt(position)
 	switch {
 	case nodeAtPosition == nil:
 		logger.Error("Node at position is nil")
@@ -78,30 +81,105 @@ func GetNodeDocsAtPosition(uri lsp.DocumentURI, position lsp.Position, source_co
 	case nodeAtPosition.Type() == kamailio_cfg.IdentifierNodeType:
 		functionName := getFunctionName(nodeAtPosition, source_code)
 		return document_manager.FindFunctionInAllModules(functionName)
+	case nodeAtPosition.Type() == kamailio_cfg.StringNodeType:
+		return getParameterDocs(nodeAtPosition, source_code)
 	}
 	return ""
 }
 
-// getNodeAtPosition finds the node at the specified position within the given AST node.
+// getParameterDocs returns the documentation of the module parameter named by the string,
+// if it is the parameter name of a modparam, e.g. "db_mode" in modparam("usrloc", "db_mode", 2).
+//
+// Parameters:
+//
+//	node *sitter.Node - The string node.
+//	source_code []byte - The source code of the document.
+//
+// Returns:
+//
+//	string - The documentation of the parameter, empty if there is none.
+func getParameterDocs(node *sitter.Node, source_code []byte) string {
+	modparam := node.Parent()
+	if modparam == nil || modparam.Type() != kamailio_cfg.ModparamNodeType || !isNode(modparam.ChildByFieldName("parameter_name"), node) {
+		return ""
+	}
+	module := modparam.ChildByFieldName("module_name")
+	if module == nil {
+		return ""
+	}
+	name := moduleName(module.Content(source_code))
+	parameterDoc, found := document_manager.GetParameterDoc(name, strings.Trim(node.Content(source_code), `"'`))
+	if !found {
+		return ""
+	}
+	return "# Module: " + name + "\n\n" + parameterDoc.String()
+}
+
+// getPseudoVariableDocs returns the documentation of the pseudo-variable at the given position,
+// including those written inside strings, e.g. $ru or $snd(ip), or of the transformation at the position,
+// e.g. s.len in $(ru{s.len}). Transformations written inside strings are not parsed, so they have no documentation.
+//
+// Parameters:
+//
+//	document *Document - The document to look up the pseudo-variable in.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	string - The documentation of the pseudo-variable or transformation, empty if it is not known.
+//	bool - False if there is no pseudo-variable at the position.
+func getPseudoVariableDocs(document *Document, position lsp.Position) (string, bool) {
+	root := document.Root()
+	if root == nil {
+		return "", false
+	}
+	source_code := document.Source()
+	cursor := offsetOfPoint(lineStarts(document.Text), document.PointAt(position))
+	text := ""
+	for _, variable := range pseudoVariables(root, source_code) {
+		// nested pseudo-variables come after the one they are nested in
+		if variable.start <= cursor && cursor <= variable.end {
+			text = string(source_code[variable.start:variable.end])
+		}
+	}
+	if text == "" {
+		return "", false
+	}
+	point := document.PointAt(position)
+	for node := root.NamedDescendantForPointRange(point, point); node != nil; node = node.Parent() {
+		if node.Type() == kamailio_cfg.PseudoVariableNodeType || node.Type() == kamailio_cfg.PseudoVariableExpressionNodeType {
+			// the position is on a pseudo-variable, e.g. one passed as argument to a transformation
+			break
+		}
+		if call, found := kamailio_cfg.TransformationCallOf(node, source_code); found {
+			transformation, found := kamailio_cfg.Transformations[call.Name]
+			if !found {
+				return "", true
+			}
+			return transformation.String(), true
+		}
+	}
+	variable, key, found := kamailio_cfg.LookupPseudoVariable(text)
+	if !found {
+		return "", true
+	}
+	if key != "" {
+		return variable.String() + "\n## Key:\n\t" + key + ": " + variable.Keys[key], true
+	}
+	return variable.String(), true
+}
+
+// getNodeAtPoint finds the node at the specified point within the given AST node.
 // Parameters:
 // - node: The root AST node.
-// - position: The position within the document.
+// - point: The point within the document, with its column in bytes.
 // Returns:
-// - The node at the specified position or nil if no such node exists.
-func getNodeAtPosition(node *sitter.Node, position lsp.Position) *sitter.Node {
+// - The node at the specified point or nil if no such node exists.
+func getNodeAtPoint(node *sitter.Node, point sitter.Point) *sitter.Node {
 	if node == nil {
 		return nil
 	}
-	nodeAtPosition := node.NamedDescendantForPointRange(
-		sitter.Point{
-			Row:    uint32(position.Line),
-			Column: uint32(position.Character),
-		},
-		sitter.Point{
-			Row:    uint32(position.Line),
-			Column: uint32(position.Character),
-		})
-	return nodeAtPosition
+	return node.NamedDescendantForPointRange(point, point)
 }
 
 // getFunctionName returns the name of the function at the given position in the AST node.
@@ -119,6 +197,9 @@ func getFunctionName(node *sitter.Node, source_code []byte) string {
 	if node == nil {
 		return ""
 	}
+	if node.Parent() == nil || node.Parent().Parent() == nil {
+		return ""
+	}
 	if node.Type() == kamailio_cfg.IdentifierNodeType &&
 		node.Parent().Parent().Type() == kamailio_cfg.CallExpressionNodeType &&
 		node.Parent().Parent().FieldNameForChild(0) == "function" {
@@ -159,56 +240,47 @@ func getAllAvailableKeywords() map[string]string {
 	return kamailio_cfg.SIPHeaders
 }
 
-// GetCompletionItems returns a list of completion items for the given document URI.
+// GetCompletionItems returns the completion items for the given position in a document,
+// depending on what is expected there, ranked by how they match the typed prefix.
 //
 // Parameters:
 //
+//	s *Snapshot - The snapshot holding the open documents.
 //	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position of the completion request.
 //
 // Returns:
 //
-//	[]lsp.CompletionItem - A list of completion items.
-func GetCompletionItems(uri lsp.DocumentURI) []lsp.CompletionItem {
+//	[]lsp.CompletionItem - A list of completion items, empty in comments and plain strings.
+func GetCompletionItems(s *Snapshot, uri lsp.DocumentURI, position lsp.Position) []lsp.CompletionItem {
+	document := s.GetDocument(uri)
+	if document == nil {
+		return nil
+	}
+	context := document.completionContextAt(position)
 	var completionItems []lsp.CompletionItem
-	functions := document_manager.GetAllAvailableFunctionDocs()
-	for _, function := range functions {
-		completionItems = append(completionItems, lsp.CompletionItem{
-			Detail:        function.Name + "(" + function.Parameters + ")",
-			Label:         function.Name + "(" + ")",
-			Documentation: function.Description + "\n" + function.Example,
-			Kind:          lsp.FUNCTION_COMPLETION,
-		})
-	}
-
-	keywords := getAllAvailableKeywords()
-	for header, description := range keywords {
-		completionItems = append(completionItems, lsp.CompletionItem{
-			Detail:        "SIP Header",
-			Label:         header,
-			Documentation: description,
-			Kind:          lsp.VARIABLE_COMPLETION,
-		})
-	}
-
-	variables := kamailio_cfg.GetGlobalVariables()
-	for variable, value := range variables {
-		completionItems = append(completionItems, lsp.CompletionItem{
-			Detail:        "AVP",
-			Label:         variable,
-			Documentation: value.GetGlobalVariableDocs(),
-			Kind:          lsp.VARIABLE_COMPLETION,
-		})
-	}
-
-	modules := document_manager.GetAllAvailableModules()
-	for _, module := range modules {
-		completionItems = append(completionItems, lsp.CompletionItem{
-			Detail:        "Module",
-			Label:         module,
-			Documentation: "Module " + module,
-			Kind:          lsp.MODULE_COMPLETION,
-		})
-	}
-	return completionItems
-
+	switch context.kind {
+	case complete_top_level:
+		completionItems = snippetCompletionItems(top_level_snippets, position, context.line)
+	case complete_code:
+		completionItems = append(functionCompletionItems(position, context.prefix), variableCompletionItems(s)...)
+		completionItems = append(completionItems, snippetCompletionItems(code_snippets, position, context.line)...)
+	case complete_module:
+		completionItems = moduleCompletionItems()
+	case complete_parameter:
+		completionItems = parameterCompletionItems(s, uri, context.module, context.prefix)
+	case complete_route:
+		completionItems = routeCompletionItems(s, uri, context.route)
+	case complete_header:
+		completionItems = headerCompletionItems()
+	case complete_pseudo_variable:
+		completionItems = pseudoVariableCompletionItems()
+	case complete_pseudo_variable_key:
+		completionItems = pseudoVariableKeyCompletionItems(context.variable)
+	case complete_transformation_class:
+		completionItems = transformationClassCompletionItems()
+	case complete_transformation:
+		completionItems = transformationCompletionItems(context.class)
+	}
+	return rankCompletionItems(completionItems, context.prefix)
 }
@@ -0,0 +1,209 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"path"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// routeBlock is a routing block of the call graph, with the routes it calls:
+// route(NAME) and the routes armed by t_on_failure, t_on_branch and t_on_reply.
+type routeBlock struct {
+	document *Document
+	node     *sitter.Node
+	kind     kamailio_cfg.SymbolKind
+	name     string
+	calls    []kamailio_cfg.Symbol
+}
+
+// is reports whether the block is the route of the given kind and name.
+func (b routeBlock) is(kind kamailio_cfg.SymbolKind, name string) bool {
+	return b.kind == kind && b.name == name
+}
+
+// item returns the block as an item of the call hierarchy, named like it is written,
+// e.g. route[RELAY] or request_route.
+func (b routeBlock) item() lsp.CallHierarchyItem {
+	item := lsp.CallHierarchyItem{
+		Name:  string(b.kind),
+		Kind:  lsp.FUNCTION_SYMBOL,
+		URI:   b.document.URI,
+		Range: b.document.RangeOf(b.node),
+	}
+	if b.kind == "event_route" {
+		item.Kind = lsp.EVENT_SYMBOL
+	}
+	if filePath, ok := b.document.URI.Path(); ok {
+		item.Detail = path.Base(filePath)
+	}
+	if name := b.node.ChildByFieldName("route_name"); name != nil {
+		item.Name += "[" + b.name + "]"
+		item.SelectionRange = b.document.RangeOf(name)
+	} else if keyword := b.node.ChildByFieldName("route"); keyword != nil {
+		item.SelectionRange = b.document.RangeOf(keyword)
+	}
+	return item
+}
+
+// routeOfItem returns the kind and name of the route of an item, e.g. route and RELAY for route[RELAY].
+func routeOfItem(item lsp.CallHierarchyItem) (kamailio_cfg.SymbolKind, string) {
+	kind, name, _ := strings.Cut(strings.TrimSuffix(item.Name, "]"), "[")
+	return kamailio_cfg.SymbolKind(kind), name
+}
+
+// routeBlocks returns the routing blocks of the document, also those inside #!ifdef regions.
+func (d *Document) routeBlocks() []routeBlock {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	source := d.Source()
+	var blocks []routeBlock
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		if node.Type() != kamailio_cfg.RoutingBlockNodeType {
+			for i := 0; i < int(node.NamedChildCount()); i++ {
+				visit(node.NamedChild(i))
+			}
+			return
+		}
+		keyword := node.ChildByFieldName("route")
+		if keyword == nil {
+			return
+		}
+		block := routeBlock{document: d, node: node, kind: kamailio_cfg.SymbolKind(keyword.Content(source))}
+		if name := node.ChildByFieldName("route_name"); name != nil {
+			block.name = name.Content(source)
+		}
+		for _, symbol := range kamailio_cfg.Symbols(node, source) {
+			if symbol.IsRoute() && !symbol.Declaration {
+				block.calls = append(block.calls, symbol)
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	visit(root)
+	return blocks
+}
+
+// routeGraph returns the routing blocks of the open documents and of the files they include,
+// those of the given document first.
+func (s *Snapshot) routeGraph(first lsp.DocumentURI) []routeBlock {
+	var blocks []routeBlock
+	for _, document := range s.workspace(first) {
+		blocks = append(blocks, document.routeBlocks()...)
+	}
+	return blocks
+}
+
+// PrepareCallHierarchy returns the routing block at the given position: the block whose header
+// is at the position, or the route declared or called at the position.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the prepareCallHierarchy request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.PrepareCallHierarchyResponse - The item of the block, with a null result if there is none.
+func (s *Snapshot) PrepareCallHierarchy(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.PrepareCallHierarchyResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("PrepareCallHierarchy request for document that is not open: ", uri)
+		return lsp.NewPrepareCallHierarchyResponse(id, nil)
+	}
+	if symbol, ok := document.SymbolAt(position); ok && symbol.IsRoute() {
+		for _, block := range s.routeGraph(uri) {
+			if block.is(symbol.Kind, symbol.Name) {
+				return lsp.NewPrepareCallHierarchyResponse(id, []lsp.CallHierarchyItem{block.item()})
+			}
+		}
+		return lsp.NewPrepareCallHierarchyResponse(id, nil)
+	}
+	for _, block := range document.routeBlocks() {
+		header := document.RangeOf(block.node)
+		if body := block.node.ChildByFieldName("body"); body != nil {
+			header.End = document.RangeOf(body).Start
+		}
+		if !before(position, header.Start) && before(position, header.End) {
+			return lsp.NewPrepareCallHierarchyResponse(id, []lsp.CallHierarchyItem{block.item()})
+		}
+	}
+	return lsp.NewPrepareCallHierarchyResponse(id, nil)
+}
+
+// IncomingCalls returns the routing blocks that call the route of the item, in the open documents
+// and the files they include.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the incomingCalls request.
+//	item lsp.CallHierarchyItem - The item returned by PrepareCallHierarchy.
+//
+// Returns:
+//
+//	lsp.CallHierarchyIncomingCallsResponse - The callers, with the ranges of the calls.
+func (s *Snapshot) IncomingCalls(id rpc.ID, item lsp.CallHierarchyItem) lsp.CallHierarchyIncomingCallsResponse {
+	kind, name := routeOfItem(item)
+	var calls []lsp.CallHierarchyIncomingCall
+	for _, block := range s.routeGraph(item.URI) {
+		var ranges []lsp.Range
+		for _, call := range block.calls {
+			if call.Kind == kind && call.Name == name {
+				ranges = append(ranges, block.document.RangeOfSymbol(call))
+			}
+		}
+		if len(ranges) > 0 {
+			calls = append(calls, lsp.CallHierarchyIncomingCall{From: block.item(), FromRanges: ranges})
+		}
+	}
+	return lsp.NewCallHierarchyIncomingCallsResponse(id, calls)
+}
+
+// OutgoingCalls returns the routing blocks called by the route of the item.
+// Routes that are called but not declared anywhere are left out.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the outgoingCalls request.
+//	item lsp.CallHierarchyItem - The item returned by PrepareCallHierarchy.
+//
+// Returns:
+//
+//	lsp.CallHierarchyOutgoingCallsResponse - The called routes, with the ranges of the calls in the item.
+func (s *Snapshot) OutgoingCalls(id rpc.ID, item lsp.CallHierarchyItem) lsp.CallHierarchyOutgoingCallsResponse {
+	kind, name := routeOfItem(item)
+	graph := s.routeGraph(item.URI)
+	var calls []lsp.CallHierarchyOutgoingCall
+	called := make(map[kamailio_cfg.Symbol]int)
+	for _, block := range graph {
+		if block.document.URI != item.URI || !block.is(kind, name) {
+			continue
+		}
+		for _, call := range block.calls {
+			target := kamailio_cfg.Symbol{Kind: call.Kind, Name: call.Name}
+			if i, found := called[target]; found {
+				calls[i].FromRanges = append(calls[i].FromRanges, block.document.RangeOfSymbol(call))
+				continue
+			}
+			for _, candidate := range graph {
+				if candidate.is(call.Kind, call.Name) {
+					called[target] = len(calls)
+					calls = append(calls, lsp.CallHierarchyOutgoingCall{
+						To:         candidate.item(),
+						FromRanges: []lsp.Range{block.document.RangeOfSymbol(call)},
+					})
+					break
+				}
+			}
+		}
+	}
+	return lsp.NewCallHierarchyOutgoingCallsResponse(id, calls)
+}
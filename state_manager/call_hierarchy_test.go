@@ -0,0 +1,89 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const call_hierarchy_cfg = `include_file "auth.cfg"
+request_route {
+	route(AUTH);
+	t_on_failure("FAIL");
+	route(AUTH);
+}
+failure_route[FAIL] {
+	route(AUTH);
+}
+event_route[xhttp:request] {
+	route("AUTH");
+	route(MISSING);
+}
+`
+
+const call_hierarchy_auth_cfg = `route[AUTH] {
+	t_on_reply("REPLY");
+}
+onreply_route[REPLY] {
+	exit;
+}
+`
+
+func TestCallHierarchy(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "auth.cfg"), []byte(call_hierarchy_auth_cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	main := lsp.NewDocumentURI(filepath.Join(directory, "kamailio.cfg"))
+	auth := lsp.NewDocumentURI(filepath.Join(directory, "auth.cfg"))
+	state := state_manager.NewState()
+	state.OpenDocument(main, call_hierarchy_cfg, 1)
+	snapshot := state.Snapshot()
+
+	prepared := snapshot.PrepareCallHierarchy(rpc.NewIntID(1), main, lsp.Position{Line: 2, Character: 8}).Result
+	if len(prepared) != 1 || prepared[0].Name != "route[AUTH]" || prepared[0].URI != auth || prepared[0].SelectionRange.Start != (lsp.Position{Line: 0, Character: 6}) {
+		t.Fatalf("Expected the declaration of route[AUTH], got: %v", prepared)
+	}
+	if header := snapshot.PrepareCallHierarchy(rpc.NewIntID(2), main, lsp.Position{Line: 9, Character: 3}).Result; len(header) != 1 || header[0].Name != "event_route[xhttp:request]" || header[0].Kind != lsp.EVENT_SYMBOL {
+		t.Fatalf("Expected the event route, got: %v", header)
+	}
+	if nothing := snapshot.PrepareCallHierarchy(rpc.NewIntID(3), main, lsp.Position{Line: 4, Character: 1}).Result; nothing != nil {
+		t.Fatalf("Expected no item, got: %v", nothing)
+	}
+
+	incoming := snapshot.IncomingCalls(rpc.NewIntID(4), prepared[0]).Result
+	callers := make(map[string]int)
+	for _, call := range incoming {
+		callers[call.From.Name] = len(call.FromRanges)
+	}
+	expected := map[string]int{"request_route": 2, "failure_route[FAIL]": 1, "event_route[xhttp:request]": 1}
+	if len(callers) != len(expected) {
+		t.Fatalf("Expected the callers %v, got: %v", expected, callers)
+	}
+	for name, count := range expected {
+		if callers[name] != count {
+			t.Errorf("Expected %d calls from %s, got: %d", count, name, callers[name])
+		}
+	}
+
+	outgoing := snapshot.OutgoingCalls(rpc.NewIntID(5), prepared[0]).Result
+	if len(outgoing) != 1 || outgoing[0].To.Name != "onreply_route[REPLY]" || outgoing[0].FromRanges[0].Start != (lsp.Position{Line: 1, Character: 13}) {
+		t.Fatalf("Expected a call to onreply_route[REPLY], got: %v", outgoing)
+	}
+
+	request := snapshot.PrepareCallHierarchy(rpc.NewIntID(6), main, lsp.Position{Line: 1, Character: 4}).Result
+	if len(request) != 1 {
+		t.Fatalf("Expected the request route, got: %v", request)
+	}
+	outgoing = snapshot.OutgoingCalls(rpc.NewIntID(7), request[0]).Result
+	if len(outgoing) != 2 || outgoing[0].To.Name != "route[AUTH]" || len(outgoing[0].FromRanges) != 2 || outgoing[1].To.Name != "failure_route[FAIL]" {
+		t.Fatalf("Expected calls to route[AUTH] and failure_route[FAIL], got: %v", outgoing)
+	}
+	event := snapshot.PrepareCallHierarchy(rpc.NewIntID(8), main, lsp.Position{Line: 9, Character: 1}).Result
+	if outgoing := snapshot.OutgoingCalls(rpc.NewIntID(9), event[0]).Result; len(outgoing) != 1 {
+		t.Fatalf("Expected the undeclared route to be left out, got: %v", outgoing)
+	}
+}
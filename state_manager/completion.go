@@ -0,0 +1,475 @@
+package state_manager
+
+import (
+	"KamaiZen/document_manager"
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"regexp"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// completionKind tells what is expected at the position of a completion request.
+type completionKind int
+
+const (
+	complete_none completionKind = iota
+	complete_top_level
+	complete_code
+	complete_module
+	complete_parameter
+	complete_route
+	complete_header
+	complete_pseudo_variable
+	complete_pseudo_variable_key
+	complete_transformation_class
+	complete_transformation
+)
+
+// completionContext is what is expected at the position of a completion request,
+// and the part of it already typed.
+type completionContext struct {
+	kind     completionKind
+	prefix   string
+	line     string                  // the text of the line before the position
+	module   string                  // the module whose parameters are expected
+	route    kamailio_cfg.SymbolKind // the kind of route whose names are expected
+	variable string                  // the pseudo-variable whose keys are expected, e.g. snd
+	class    string                  // the class of transformations whose functions are expected, e.g. s
+}
+
+// completion_prefix_pattern matches the part of a name typed before the position.
+var completion_prefix_pattern = regexp.MustCompile(`[\w.\-]*$`)
+
+// The patterns the line before the position is matched against when the parser could not make sense of it,
+// e.g. while a string or an argument list is still open.
+var (
+	open_loadmodule_pattern     = regexp.MustCompile(`\bloadmodule\s+"[^"]*$`)
+	open_modparam_pattern       = regexp.MustCompile(`\bmodparam\s*\(\s*"[^"]*$`)
+	open_parameter_pattern      = regexp.MustCompile(`\bmodparam\s*\(\s*"([^"]*)"\s*,\s*"[^"]*$`)
+	open_route_pattern          = regexp.MustCompile(`\broute\s*\(\s*"?[\w]*$`)
+	open_callback_pattern       = regexp.MustCompile(`\b(\w+)\s*\(\s*"[\w]*$`)
+	open_header_pattern         = regexp.MustCompile(`\$\(?hdr\([\w\-]*$`)
+	open_variable_pattern       = regexp.MustCompile(`\$\(?\w*$`)
+	open_variable_key_pattern   = regexp.MustCompile(`\$\(?(\w+)\(\w*$`)
+	open_transformation_pattern = regexp.MustCompile(`\$\((?:[^(){}]|\([^()]*\)|\{[^{}]*\})*\{(?:(\w+)\.)?([\w.]*)$`)
+	open_comment_pattern        = regexp.MustCompile(`(^|\s)(#([^!]|$)|//)`)
+	open_statement_pattern      = regexp.MustCompile(`^\s*(#!)?\w*$`)
+)
+
+// completionContextAt returns what is expected at the given position, going by the node before it.
+//
+// Parameters:
+//
+//	position lsp.Position - The position of the completion request.
+//
+// Returns:
+//
+//	completionContext - What is expected at the position.
+func (d *Document) completionContextAt(position lsp.Position) completionContext {
+	lines := lineStarts(d.Text)
+	point := d.PointAt(position)
+	cursor := offsetOfPoint(lines, point)
+	source := d.Source()
+	line := string(source[offsetOfPoint(lines, sitter.Point{Row: point.Row}):cursor])
+	context := completionContext{kind: complete_none, prefix: completion_prefix_pattern.FindString(line), line: line}
+
+	root := d.Root()
+	if root == nil {
+		return context
+	}
+	before := point
+	if before.Column > 0 {
+		before.Column--
+	}
+	for node := root.NamedDescendantForPointRange(before, before); node != nil; node = node.Parent() {
+		if node.EndByte() == cursor && node.EndByte() > node.StartByte() && strings.ContainsRune(`)"'}]`, rune(source[cursor-1])) {
+			// the position is after the node, e.g. after the closing quote of a string
+			continue
+		}
+		switch node.Type() {
+		case kamailio_cfg.CommentNodeType, kamailio_cfg.MultilineCommentNodeType:
+			return context
+		case kamailio_cfg.ErrorNodeType:
+			context = lineCompletionContext(line, context)
+			if parent := node.Parent(); context.kind == complete_code && parent != nil && parent.Parent() == nil &&
+				node.StartPoint().Row == point.Row && open_statement_pattern.MatchString(line) {
+				// a statement being typed at the top level, rather than a routing block the parser gave up on
+				context.kind = complete_top_level
+			}
+			return context
+		case kamailio_cfg.HdrNodeType:
+			context.kind = complete_header
+			return context
+		case kamailio_cfg.RouteCallNodeType:
+			context.kind, context.route = complete_route, kamailio_cfg.SYMBOL_KIND_ROUTE
+			return context
+		case kamailio_cfg.StringNodeType:
+			if parent := node.Parent(); parent != nil && parent.Type() == kamailio_cfg.ErrorNodeType {
+				return lineCompletionContext(line, context)
+			}
+			return stringCompletionContext(node, source, line, context)
+		case kamailio_cfg.PseudoVariableNodeType, kamailio_cfg.PseudoVariableExpressionNodeType:
+			context, _ = variableCompletionContext(string(source[node.StartByte():cursor]), context)
+			return context
+		case kamailio_cfg.RoutingBlockNodeType:
+			if body := node.ChildByFieldName("body"); body != nil && body.StartByte() < cursor {
+				context.kind = complete_code
+			}
+			return context
+		}
+	}
+	// the position is outside of the routing blocks
+	if open_statement_pattern.MatchString(line) {
+		context.kind = complete_top_level
+	}
+	return context
+}
+
+// stringCompletionContext returns what is expected within a string: a module name in loadmodule
+// and modparam, the name of a parameter in modparam, the name of a route passed to a route callback
+// or a pseudo-variable after a $. Nothing is expected within other strings.
+func stringCompletionContext(node *sitter.Node, source []byte, line string, context completionContext) completionContext {
+	parent := node.Parent()
+	if parent == nil {
+		return context
+	}
+	if context, found := variableCompletionContext(line, context); found {
+		return context
+	}
+	switch {
+	case isModuleName(node):
+		context.kind = complete_module
+	case parent.Type() == kamailio_cfg.ModparamNodeType && isNode(parent.ChildByFieldName("parameter_name"), node):
+		if module := parent.ChildByFieldName("module_name"); module != nil {
+			context.kind, context.module = complete_parameter, moduleName(module.Content(source))
+		}
+	case parent.Type() == kamailio_cfg.RouteCallNodeType:
+		context.kind, context.route = complete_route, kamailio_cfg.SYMBOL_KIND_ROUTE
+	default:
+		for _, symbol := range kamailio_cfg.Symbols(node, source) {
+			if symbol.IsRoute() {
+				context.kind, context.route = complete_route, symbol.Kind
+			}
+		}
+	}
+	return context
+}
+
+// lineCompletionContext returns what is expected after the text of the line before the position,
+// for the parts of a document the parser could not make sense of.
+func lineCompletionContext(line string, context completionContext) completionContext {
+	switch {
+	case open_comment_pattern.MatchString(line) && strings.Count(line, `"`)%2 == 0:
+		return context
+	case open_loadmodule_pattern.MatchString(line), open_modparam_pattern.MatchString(line):
+		context.kind = complete_module
+	case open_parameter_pattern.MatchString(line):
+		context.kind = complete_parameter
+		context.module = moduleName(open_parameter_pattern.FindStringSubmatch(line)[1])
+	case open_route_pattern.MatchString(line):
+		context.kind, context.route = complete_route, kamailio_cfg.SYMBOL_KIND_ROUTE
+	case open_header_pattern.MatchString(line):
+		context.kind = complete_header
+	case open_variable_pattern.MatchString(line), open_variable_key_pattern.MatchString(line), open_transformation_pattern.MatchString(line):
+		context, _ = variableCompletionContext(line, context)
+	case open_callback_pattern.MatchString(line):
+		if kind, found := kamailio_cfg.RouteCallbacks[open_callback_pattern.FindStringSubmatch(line)[1]]; found {
+			context.kind, context.route = complete_route, kind
+		}
+	case strings.Count(line, `"`)%2 == 0:
+		context.kind = complete_code
+	}
+	return context
+}
+
+// variableCompletionContext returns what is expected after the text before the position
+// if it ends within a pseudo-variable: the name of a pseudo-variable after the $, one of the keys
+// of the pseudo-variables taking a key, e.g. ip in $snd(ip), or a transformation after a {, e.g. s.len
+// in $(ru{s.len}). Nothing is expected within other arguments.
+//
+// Parameters:
+//
+//	text string - The text before the position.
+//	context completionContext - The context to fill in.
+//
+// Returns:
+//
+//	completionContext - What is expected at the position.
+//	bool - False if the text does not end within a pseudo-variable.
+func variableCompletionContext(text string, context completionContext) (completionContext, bool) {
+	switch {
+	case open_transformation_pattern.MatchString(text):
+		match := open_transformation_pattern.FindStringSubmatch(text)
+		if match[1] == "" {
+			context.kind = complete_transformation_class
+		} else {
+			// the labels are the names of the functions, without their class
+			context.kind, context.class, context.prefix = complete_transformation, match[1], match[2]
+		}
+	case open_variable_pattern.MatchString(text):
+		context.kind = complete_pseudo_variable
+	case open_header_pattern.MatchString(text):
+		context.kind = complete_header
+	case open_variable_key_pattern.MatchString(text):
+		name := open_variable_key_pattern.FindStringSubmatch(text)[1]
+		if len(kamailio_cfg.PseudoVariables[name].Keys) > 0 {
+			context.kind, context.variable = complete_pseudo_variable_key, name
+		}
+	default:
+		return context, false
+	}
+	return context, true
+}
+
+// functionCompletionItems returns the functions of the core and of the modules.
+// Clients supporting snippets get calls with placeholders for the mandatory parameters, replacing the typed prefix.
+func functionCompletionItems(position lsp.Position, prefix string) []lsp.CompletionItem {
+	snippets := lsp.GetClientCapabilities().SnippetSupport()
+	var items []lsp.CompletionItem
+	functions := append(document_manager.GetCoreFunctionDocs(), document_manager.GetAllAvailableFunctionDocs()...)
+	for _, function := range functions {
+		item := lsp.CompletionItem{
+			Detail:        function.Name + "(" + function.Parameters + ")",
+			Label:         function.Name + "(" + ")",
+			Documentation: lsp.NewCompletionDocumentation(function.Description + "\n" + function.Example),
+			Kind:          lsp.FUNCTION_COMPLETION,
+		}
+		if snippets {
+			item.InsertTextFormat = lsp.SNIPPET_FORMAT
+			item.TextEdit = &lsp.TextEdit{Range: typedRange(position, prefix), NewText: functionSnippet(function)}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// variableCompletionItems returns the AVPs assigned in the open documents.
+// AVPs are global in Kamailio, so the variables of every open document are offered.
+func variableCompletionItems(s *Snapshot) []lsp.CompletionItem {
+	variables := make(map[string]kamailio_cfg.Variable)
+	for _, document := range s.Documents {
+		for name, variable := range document.Variables {
+			variables[name] = variable
+		}
+	}
+	var items []lsp.CompletionItem
+	for variable, value := range variables {
+		items = append(items, lsp.CompletionItem{
+			Detail:        "AVP",
+			Label:         variable,
+			Documentation: lsp.NewCompletionDocumentation(value.GetGlobalVariableDocs()),
+			Kind:          lsp.VARIABLE_COMPLETION,
+		})
+	}
+	return items
+}
+
+// headerCompletionItems returns the names of the standard SIP headers.
+func headerCompletionItems() []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for header, description := range getAllAvailableKeywords() {
+		items = append(items, lsp.CompletionItem{
+			Detail:        "SIP Header",
+			Label:         header,
+			Documentation: lsp.NewCompletionDocumentation(description),
+			Kind:          lsp.VARIABLE_COMPLETION,
+		})
+	}
+	return items
+}
+
+// pseudoVariableCompletionItems returns the pseudo-variables of Kamailio.
+// Their labels leave out the $, which is typed before completion is requested.
+func pseudoVariableCompletionItems() []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for _, variable := range kamailio_cfg.PseudoVariables {
+		detail := "$" + variable.Name
+		if variable.Argument != "" {
+			detail += "(" + variable.Argument + ")"
+		}
+		items = append(items, lsp.CompletionItem{
+			Detail:        detail + " - " + variable.Module,
+			Label:         variable.Name,
+			Documentation: lsp.NewCompletionDocumentation(variable.String()),
+			Kind:          lsp.VARIABLE_COMPLETION,
+		})
+	}
+	return items
+}
+
+// pseudoVariableKeyCompletionItems returns the keys the pseudo-variable takes, e.g. ip for $snd(ip).
+func pseudoVariableKeyCompletionItems(name string) []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for key, description := range kamailio_cfg.PseudoVariables[name].Keys {
+		items = append(items, lsp.CompletionItem{
+			Detail:        "$" + name + "(" + key + ")",
+			Label:         key,
+			Documentation: lsp.NewCompletionDocumentation(description),
+			Kind:          lsp.FIELD_COMPLETION,
+		})
+	}
+	return items
+}
+
+// transformationClassCompletionItems returns the classes of transformations.
+func transformationClassCompletionItems() []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for class, description := range kamailio_cfg.TransformationClasses {
+		items = append(items, lsp.CompletionItem{
+			Detail:        "Transformation class",
+			Label:         class,
+			Documentation: lsp.NewCompletionDocumentation(description),
+			Kind:          lsp.CLASS_COMPLETION,
+		})
+	}
+	return items
+}
+
+// transformationCompletionItems returns the transformations of the class, labelled with their name without the class.
+func transformationCompletionItems(class string) []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for _, transformation := range kamailio_cfg.Transformations {
+		if transformation.Class != class {
+			continue
+		}
+		items = append(items, lsp.CompletionItem{
+			Detail:        transformation.Signature(),
+			Label:         transformation.Name,
+			Documentation: lsp.NewCompletionDocumentation(transformation.String()),
+			Kind:          lsp.METHOD_COMPLETION,
+		})
+	}
+	return items
+}
+
+// moduleCompletionItems returns the names of the modules with documentation.
+func moduleCompletionItems() []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	for _, module := range document_manager.GetAllAvailableModules() {
+		items = append(items, lsp.CompletionItem{
+			Detail:        "Module",
+			Label:         module,
+			Documentation: lsp.NewCompletionDocumentation("Module " + module),
+			Kind:          lsp.MODULE_COMPLETION,
+		})
+	}
+	return items
+}
+
+// parameterCompletionItems returns the parameters of the module: those documented in its README,
+// with their type and default value, and those set by modparam in the open documents
+// and the files they include, apart from the one being typed.
+func parameterCompletionItems(s *Snapshot, uri lsp.DocumentURI, module string, prefix string) []lsp.CompletionItem {
+	seen := map[string]bool{prefix: true}
+	var items []lsp.CompletionItem
+	for _, parameter := range document_manager.GetAllParametersInModule(module) {
+		seen[parameter.Name] = true
+		detail := "Parameter of " + module
+		if parameter.Type != "" {
+			detail = parameter.Type + " parameter of " + module
+		}
+		if parameter.Default != "" {
+			detail += ", default " + parameter.Default
+		}
+		items = append(items, lsp.CompletionItem{
+			Detail:        detail,
+			Label:         parameter.Name,
+			Documentation: lsp.NewCompletionDocumentation(parameter.String()),
+			Kind:          lsp.PROPERTY_COMPLETION,
+		})
+	}
+	for _, document := range s.workspace(uri) {
+		for _, parameter := range document.moduleParameters(module) {
+			if parameter == "" || seen[parameter] {
+				continue
+			}
+			seen[parameter] = true
+			items = append(items, lsp.CompletionItem{
+				Detail: "Parameter of " + module,
+				Label:  parameter,
+				Kind:   lsp.PROPERTY_COMPLETION,
+			})
+		}
+	}
+	return items
+}
+
+// moduleParameters returns the names of the parameters of the module set by modparam in the document.
+func (d *Document) moduleParameters(module string) []string {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	source := d.Source()
+	var parameters []string
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		if node.Type() == kamailio_cfg.ModparamNodeType {
+			name := node.ChildByFieldName("module_name")
+			parameter := node.ChildByFieldName("parameter_name")
+			if name != nil && parameter != nil && moduleName(name.Content(source)) == module {
+				parameters = append(parameters, strings.Trim(parameter.Content(source), `"'`))
+			}
+			return
+		}
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(i))
+		}
+	}
+	visit(root)
+	return parameters
+}
+
+// routeCompletionItems returns the names of the routes of the given kind declared in the open
+// documents and the files they include.
+func routeCompletionItems(s *Snapshot, uri lsp.DocumentURI, kind kamailio_cfg.SymbolKind) []lsp.CompletionItem {
+	seen := make(map[string]bool)
+	var items []lsp.CompletionItem
+	for _, block := range s.routeGraph(uri) {
+		if block.kind != kind || block.name == "" || seen[block.name] {
+			continue
+		}
+		seen[block.name] = true
+		items = append(items, lsp.CompletionItem{
+			Detail: string(kind) + "[" + block.name + "]",
+			Label:  block.name,
+			Kind:   lsp.FUNCTION_COMPLETION,
+		})
+	}
+	return items
+}
+
+// rankCompletionItems sorts the items by how their label matches the typed prefix:
+// labels starting with the prefix come first, then those starting with it in another case,
+// then those containing it, then the others.
+//
+// Parameters:
+//
+//	items []lsp.CompletionItem - The items to rank.
+//	prefix string - The part of the name typed before the position.
+//
+// Returns:
+//
+//	[]lsp.CompletionItem - The items, in order, with their SortText set.
+func rankCompletionItems(items []lsp.CompletionItem, prefix string) []lsp.CompletionItem {
+	lower := strings.ToLower(prefix)
+	rank := func(label string) string {
+		switch {
+		case strings.HasPrefix(label, prefix):
+			return "0"
+		case strings.HasPrefix(strings.ToLower(label), lower):
+			return "1"
+		case strings.Contains(strings.ToLower(label), lower):
+			return "2"
+		}
+		return "3"
+	}
+	for i := range items {
+		items[i].SortText = rank(items[i].Label) + items[i].Label
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].SortText < items[j].SortText })
+	return items
+}
@@ -0,0 +1,232 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Document is an immutable snapshot of a single open document.
+// Every change produces a new Document, so readers holding an older version
+// can keep using it while the document is being edited.
+type Document struct {
+	URI         lsp.DocumentURI                  // The URI of the document.
+	Text        string                           // The text content of the document.
+	Version     int                              // The version of the document as reported by the client.
+	Diagnostics []lsp.Diagnostic                 // The diagnostics of this version.
+	Variables   map[string]kamailio_cfg.Variable // The AVPs assigned in the document.
+	tree        *sitter.Tree
+	parser      *documentParser
+}
+
+// documentParser owns the analyzer of a document.
+// It is shared by all versions of the document and serialises their construction,
+// since the tree-sitter parser and its tree can only be used by one goroutine at a time.
+type documentParser struct {
+	mu       sync.Mutex
+	analyzer *kamailio_cfg.Analyzer
+	current  *Document
+}
+
+// NewDocument creates a new document with the given URI, text and version and analyses it.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document.
+//	text string - The text content of the document.
+//	version int - The version of the document.
+//
+// Returns:
+//
+//	*Document - The analysed document.
+func NewDocument(uri lsp.DocumentURI, text string, version int) *Document {
+	p := &documentParser{analyzer: kamailio_cfg.NewAnalyzer()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.build(uri, text, version)
+}
+
+// build parses the given text, collects diagnostics and symbols and returns the resulting snapshot.
+// The caller must hold p.mu.
+func (p *documentParser) build(uri lsp.DocumentURI, text string, version int) *Document {
+	source := []byte(text)
+	d := &Document{
+		URI:       uri,
+		Text:      text,
+		Version:   version,
+		Variables: make(map[string]kamailio_cfg.Variable),
+		parser:    p,
+	}
+	p.analyzer.Build(source)
+	p.current = d
+	if p.analyzer.GetAST() == nil {
+		return d
+	}
+	visitor := kamailio_cfg.NewDiagnosticVisitor()
+	p.analyzer.GetAST().Accept(visitor, p.analyzer)
+	d.Variables = kamailio_cfg.ExtractGlobalVariables(p.analyzer, source)
+	visitor.GetQueryDiagnostics(p.analyzer.GetAST(), p.analyzer)
+	visitor.AddTransformationErrors(p.analyzer.GetAST(), p.analyzer, source)
+	d.Diagnostics = visitor.GetDiagnostics()
+	for i, diagnostic := range d.Diagnostics {
+		// the visitor reports tree-sitter columns, which count bytes
+		d.Diagnostics[i].Range = lsp.RangeOf(text, pointOf(diagnostic.Range.Start), pointOf(diagnostic.Range.End))
+	}
+	d.tree = p.analyzer.GetParser().GetTree().Copy()
+	return d
+}
+
+// Apply applies the given content changes to the document and returns the new version.
+// Ranged changes are recorded as edits on the document's parse tree so that tree-sitter
+// only reparses the regions that changed. A change without a range replaces the whole
+// document and discards the tree. The receiver is left untouched.
+//
+// Parameters:
+//
+//	changes []lsp.TextDocumentContentChangeEvent - The changes, in the order they were made.
+//	version int - The version of the document after the changes.
+//
+// Returns:
+//
+//	*Document - The analysed document after the changes.
+func (d *Document) Apply(changes []lsp.TextDocumentContentChangeEvent, version int) *Document {
+	p := d.parser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != d {
+		// the parser's tree belongs to another version, edits can't be reused
+		p.analyzer.Reset()
+	}
+	text := d.Text
+	for _, change := range changes {
+		if change.IsFull() {
+			p.analyzer.Reset()
+		} else {
+			p.analyzer.Edit(change.Edit(text))
+		}
+		text = change.Apply(text)
+	}
+	return p.build(d.URI, text, version)
+}
+
+// Source returns the text content of the document as a byte slice.
+//
+// Returns:
+//
+//	[]byte - The source code of the document.
+func (d *Document) Source() []byte {
+	return []byte(d.Text)
+}
+
+// Root returns the root node of the document's AST.
+// Each call returns the root of a private copy of the tree, so the nodes may be
+// walked by the calling goroutine while other goroutines read the same document.
+//
+// Returns:
+//
+//	*sitter.Node - The root node, or nil if the document could not be parsed.
+func (d *Document) Root() *sitter.Node {
+	if d.tree == nil {
+		return nil
+	}
+	return d.tree.Copy().RootNode()
+}
+
+// NodeAt returns the smallest named node at the given position in the document.
+//
+// Parameters:
+//
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	*sitter.Node - The node at the position, or nil if there is none.
+func (d *Document) NodeAt(position lsp.Position) *sitter.Node {
+	return getNodeAtPoint(d.Root(), d.PointAt(position))
+}
+
+// PointAt converts a position in the negotiated encoding into a tree-sitter point of the document.
+//
+// Parameters:
+//
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	sitter.Point - The point, with its column in bytes.
+func (d *Document) PointAt(position lsp.Position) sitter.Point {
+	return lsp.PointAt(d.Text, lsp.OffsetAt(d.Text, position))
+}
+
+// RangeOf returns the range of the node in the negotiated encoding.
+//
+// Parameters:
+//
+//	node *sitter.Node - A node of the document.
+//
+// Returns:
+//
+//	lsp.Range - The range of the node.
+func (d *Document) RangeOf(node *sitter.Node) lsp.Range {
+	return lsp.RangeOf(d.Text, node.StartPoint(), node.EndPoint())
+}
+
+// pointOf returns the tree-sitter point of a position whose character is a byte column.
+func pointOf(position lsp.Position) sitter.Point {
+	return sitter.Point{Row: uint32(position.Line), Column: uint32(position.Character)}
+}
+
+// Symbols returns the declarations and uses of the names in the document, in the order they appear.
+//
+// Returns:
+//
+//	[]kamailio_cfg.Symbol - The symbols of the document.
+func (d *Document) Symbols() []kamailio_cfg.Symbol {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	return kamailio_cfg.Symbols(root, d.Source())
+}
+
+// SymbolAt returns the symbol at the given position in the document.
+//
+// Parameters:
+//
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	kamailio_cfg.Symbol - The symbol.
+//	bool - False if there is no symbol at the position.
+func (d *Document) SymbolAt(position lsp.Position) (kamailio_cfg.Symbol, bool) {
+	return kamailio_cfg.SymbolAt(d.Root(), d.PointAt(position), d.Source())
+}
+
+// RangeOfSymbol returns the range of the name of the symbol in the negotiated encoding.
+//
+// Parameters:
+//
+//	symbol kamailio_cfg.Symbol - A symbol of the document.
+//
+// Returns:
+//
+//	lsp.Range - The range of the name.
+func (d *Document) RangeOfSymbol(symbol kamailio_cfg.Symbol) lsp.Range {
+	return lsp.RangeOf(d.Text, symbol.Start, symbol.End)
+}
+
+// Includes returns the names of the files the document includes, as written in the document.
+//
+// Returns:
+//
+//	[]string - The names of the included files.
+func (d *Document) Includes() []string {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	return kamailio_cfg.Includes(root, d.Source())
+}
@@ -0,0 +1,83 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/state_manager"
+	"sync"
+	"testing"
+)
+
+func TestDocumentsKeepTheirOwnTrees(t *testing.T) {
+	state := state_manager.NewState()
+	state.OpenDocument("file:///kamailio.cfg", "request_route {\n\tt_relay();\n}\n", 1)
+	state.OpenDocument("file:///routing.cfg", "route[RELAY] {\n\tsl_send_reply();\n}\n", 1)
+
+	position := lsp.Position{Line: 1, Character: 3}
+	for uri, expected := range map[lsp.DocumentURI]string{
+		"file:///kamailio.cfg": "t_relay",
+		"file:///routing.cfg":  "sl_send_reply",
+	} {
+		document := state.GetDocument(uri)
+		node := document.NodeAt(position)
+		if node == nil {
+			t.Fatalf("Expected a node in %s", uri)
+		}
+		if actual := node.Content(document.Source()); actual != expected {
+			t.Fatalf("Expected: %s,\ngot: %s", expected, actual)
+		}
+	}
+}
+
+func TestChangeDocumentUpdatesVersionAndVariables(t *testing.T) {
+	state := state_manager.NewState()
+	uri := lsp.DocumentURI("file:///kamailio.cfg")
+	state.OpenDocument(uri, "request_route {\n\t$avp(a) = 1;\n}\n", 1)
+	state.ChangeDocument(uri, []lsp.TextDocumentContentChangeEvent{{
+		Range: &lsp.Range{Start: lsp.Position{Line: 1, Character: 6}, End: lsp.Position{Line: 1, Character: 7}},
+		Text:  "b",
+	}}, 2)
+
+	document := state.GetDocument(uri)
+	if document.Version != 2 {
+		t.Fatalf("Expected: 2,\ngot: %d", document.Version)
+	}
+	if _, ok := document.Variables["$avp(b)"]; !ok {
+		t.Fatalf("Expected $avp(b) in %v", document.Variables)
+	}
+	if _, ok := document.Variables["$avp(a)"]; ok {
+		t.Fatalf("Did not expect $avp(a) in %v", document.Variables)
+	}
+}
+
+func TestSnapshotsAreImmutable(t *testing.T) {
+	state := state_manager.NewState()
+	uri := lsp.DocumentURI("file:///kamailio.cfg")
+	state.OpenDocument(uri, "request_route {\n\tt_relay();\n}\n", 1)
+	before := state.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				document := state.Snapshot().GetDocument(uri)
+				document.NodeAt(lsp.Position{Line: 1, Character: 3})
+			}
+		}()
+	}
+	for version := 2; version < 20; version++ {
+		state.ChangeDocument(uri, []lsp.TextDocumentContentChangeEvent{{
+			Range: &lsp.Range{Start: lsp.Position{Line: 1, Character: 1}, End: lsp.Position{Line: 1, Character: 1}},
+			Text:  " ",
+		}}, version)
+	}
+	wg.Wait()
+
+	if before.GetDocument(uri).Version != 1 {
+		t.Fatalf("Expected: 1,\ngot: %d", before.GetDocument(uri).Version)
+	}
+	if after := state.Snapshot(); after.Version <= before.Version {
+		t.Fatalf("Expected snapshot version to grow, got %d after %d", after.Version, before.Version)
+	}
+}
@@ -0,0 +1,118 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"regexp"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// banner_pattern matches the comments that head a section of the configuration,
+// e.g. "####### Global Parameters #########" or "# ----- tm params -----".
+var banner_pattern = regexp.MustCompile(`^#+\s*([-#=]{3,})\s*\w.*?\s*[-#=]{3,}\s*$`)
+
+// FoldingRanges returns the ranges of the document that can be folded: the bodies of blocks,
+// the arms of switch statements, #!ifdef regions, multiline comments and the sections
+// headed by banner comments.
+//
+// Returns:
+//
+//	[]lsp.FoldingRange - The folding ranges, ordered by their start line.
+func (d *Document) FoldingRanges() []lsp.FoldingRange {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	f := folding{source: d.Source()}
+	f.visit(root)
+	sort.SliceStable(f.ranges, func(i, j int) bool {
+		return f.ranges[i].StartLine < f.ranges[j].StartLine
+	})
+	return f.ranges
+}
+
+// folding collects the folding ranges of a document.
+type folding struct {
+	source []byte
+	ranges []lsp.FoldingRange
+}
+
+// add adds the range between the given rows, unless it spans a single line.
+func (f *folding) add(start int, end int, kind lsp.FoldingRangeKind) {
+	if end <= start {
+		return
+	}
+	f.ranges = append(f.ranges, lsp.FoldingRange{StartLine: start, EndLine: end, Kind: kind})
+}
+
+// visit adds the folding ranges of the node and of its descendants.
+func (f *folding) visit(node *sitter.Node) {
+	start, end := int(node.StartPoint().Row), int(node.EndPoint().Row)
+	switch node.Type() {
+	case kamailio_cfg.CompoundStatementNodeType:
+		// the closing brace stays visible if it is on its own line
+		f.add(start, end-1, "")
+	case kamailio_cfg.CaseStatementNodeType:
+		f.add(start, end, "")
+	case kamailio_cfg.MultilineCommentNodeType:
+		f.add(start, end, lsp.FOLDING_RANGE_KIND_COMMENT)
+	case kamailio_cfg.PreprocIfdefNodeType, kamailio_cfg.PreprocIfndefNodeType:
+		// the region ends before #!else or #!endif, which fold on their own
+		if alternative := node.ChildByFieldName("alternative"); alternative != nil {
+			end = int(alternative.StartPoint().Row)
+		}
+		f.add(start, end-1, lsp.FOLDING_RANGE_KIND_REGION)
+		f.sections(node)
+	case kamailio_cfg.PreprocElseNodeType:
+		// the node ends where #!endif starts, which stays visible
+		f.add(start, end-1, lsp.FOLDING_RANGE_KIND_REGION)
+		f.sections(node)
+	case kamailio_cfg.SourceFileNodeType:
+		f.sections(node)
+	}
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		f.visit(node.NamedChild(i))
+	}
+}
+
+// sections adds the sections headed by banner comments among the items of the node.
+// A section ends with the last item before the next banner, or before the next banner drawn
+// with '#' for the sections drawn with '#', so that they hold the sections drawn with '-'.
+func (f *folding) sections(node *sitter.Node) {
+	type section struct {
+		start int
+		major bool
+	}
+	var open []section
+	var last int
+	close := func(major bool) {
+		for len(open) > 0 && (major || !open[len(open)-1].major) {
+			f.add(open[len(open)-1].start, last, lsp.FOLDING_RANGE_KIND_REGION)
+			open = open[:len(open)-1]
+		}
+	}
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		item := node.NamedChild(i)
+		if item.Type() == kamailio_cfg.TopLevelItemNodeType && item.NamedChildCount() > 0 {
+			item = item.NamedChild(0)
+		}
+		if item.Type() != kamailio_cfg.CommentNodeType {
+			if item.Type() != kamailio_cfg.MultilineCommentNodeType {
+				last = int(item.EndPoint().Row)
+			}
+			continue
+		}
+		match := banner_pattern.FindStringSubmatch(strings.TrimSpace(item.Content(f.source)))
+		if match == nil {
+			continue
+		}
+		major := strings.HasPrefix(match[1], "#")
+		close(major)
+		last = int(item.StartPoint().Row)
+		open = append(open, section{start: last, major: major})
+	}
+	close(true)
+}
@@ -0,0 +1,61 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/state_manager"
+	"reflect"
+	"testing"
+)
+
+const folding_cfg = `#!KAMAILIO
+/* multi
+ * line */
+#!ifdef WITH_X
+#!define A 1
+#!else
+#!define A 2
+#!endif
+
+####### Modules Section ########
+loadmodule "tm.so"
+
+# ----- tm params -----
+modparam("tm", "a", 1)
+modparam("tm", "b", 2)
+
+# ----- rr params -----
+# comment
+modparam("rr", "x", 1)
+
+####### Routing Logic ########
+request_route {
+	switch($rU) {
+		case "a":
+			xlog("a");
+			break;
+		default:
+			exit;
+	}
+	if ($rU == "b") { exit; }
+}
+`
+
+func TestFoldingRanges(t *testing.T) {
+	document := state_manager.NewDocument("file:///kamailio.cfg", folding_cfg, 1)
+	expected := []lsp.FoldingRange{
+		{StartLine: 1, EndLine: 2, Kind: lsp.FOLDING_RANGE_KIND_COMMENT},
+		{StartLine: 3, EndLine: 4, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 5, EndLine: 6, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 9, EndLine: 18, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 12, EndLine: 14, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 16, EndLine: 18, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 20, EndLine: 30, Kind: lsp.FOLDING_RANGE_KIND_REGION},
+		{StartLine: 21, EndLine: 29},
+		{StartLine: 22, EndLine: 27},
+		{StartLine: 23, EndLine: 25},
+		{StartLine: 26, EndLine: 27},
+	}
+	if ranges := document.FoldingRanges(); !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("Expected: %v,\ngot: %v", expected, ranges)
+	}
+}
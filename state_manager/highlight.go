@@ -0,0 +1,217 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// avp_type_pattern matches the type of an AVP name, e.g. the s: of $avp(s:name).
+var avp_type_pattern = regexp.MustCompile(`\(([is]):`)
+
+// pseudoVariable is an occurrence of a pseudo-variable in a document, in bytes.
+// Its key is the pseudo-variable without its transformations and index, e.g. hdr(From) for $(hdr(From)[0]{s.len}).
+type pseudoVariable struct {
+	start    uint32
+	end      uint32
+	key      string
+	assigned bool
+}
+
+// Highlights returns the occurrences in the document of the symbol at the given position:
+// a route, a define, a pseudo-variable or the name of a module.
+// Declarations and assigned pseudo-variables are written, other occurrences are read.
+//
+// Parameters:
+//
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	[]lsp.DocumentHighlight - The occurrences, empty if there is no symbol at the position.
+func (d *Document) Highlights(position lsp.Position) []lsp.DocumentHighlight {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	source := d.Source()
+	lines := lineStarts(d.Text)
+	point := d.PointAt(position)
+	cursor := offsetOfPoint(lines, point)
+	variables := pseudoVariables(root, source)
+	var at *pseudoVariable
+	for i := range variables {
+		// nested pseudo-variables come after the one they are nested in
+		if variables[i].start <= cursor && cursor <= variables[i].end {
+			at = &variables[i]
+		}
+	}
+	assigned := func(start uint32) bool {
+		for _, variable := range variables {
+			if variable.assigned && variable.start <= start && start < variable.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	symbols := kamailio_cfg.Symbols(root, source)
+	symbol, found := kamailio_cfg.SymbolAt(root, point, source)
+	if !found && at != nil {
+		// the position is on a pseudo-variable, but not on its name
+		for _, candidate := range symbols {
+			if isVariable(candidate) && at.start <= offsetOfPoint(lines, candidate.Start) && offsetOfPoint(lines, candidate.End) <= at.end {
+				symbol, found = candidate, true
+				break
+			}
+		}
+	}
+
+	var highlights []lsp.DocumentHighlight
+	switch {
+	case found:
+		for _, occurrence := range symbols {
+			if !occurrence.Is(symbol) {
+				continue
+			}
+			kind := lsp.READ_HIGHLIGHT
+			if occurrence.Declaration || assigned(offsetOfPoint(lines, occurrence.Start)) {
+				kind = lsp.WRITE_HIGHLIGHT
+			}
+			highlights = append(highlights, lsp.DocumentHighlight{Range: d.RangeOfSymbol(occurrence), Kind: kind})
+		}
+	case at != nil:
+		for _, variable := range variables {
+			if variable.key != at.key {
+				continue
+			}
+			kind := lsp.READ_HIGHLIGHT
+			if variable.assigned {
+				kind = lsp.WRITE_HIGHLIGHT
+			}
+			highlights = append(highlights, lsp.DocumentHighlight{Range: d.rangeOfOffsets(lines, variable.start, variable.end), Kind: kind})
+		}
+	default:
+		for _, name := range moduleNames(root, point, source) {
+			highlights = append(highlights, lsp.DocumentHighlight{Range: d.RangeOf(name), Kind: lsp.TEXT_HIGHLIGHT})
+		}
+	}
+	return highlights
+}
+
+// rangeOfOffsets returns the range between two offsets of the document in the negotiated encoding.
+func (d *Document) rangeOfOffsets(lines []uint32, start uint32, end uint32) lsp.Range {
+	return lsp.RangeOf(d.Text, pointOfOffset(lines, start), pointOfOffset(lines, end))
+}
+
+// pseudoVariables returns the pseudo-variables of the node and of its descendants,
+// including those written inside strings, in the order they appear.
+func pseudoVariables(node *sitter.Node, source []byte) []pseudoVariable {
+	var variables []pseudoVariable
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		switch node.Type() {
+		case kamailio_cfg.PseudoVariableNodeType, kamailio_cfg.PseudoVariableExpressionNodeType:
+			parent := node.Parent()
+			variables = append(variables, pseudoVariable{
+				start:    node.StartByte(),
+				end:      node.EndByte(),
+				key:      pseudoVariableKey(node.Content(source)),
+				assigned: parent != nil && parent.Type() == kamailio_cfg.AssignmentExpressionNodeType && isNode(parent.ChildByFieldName("left"), node),
+			})
+		case kamailio_cfg.StringNodeType:
+			start := node.StartByte()
+			content := node.Content(source)
+			for _, match := range string_pseudo_variable_pattern.FindAllStringIndex(content, -1) {
+				variables = append(variables, pseudoVariable{
+					start: start + uint32(match[0]),
+					end:   start + uint32(match[1]),
+					key:   pseudoVariableKey(content[match[0]:match[1]]),
+				})
+			}
+			return
+		}
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(i))
+		}
+	}
+	visit(node)
+	return variables
+}
+
+// pseudoVariableKey returns the pseudo-variable written as text without its transformations,
+// its index and the type of an AVP name, so that every way of writing it has the same key.
+func pseudoVariableKey(text string) string {
+	key := strings.TrimPrefix(text, "$")
+	if strings.HasPrefix(key, "(") && strings.HasSuffix(key, ")") {
+		key = key[1 : len(key)-1]
+	}
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		key = key[:i]
+	}
+	if strings.HasSuffix(key, "]") {
+		if i := strings.LastIndexByte(key, '['); i >= 0 {
+			key = key[:i]
+		}
+	}
+	return avp_type_pattern.ReplaceAllString(key, "(")
+}
+
+// moduleNames returns the strings naming the module named at the point, in loadmodule and modparam.
+func moduleNames(root *sitter.Node, point sitter.Point, source []byte) []*sitter.Node {
+	node := root.NamedDescendantForPointRange(point, point)
+	if node == nil || node.Type() != kamailio_cfg.StringNodeType || !isModuleName(node) {
+		return nil
+	}
+	module := moduleName(node.Content(source))
+	var names []*sitter.Node
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		switch node.Type() {
+		case kamailio_cfg.LoadmoduleNodeType, kamailio_cfg.ModparamNodeType:
+			if name := node.ChildByFieldName("module_name"); name != nil && moduleName(name.Content(source)) == module {
+				names = append(names, name)
+			}
+			return
+		}
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(i))
+		}
+	}
+	visit(root)
+	return names
+}
+
+// isModuleName reports whether the string names the module of a loadmodule or modparam.
+func isModuleName(node *sitter.Node) bool {
+	parent := node.Parent()
+	if parent == nil || (parent.Type() != kamailio_cfg.LoadmoduleNodeType && parent.Type() != kamailio_cfg.ModparamNodeType) {
+		return false
+	}
+	return isNode(parent.ChildByFieldName("module_name"), node)
+}
+
+// DocumentHighlight returns the occurrences of the symbol at the given position in the document.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the documentHighlight request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.DocumentHighlightResponse - The occurrences of the symbol.
+func (s *Snapshot) DocumentHighlight(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.DocumentHighlightResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("DocumentHighlight request for document that is not open: ", uri)
+		return lsp.NewDocumentHighlightResponse(id, nil)
+	}
+	return lsp.NewDocumentHighlightResponse(id, document.Highlights(position))
+}
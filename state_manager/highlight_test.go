@@ -0,0 +1,71 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/state_manager"
+	"strings"
+	"testing"
+)
+
+const highlight_cfg = `#!define FLT_ACC 1
+loadmodule "tm.so"
+modparam("tm", "fr_timer", 30000)
+request_route {
+	$avp(x) = $ru;
+	xlog("L_INFO", "$avp(x) $(ru{uri.user})\n");
+	if ($avp(s:x) == 1) { setflag(FLT_ACC); }
+	$ru = "sip:a@b";
+	route(RELAY);
+}
+route[RELAY] {
+	exit;
+}
+`
+
+func TestHighlights(t *testing.T) {
+	document := state_manager.NewDocument("file:///kamailio.cfg", highlight_cfg, 1)
+	lines := strings.Split(highlight_cfg, "\n")
+
+	type highlight struct {
+		line int
+		text string
+		kind lsp.DocumentHighlightKind
+	}
+	tests := []struct {
+		name     string
+		position lsp.Position
+		expected []highlight
+	}{
+		{"avp", lsp.Position{Line: 4, Character: 2}, []highlight{
+			{4, "x", lsp.WRITE_HIGHLIGHT}, {5, "x", lsp.READ_HIGHLIGHT}, {6, "x", lsp.READ_HIGHLIGHT},
+		}},
+		{"builtin pseudo-variable", lsp.Position{Line: 4, Character: 12}, []highlight{
+			{4, "$ru", lsp.READ_HIGHLIGHT}, {5, "$(ru{uri.user})", lsp.READ_HIGHLIGHT}, {7, "$ru", lsp.WRITE_HIGHLIGHT},
+		}},
+		{"flag", lsp.Position{Line: 6, Character: 34}, []highlight{
+			{0, "FLT_ACC", lsp.WRITE_HIGHLIGHT}, {6, "FLT_ACC", lsp.READ_HIGHLIGHT},
+		}},
+		{"route", lsp.Position{Line: 8, Character: 8}, []highlight{
+			{8, "RELAY", lsp.READ_HIGHLIGHT}, {10, "RELAY", lsp.WRITE_HIGHLIGHT},
+		}},
+		{"module", lsp.Position{Line: 2, Character: 10}, []highlight{
+			{1, `"tm.so"`, lsp.TEXT_HIGHLIGHT}, {2, `"tm"`, lsp.TEXT_HIGHLIGHT},
+		}},
+		{"nothing", lsp.Position{Line: 11, Character: 2}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			highlights := document.Highlights(test.position)
+			if len(highlights) != len(test.expected) {
+				t.Fatalf("Expected %d highlights, got: %v", len(test.expected), highlights)
+			}
+			for i, h := range highlights {
+				start, end := h.Range.Start, h.Range.End
+				got := highlight{start.Line, lines[start.Line][start.Character:end.Character], h.Kind}
+				if got != test.expected[i] {
+					t.Errorf("Expected: %v,\ngot: %v", test.expected[i], got)
+				}
+			}
+		})
+	}
+}
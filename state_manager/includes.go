@@ -0,0 +1,108 @@
+package state_manager
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// includedDocument is a file included by an open document, parsed from disk.
+type includedDocument struct {
+	modTime  time.Time
+	document *Document
+}
+
+// included_documents caches the included files that are not open, keyed by their URI.
+// A file is parsed again once it is modified on disk.
+var (
+	included_mu        sync.Mutex
+	included_documents = make(map[lsp.DocumentURI]includedDocument)
+)
+
+// workspace returns the open documents and every file they include, directly or not,
+// starting with the document with the given URI. Included files that are not open are read from disk.
+//
+// Parameters:
+//
+//	first lsp.DocumentURI - The URI of the document to return first.
+//
+// Returns:
+//
+//	[]*Document - The documents of the configuration.
+func (s *Snapshot) workspace(first lsp.DocumentURI) []*Document {
+	documents := s.documents(first)
+	seen := make(map[lsp.DocumentURI]bool, len(documents))
+	for _, document := range documents {
+		seen[document.URI] = true
+	}
+	for i := 0; i < len(documents); i++ {
+		for _, name := range documents[i].Includes() {
+			uri, ok := resolveInclude(documents[i].URI, name)
+			if !ok || seen[uri] {
+				continue
+			}
+			seen[uri] = true
+			if document := loadIncluded(uri); document != nil {
+				documents = append(documents, document)
+			}
+		}
+	}
+	return documents
+}
+
+// resolveInclude returns the URI of a file included by a document.
+// Relative names are resolved against the directory of the including document, as Kamailio does.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the including document.
+//	name string - The name of the included file, as written in the document.
+//
+// Returns:
+//
+//	lsp.DocumentURI - The URI of the included file.
+//	bool - False if the including document is not a file.
+func resolveInclude(uri lsp.DocumentURI, name string) (lsp.DocumentURI, bool) {
+	if filepath.IsAbs(name) {
+		return lsp.NewDocumentURI(name), true
+	}
+	path, ok := uri.Path()
+	if !ok {
+		return "", false
+	}
+	return lsp.NewDocumentURI(filepath.Join(filepath.Dir(path), name)), true
+}
+
+// loadIncluded returns the included file with the given URI, parsed from disk.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the included file.
+//
+// Returns:
+//
+//	*Document - The parsed file, nil if it can't be read.
+func loadIncluded(uri lsp.DocumentURI) *Document {
+	path, _ := uri.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		logger.Debug("Included file not found: ", path)
+		return nil
+	}
+	included_mu.Lock()
+	defer included_mu.Unlock()
+	if cached, found := included_documents[uri]; found && cached.modTime.Equal(info.ModTime()) {
+		return cached.document
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("Error reading included file: ", err)
+		return nil
+	}
+	document := NewDocument(uri, string(text), 0)
+	included_documents[uri] = includedDocument{modTime: info.ModTime(), document: document}
+	return document
+}
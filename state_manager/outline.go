@@ -0,0 +1,180 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"path"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Outline returns the symbols of the document as a tree: the routing blocks, the modules
+// with their parameters, the preprocessor definitions and the core parameters.
+// Items inside #!ifdef regions are listed as if they were at the top level.
+//
+// Returns:
+//
+//	[]lsp.DocumentSymbol - The symbols of the document, in the order they appear.
+func (d *Document) Outline() []lsp.DocumentSymbol {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	o := outline{document: d, source: d.Source(), modules: make(map[string]int)}
+	o.visit(root)
+	return o.symbols
+}
+
+// outline collects the symbols of a document.
+// Modules are kept at the position of their first loadmodule or modparam,
+// so that their parameters can be added to them wherever they appear.
+type outline struct {
+	document *Document
+	source   []byte
+	symbols  []lsp.DocumentSymbol
+	modules  map[string]int
+}
+
+// visit adds the symbols of the top level items of the node.
+func (o *outline) visit(node *sitter.Node) {
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		switch child.Type() {
+		case kamailio_cfg.TopLevelItemNodeType, kamailio_cfg.PreprocIfdefNodeType,
+			kamailio_cfg.PreprocIfndefNodeType, kamailio_cfg.PreprocElseNodeType:
+			o.visit(child)
+		case kamailio_cfg.RoutingBlockNodeType:
+			o.route(child)
+		case kamailio_cfg.LoadmoduleNodeType:
+			o.module(child, child.ChildByFieldName("module_name"))
+		case kamailio_cfg.ModparamNodeType:
+			o.modparam(child)
+		case kamailio_cfg.PreprocDefNodeType, kamailio_cfg.PreprocTrydefNodeType, kamailio_cfg.PreprocRedefNodeType:
+			o.add(child, child.ChildByFieldName("name"), lsp.CONSTANT_SYMBOL, child.ChildByFieldName("value"))
+		case kamailio_cfg.PreprocSubstNodeType, kamailio_cfg.PreprocSubstdefNodeType, kamailio_cfg.PreprocSubstdefsNodeType:
+			o.add(child, child.ChildByFieldName("value"), lsp.STRING_SYMBOL, nil)
+		case kamailio_cfg.TopLevelAssignmentNodeType:
+			o.add(child, child.ChildByFieldName("key"), lsp.VARIABLE_SYMBOL, child.ChildByFieldName("value"))
+		}
+	}
+}
+
+// add adds a symbol named after the given node.
+//
+// Parameters:
+//
+//	node *sitter.Node - The node of the whole symbol.
+//	name *sitter.Node - The node of its name, the symbol is skipped if it is nil.
+//	kind lsp.SymbolKind - The kind of the symbol.
+//	detail *sitter.Node - The node shown next to the name, may be nil.
+func (o *outline) add(node *sitter.Node, name *sitter.Node, kind lsp.SymbolKind, detail *sitter.Node) {
+	if name == nil {
+		return
+	}
+	symbol := lsp.DocumentSymbol{
+		Name:           strings.TrimSpace(name.Content(o.source)),
+		Kind:           kind,
+		Range:          o.document.RangeOf(node),
+		SelectionRange: o.document.RangeOf(name),
+	}
+	if detail != nil {
+		symbol.Detail = strings.TrimSpace(detail.Content(o.source))
+	}
+	o.symbols = append(o.symbols, symbol)
+}
+
+// route adds a routing block, named like it is written, e.g. route[RELAY] or request_route.
+func (o *outline) route(block *sitter.Node) {
+	keyword := block.ChildByFieldName("route")
+	if keyword == nil {
+		return
+	}
+	symbol := lsp.DocumentSymbol{
+		Name:           keyword.Content(o.source),
+		Kind:           lsp.FUNCTION_SYMBOL,
+		Range:          o.document.RangeOf(block),
+		SelectionRange: o.document.RangeOf(keyword),
+	}
+	if symbol.Name == "event_route" {
+		symbol.Kind = lsp.EVENT_SYMBOL
+	}
+	if name := block.ChildByFieldName("route_name"); name != nil {
+		symbol.Name += "[" + name.Content(o.source) + "]"
+		symbol.SelectionRange = o.document.RangeOf(name)
+	}
+	o.symbols = append(o.symbols, symbol)
+}
+
+// module returns the symbol of the module named by the given string, adding it if needed,
+// and extends its range over the node.
+//
+// Parameters:
+//
+//	node *sitter.Node - The loadmodule or modparam that names the module.
+//	name *sitter.Node - The string holding the name or path of the module.
+//
+// Returns:
+//
+//	*lsp.DocumentSymbol - The symbol of the module, nil if the node has no module name.
+func (o *outline) module(node *sitter.Node, name *sitter.Node) *lsp.DocumentSymbol {
+	if name == nil {
+		return nil
+	}
+	module := moduleName(name.Content(o.source))
+	index, found := o.modules[module]
+	if !found {
+		index = len(o.symbols)
+		o.modules[module] = index
+		o.symbols = append(o.symbols, lsp.DocumentSymbol{
+			Name:           module,
+			Kind:           lsp.MODULE_SYMBOL,
+			Range:          o.document.RangeOf(node),
+			SelectionRange: o.document.RangeOf(name),
+		})
+	}
+	symbol := &o.symbols[index]
+	symbol.Range = union(symbol.Range, o.document.RangeOf(node))
+	return symbol
+}
+
+// modparam adds a module parameter to the symbol of its module.
+func (o *outline) modparam(node *sitter.Node) {
+	module := o.module(node, node.ChildByFieldName("module_name"))
+	parameter := node.ChildByFieldName("parameter_name")
+	if module == nil || parameter == nil {
+		return
+	}
+	symbol := lsp.DocumentSymbol{
+		Name:           strings.Trim(parameter.Content(o.source), `"'`),
+		Kind:           lsp.PROPERTY_SYMBOL,
+		Range:          o.document.RangeOf(node),
+		SelectionRange: o.document.RangeOf(parameter),
+	}
+	if value := node.ChildByFieldName("value"); value != nil {
+		symbol.Detail = value.Content(o.source)
+	}
+	module.Children = append(module.Children, symbol)
+}
+
+// moduleName returns the name of a module from the string naming it in loadmodule or modparam,
+// e.g. tm for "tm.so" or "/usr/lib/kamailio/modules/tm.so".
+func moduleName(name string) string {
+	return strings.TrimSuffix(path.Base(strings.Trim(name, `"'`)), ".so")
+}
+
+// union returns the smallest range that covers both ranges.
+func union(a lsp.Range, b lsp.Range) lsp.Range {
+	if before(b.Start, a.Start) {
+		a.Start = b.Start
+	}
+	if before(a.End, b.End) {
+		a.End = b.End
+	}
+	return a
+}
+
+// before reports whether the position a comes before b.
+func before(a lsp.Position, b lsp.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
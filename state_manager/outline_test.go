@@ -0,0 +1,67 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/state_manager"
+	"testing"
+)
+
+const outline_cfg = `#!KAMAILIO
+#!define WITH_NAT
+debug=3
+#!subst "/ABC/abc/"
+loadmodule "/usr/lib/kamailio/modules/tm.so"
+#!ifdef WITH_NAT
+loadmodule "nathelper.so"
+modparam("nathelper", "natping_interval", 30)
+#!endif
+modparam("tm", "fr_timer", 30000)
+request_route {
+	route(RELAY);
+}
+route[RELAY] {
+	exit;
+}
+event_route[xhttp:request] {
+	exit;
+}
+`
+
+func TestOutline(t *testing.T) {
+	document := state_manager.NewDocument("file:///kamailio.cfg", outline_cfg, 1)
+	symbols := document.Outline()
+
+	expected := []struct {
+		name     string
+		kind     lsp.SymbolKind
+		children []string
+	}{
+		{"WITH_NAT", lsp.CONSTANT_SYMBOL, nil},
+		{"debug", lsp.VARIABLE_SYMBOL, nil},
+		{`"/ABC/abc/"`, lsp.STRING_SYMBOL, nil},
+		{"tm", lsp.MODULE_SYMBOL, []string{"fr_timer"}},
+		{"nathelper", lsp.MODULE_SYMBOL, []string{"natping_interval"}},
+		{"request_route", lsp.FUNCTION_SYMBOL, nil},
+		{"route[RELAY]", lsp.FUNCTION_SYMBOL, nil},
+		{"event_route[xhttp:request]", lsp.EVENT_SYMBOL, nil},
+	}
+	if len(symbols) != len(expected) {
+		t.Fatalf("Expected %d symbols, got: %v", len(expected), symbols)
+	}
+	for i, symbol := range symbols {
+		if symbol.Name != expected[i].name || symbol.Kind != expected[i].kind || len(symbol.Children) != len(expected[i].children) {
+			t.Fatalf("Expected: %v,\ngot: %v", expected[i], symbol)
+		}
+		for j, child := range symbol.Children {
+			if child.Name != expected[i].children[j] {
+				t.Fatalf("Expected: %s,\ngot: %s", expected[i].children[j], child.Name)
+			}
+		}
+	}
+
+	// the tm module spans from its loadmodule to its last modparam
+	tm := symbols[3]
+	if tm.Range.Start.Line != 4 || tm.Range.End.Line != 9 || tm.SelectionRange.Start.Line != 4 {
+		t.Fatalf("Expected the tm module to cover lines 4 to 9, got: %v", tm.Range)
+	}
+}
@@ -0,0 +1,451 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// keyword_pattern matches the anonymous nodes that are keywords, e.g. if, request_route or #!ifdef.
+var keyword_pattern = regexp.MustCompile(`^(#!)?[a-z_]+$`)
+
+// string_pseudo_variable_pattern matches the pseudo-variables written inside strings,
+// e.g. $fu, $var(x), $hdr(From)[0] or $(ru{s.len}).
+var string_pseudo_variable_pattern = regexp.MustCompile(`\$(?:\([A-Za-z_]\w*(?:\([^()]*\))?(?:\[[^\]]*\])?(?:\{[^{}]*\})*\)|[A-Za-z_]\w*(?:\([^()]*\))?(?:\[[^\]]*\])?)`)
+
+// span is a part of the source of a document that is a semantic token, in bytes.
+// Spans may be nested, the innermost span gives the token of its part of the source.
+type span struct {
+	start     uint32
+	end       uint32
+	kind      lsp.SemanticTokenType
+	modifiers lsp.SemanticTokenModifiers
+}
+
+// SemanticTokens returns the semantic tokens of the document, see lsp.SemanticTokenType.
+// Tokens in the branches of #!ifdef and #!ifndef that are left out have the inactive modifier;
+// only the defines of the document are known, not those of included files or of the command line.
+//
+// Returns:
+//
+//	[]lsp.SemanticToken - The tokens, each on a single line, in the order they appear.
+func (d *Document) SemanticTokens() []lsp.SemanticToken {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	h := highlighter{source: d.Source(), lines: lineStarts(d.Text)}
+	h.visit(root)
+	h.symbols(kamailio_cfg.Symbols(root, h.source))
+	return h.tokens(d.Text, inactiveRegions(root, h.source))
+}
+
+// highlighter collects the spans of a document.
+type highlighter struct {
+	source []byte
+	lines  []uint32
+	spans  []span
+}
+
+// add adds a span over the node.
+func (h *highlighter) add(node *sitter.Node, kind lsp.SemanticTokenType, modifiers lsp.SemanticTokenModifiers) {
+	if node == nil {
+		return
+	}
+	h.spans = append(h.spans, span{start: node.StartByte(), end: node.EndByte(), kind: kind, modifiers: modifiers})
+}
+
+// visit adds the spans of the node and of its descendants.
+func (h *highlighter) visit(node *sitter.Node) {
+	switch node.Type() {
+	case kamailio_cfg.CommentNodeType, kamailio_cfg.MultilineCommentNodeType:
+		h.add(node, lsp.COMMENT_TOKEN, 0)
+		return
+	case kamailio_cfg.StringNodeType:
+		h.string(node)
+		return
+	case kamailio_cfg.NumberLiteralNodeType:
+		h.add(node, lsp.NUMBER_TOKEN, 0)
+		return
+	case kamailio_cfg.PseudoVariableNodeType, kamailio_cfg.PseudoVariableExpressionNodeType:
+		h.pseudoVariable(node)
+		return
+	case kamailio_cfg.CallExpressionNodeType:
+		h.add(node.ChildByFieldName("function"), lsp.FUNCTION_TOKEN, 0)
+	case kamailio_cfg.TopLevelAssignmentNodeType:
+		h.add(node.ChildByFieldName("key"), lsp.PROPERTY_TOKEN, 0)
+	case kamailio_cfg.AssignmentExpressionNodeType:
+		// core parameters inside #!ifdef are not top level assignments
+		if left := node.ChildByFieldName("left"); left != nil && left.Type() == kamailio_cfg.IdentifierNodeType {
+			h.add(left, lsp.PROPERTY_TOKEN, 0)
+		}
+	}
+	if operator := node.ChildByFieldName("operator"); operator != nil {
+		h.add(operator, lsp.OPERATOR_TOKEN, 0)
+	}
+	for i := 0; i < int(node.ChildCount()); i++ {
+		child := node.Child(i)
+		if child.IsNamed() {
+			h.visit(child)
+		} else if keyword_pattern.MatchString(child.Type()) {
+			h.add(child, lsp.KEYWORD_TOKEN, 0)
+		}
+	}
+}
+
+// string adds the span of a string and of the pseudo-variables written inside it.
+// The names of modules and of their parameters are not plain strings.
+func (h *highlighter) string(node *sitter.Node) {
+	if parent := node.Parent(); parent != nil {
+		if isModuleName(node) {
+			h.add(node, lsp.NAMESPACE_TOKEN, 0)
+			return
+		}
+		if parent.Type() == kamailio_cfg.ModparamNodeType && isNode(parent.ChildByFieldName("parameter_name"), node) {
+			h.add(node, lsp.PROPERTY_TOKEN, 0)
+			return
+		}
+	}
+	h.add(node, lsp.STRING_TOKEN, 0)
+	start := node.StartByte()
+	for _, match := range string_pseudo_variable_pattern.FindAllStringIndex(node.Content(h.source), -1) {
+		h.spans = append(h.spans, span{start: start + uint32(match[0]), end: start + uint32(match[1]), kind: lsp.VARIABLE_TOKEN})
+	}
+}
+
+// pseudoVariable adds the span of a pseudo-variable, of its transformations
+// and of the pseudo-variables they take as parameters.
+func (h *highlighter) pseudoVariable(node *sitter.Node) {
+	h.add(node, lsp.VARIABLE_TOKEN, 0)
+	h.transformations(node)
+}
+
+// transformations adds the spans of the transformations and pseudo-variables nested in the node.
+func (h *highlighter) transformations(node *sitter.Node) {
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		switch child.Type() {
+		case kamailio_cfg.PseudoVariableNodeType, kamailio_cfg.PseudoVariableExpressionNodeType:
+			h.pseudoVariable(child)
+			continue
+		case kamailio_cfg.TransformationNodeType:
+			h.add(child, lsp.OPERATOR_TOKEN, 0)
+		}
+		h.transformations(child)
+	}
+}
+
+// symbols adds the spans of the names of routes and defines,
+// and of the htables declared by modparam.
+func (h *highlighter) symbols(symbols []kamailio_cfg.Symbol) {
+	for _, symbol := range symbols {
+		var kind lsp.SemanticTokenType
+		switch symbol.Kind {
+		case kamailio_cfg.SYMBOL_KIND_AVP, kamailio_cfg.SYMBOL_KIND_VAR, kamailio_cfg.SYMBOL_KIND_XAVP:
+			continue
+		case kamailio_cfg.SYMBOL_KIND_HTABLE:
+			if !symbol.Declaration {
+				continue
+			}
+			kind = lsp.VARIABLE_TOKEN
+		case kamailio_cfg.SYMBOL_KIND_DEFINE:
+			kind = lsp.MACRO_TOKEN
+		default:
+			kind = lsp.FUNCTION_TOKEN
+		}
+		var modifiers lsp.SemanticTokenModifiers
+		if symbol.Declaration {
+			modifiers = lsp.DECLARATION_MODIFIER
+		}
+		h.spans = append(h.spans, span{start: offsetOfPoint(h.lines, symbol.Start), end: offsetOfPoint(h.lines, symbol.End), kind: kind, modifiers: modifiers})
+	}
+}
+
+// tokens turns the spans into tokens that do not overlap and do not span several lines.
+//
+// Parameters:
+//
+//	text string - The text of the document.
+//	inactive []span - The parts of the document in branches that are left out.
+//
+// Returns:
+//
+//	[]lsp.SemanticToken - The tokens, in the order they appear.
+func (h *highlighter) tokens(text string, inactive []span) []lsp.SemanticToken {
+	var tokens []lsp.SemanticToken
+	for _, piece := range flatten(h.spans) {
+		for _, region := range inactive {
+			if region.start <= piece.start && piece.start < region.end {
+				piece.modifiers |= lsp.INACTIVE_MODIFIER
+				break
+			}
+		}
+		line := int(pointOfOffset(h.lines, piece.start).Row)
+		for start := piece.start; start < piece.end; line++ {
+			end := piece.end
+			if next := strings.IndexByte(text[start:end], '\n'); next >= 0 {
+				end = start + uint32(next)
+			}
+			if segment := strings.TrimRight(text[start:end], "\r"); segment != "" {
+				tokens = append(tokens, lsp.SemanticToken{
+					Line:      line,
+					Character: lsp.LengthOf(text[h.lines[line]:start]),
+					Length:    lsp.LengthOf(segment),
+					Type:      piece.kind,
+					Modifiers: piece.modifiers,
+				})
+			}
+			start = end + 1
+		}
+	}
+	return tokens
+}
+
+// flatten cuts nested spans into pieces that do not overlap.
+// A span that starts within another one is nested in it, and cut at its end if it goes beyond it;
+// of two spans over the same part of the source, the last one added is nested in the other one.
+//
+// Parameters:
+//
+//	spans []span - The spans, in any order.
+//
+// Returns:
+//
+//	[]span - The pieces, in the order they appear.
+func flatten(spans []span) []span {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	// cursor is where the next piece of an open span starts
+	type open struct {
+		span
+		cursor uint32
+	}
+	var pieces []span
+	var stack []*open
+	emit := func(o *open, end uint32) {
+		if o.cursor < end {
+			pieces = append(pieces, span{start: o.cursor, end: end, kind: o.kind, modifiers: o.modifiers})
+		}
+	}
+	for _, s := range spans {
+		for len(stack) > 0 && stack[len(stack)-1].end <= s.start {
+			top := stack[len(stack)-1]
+			emit(top, top.end)
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if s.end > top.end {
+				s.end = top.end
+			}
+			emit(top, s.start)
+			top.cursor = s.end
+		}
+		stack = append(stack, &open{span: s, cursor: s.start})
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		emit(top, top.end)
+		stack = stack[:len(stack)-1]
+	}
+	return pieces
+}
+
+// inactiveRegions returns the parts of the document in branches of #!ifdef and #!ifndef
+// that are left out, going by the defines of the document that come before them.
+func inactiveRegions(root *sitter.Node, source []byte) []span {
+	defined := make(map[string]bool)
+	var regions []span
+	var visit func(node *sitter.Node, active bool)
+	visit = func(node *sitter.Node, active bool) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			switch child.Type() {
+			case kamailio_cfg.TopLevelItemNodeType:
+				visit(child, active)
+			case kamailio_cfg.PreprocDefNodeType, kamailio_cfg.PreprocTrydefNodeType, kamailio_cfg.PreprocRedefNodeType:
+				if name := child.ChildByFieldName("name"); name != nil && active {
+					defined[name.Content(source)] = true
+				}
+			case kamailio_cfg.PreprocIfdefNodeType, kamailio_cfg.PreprocIfndefNodeType:
+				// the branch starts after the name and ends at #!else or #!endif
+				start := child.Child(0).EndByte()
+				taken := false
+				if name := child.ChildByFieldName("name"); name != nil {
+					start = name.EndByte()
+					taken = defined[name.Content(source)]
+				}
+				if child.Type() == kamailio_cfg.PreprocIfndefNodeType {
+					taken = !taken
+				}
+				end := child.EndByte()
+				if last := child.Child(int(child.ChildCount()) - 1); last.Type() == "#!endif" {
+					end = last.StartByte()
+				}
+				alternative := child.ChildByFieldName("alternative")
+				branchEnd := end
+				if alternative != nil {
+					branchEnd = alternative.StartByte()
+				}
+				if !active || !taken {
+					regions = append(regions, span{start: start, end: branchEnd})
+				}
+				visit(child, active && taken)
+				if alternative != nil {
+					if !active || taken {
+						regions = append(regions, span{start: alternative.Child(0).EndByte(), end: end})
+					}
+					visit(alternative, active && !taken)
+				}
+			}
+		}
+	}
+	visit(root, true)
+	return regions
+}
+
+// lineStarts returns the offsets of the lines of the text.
+func lineStarts(text string) []uint32 {
+	lines := []uint32{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, uint32(i+1))
+		}
+	}
+	return lines
+}
+
+// offsetOfPoint returns the offset of a point, given the offsets of the lines.
+func offsetOfPoint(lines []uint32, point sitter.Point) uint32 {
+	if int(point.Row) >= len(lines) {
+		return lines[len(lines)-1]
+	}
+	return lines[point.Row] + point.Column
+}
+
+// pointOfOffset returns the point of an offset, given the offsets of the lines.
+func pointOfOffset(lines []uint32, offset uint32) sitter.Point {
+	row := sort.Search(len(lines), func(i int) bool { return lines[i] > offset }) - 1
+	return sitter.Point{Row: uint32(row), Column: offset - lines[row]}
+}
+
+// isNode reports whether both nodes are the same node of the tree.
+func isNode(a *sitter.Node, b *sitter.Node) bool {
+	return a != nil && b != nil && a.StartByte() == b.StartByte() && a.EndByte() == b.EndByte() && a.Type() == b.Type()
+}
+
+// semanticTokensCache keeps the last tokens sent for each document,
+// so that delta requests are answered with the changes since then.
+type semanticTokensCache struct {
+	mu      sync.Mutex
+	results map[lsp.DocumentURI]lsp.SemanticTokens
+	lastID  int
+}
+
+// newSemanticTokensCache creates and returns a new empty semanticTokensCache.
+func newSemanticTokensCache() *semanticTokensCache {
+	return &semanticTokensCache{results: make(map[lsp.DocumentURI]lsp.SemanticTokens)}
+}
+
+// encode encodes the tokens of the document under a new result ID and stores them.
+// It returns them together with the tokens stored before, if any.
+func (c *semanticTokensCache) encode(document *Document) (lsp.SemanticTokens, lsp.SemanticTokens, bool) {
+	tokens := document.SemanticTokens()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastID++
+	current := lsp.EncodeSemanticTokens(strconv.Itoa(c.lastID), tokens)
+	previous, found := c.results[document.URI]
+	c.results[document.URI] = current
+	return current, previous, found
+}
+
+// forget removes the tokens stored for the document with the given URI.
+func (c *semanticTokensCache) forget(uri lsp.DocumentURI) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.results, uri)
+}
+
+// SemanticTokensFull returns the semantic tokens of the document with the given URI.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the semanticTokens/full request.
+//	uri lsp.DocumentURI - The URI of the document.
+//
+// Returns:
+//
+//	lsp.SemanticTokensResponse - The tokens of the document, with the ID of the result.
+func (s *State) SemanticTokensFull(id rpc.ID, uri lsp.DocumentURI) lsp.SemanticTokensResponse {
+	document := s.Snapshot().GetDocument(uri)
+	if document == nil {
+		logger.Error("SemanticTokens request for document that is not open: ", uri)
+		return lsp.NewSemanticTokensResponse(id, lsp.SemanticTokens{})
+	}
+	tokens, _, _ := s.tokens.encode(document)
+	return lsp.NewSemanticTokensResponse(id, tokens)
+}
+
+// SemanticTokensDelta returns the changes of the semantic tokens of the document with the given URI,
+// or all of its tokens if the previous result is not the last one sent.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the semanticTokens/full/delta request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	previousResultID string - The ID of the result the client holds.
+//
+// Returns:
+//
+//	lsp.SemanticTokensDeltaResponse - The changes of the tokens, or the tokens.
+func (s *State) SemanticTokensDelta(id rpc.ID, uri lsp.DocumentURI, previousResultID string) lsp.SemanticTokensDeltaResponse {
+	document := s.Snapshot().GetDocument(uri)
+	if document == nil {
+		logger.Error("SemanticTokens delta request for document that is not open: ", uri)
+		return lsp.NewSemanticTokensDeltaResponse(id, lsp.SemanticTokens{Data: []uint32{}})
+	}
+	current, previous, found := s.tokens.encode(document)
+	if !found || previous.ResultID != previousResultID {
+		return lsp.NewSemanticTokensDeltaResponse(id, current)
+	}
+	return lsp.NewSemanticTokensDeltaResponse(id, lsp.DiffSemanticTokens(previous, current))
+}
+
+// SemanticTokensRange returns the semantic tokens of the lines of the range.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the semanticTokens/range request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	lines lsp.Range - The range, whose lines are highlighted entirely.
+//
+// Returns:
+//
+//	lsp.SemanticTokensResponse - The tokens of the range.
+func (s *Snapshot) SemanticTokensRange(id rpc.ID, uri lsp.DocumentURI, lines lsp.Range) lsp.SemanticTokensResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("SemanticTokens range request for document that is not open: ", uri)
+		return lsp.NewSemanticTokensResponse(id, lsp.SemanticTokens{})
+	}
+	var tokens []lsp.SemanticToken
+	for _, token := range document.SemanticTokens() {
+		if lines.Start.Line <= token.Line && token.Line <= lines.End.Line {
+			tokens = append(tokens, token)
+		}
+	}
+	return lsp.NewSemanticTokensResponse(id, lsp.EncodeSemanticTokens("", tokens))
+}
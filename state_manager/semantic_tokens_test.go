@@ -0,0 +1,97 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tokens_cfg = `#!define WITH_NAT
+#!ifndef WITH_NAT
+debug=3
+#!endif
+modparam("tm", "fr_timer", 30)
+request_route {
+	$var(x) = $(ru{s.len});
+	xlog("L_INFO", "from $fu\n");
+	t_on_failure("FAIL");
+}
+`
+
+func TestSemanticTokens(t *testing.T) {
+	document := state_manager.NewDocument("file:///kamailio.cfg", tokens_cfg, 1)
+	lines := strings.Split(tokens_cfg, "\n")
+
+	type token struct {
+		text      string
+		kind      lsp.SemanticTokenType
+		modifiers lsp.SemanticTokenModifiers
+	}
+	expected := []token{
+		{"#!define", lsp.KEYWORD_TOKEN, 0},
+		{"WITH_NAT", lsp.MACRO_TOKEN, lsp.DECLARATION_MODIFIER},
+		{"#!ifndef", lsp.KEYWORD_TOKEN, 0},
+		{"WITH_NAT", lsp.MACRO_TOKEN, 0},
+		{"debug", lsp.PROPERTY_TOKEN, lsp.INACTIVE_MODIFIER},
+		{"=", lsp.OPERATOR_TOKEN, lsp.INACTIVE_MODIFIER},
+		{"3", lsp.NUMBER_TOKEN, lsp.INACTIVE_MODIFIER},
+		{"#!endif", lsp.KEYWORD_TOKEN, 0},
+		{"modparam", lsp.KEYWORD_TOKEN, 0},
+		{`"tm"`, lsp.NAMESPACE_TOKEN, 0},
+		{`"fr_timer"`, lsp.PROPERTY_TOKEN, 0},
+		{"30", lsp.NUMBER_TOKEN, 0},
+		{"request_route", lsp.KEYWORD_TOKEN, 0},
+		{"$var(x)", lsp.VARIABLE_TOKEN, 0},
+		{"=", lsp.OPERATOR_TOKEN, 0},
+		{"$(ru", lsp.VARIABLE_TOKEN, 0},
+		{"{s.len}", lsp.OPERATOR_TOKEN, 0},
+		{")", lsp.VARIABLE_TOKEN, 0},
+		{"xlog", lsp.FUNCTION_TOKEN, 0},
+		{`"L_INFO"`, lsp.STRING_TOKEN, 0},
+		{`"from `, lsp.STRING_TOKEN, 0},
+		{"$fu", lsp.VARIABLE_TOKEN, 0},
+		{`\n"`, lsp.STRING_TOKEN, 0},
+		{"t_on_failure", lsp.FUNCTION_TOKEN, 0},
+		{`"`, lsp.STRING_TOKEN, 0},
+		{"FAIL", lsp.FUNCTION_TOKEN, 0},
+		{`"`, lsp.STRING_TOKEN, 0},
+	}
+	tokens := document.SemanticTokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got: %v", len(expected), tokens)
+	}
+	for i, token := range tokens {
+		text := lines[token.Line][token.Character : token.Character+token.Length]
+		if text != expected[i].text || token.Type != expected[i].kind || token.Modifiers != expected[i].modifiers {
+			t.Errorf("Expected: %v,\ngot: %q %v %v", expected[i], text, token.Type, token.Modifiers)
+		}
+	}
+}
+
+func TestSemanticTokensDelta(t *testing.T) {
+	uri := lsp.DocumentURI("file:///kamailio.cfg")
+	state := state_manager.NewState()
+	state.OpenDocument(uri, tokens_cfg, 1)
+	full := state.SemanticTokensFull(rpc.NewIntID(1), uri).Result
+
+	// the new line shifts the tokens after it by one line, only the first of them changes
+	state.ChangeDocument(uri, []lsp.TextDocumentContentChangeEvent{{
+		Range: &lsp.Range{Start: lsp.Position{Line: 5, Character: 0}, End: lsp.Position{Line: 5, Character: 0}},
+		Text:  "\n",
+	}}, 2)
+	delta, ok := state.SemanticTokensDelta(rpc.NewIntID(2), uri, full.ResultID).Result.(lsp.SemanticTokensDelta)
+	if !ok || delta.ResultID == full.ResultID {
+		t.Fatalf("Expected a delta with a new result ID, got: %v", delta)
+	}
+	expected := []lsp.SemanticTokensEdit{{Start: 60, DeleteCount: 1, Data: []uint32{2}}}
+	if !reflect.DeepEqual(delta.Edits, expected) {
+		t.Fatalf("Expected: %v,\ngot: %v", expected, delta.Edits)
+	}
+
+	if _, ok := state.SemanticTokensDelta(rpc.NewIntID(3), uri, "unknown").Result.(lsp.SemanticTokens); !ok {
+		t.Fatal("Expected all the tokens for an unknown previous result")
+	}
+}
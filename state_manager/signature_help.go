@@ -0,0 +1,250 @@
+package state_manager
+
+import (
+	"KamaiZen/document_manager"
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// CallAt returns the function called at the given position, when the position is within its argument list,
+// and the index of the argument the position is in.
+//
+// Parameters:
+//
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	string - The name of the function.
+//	int - The index of the argument, counting the commas before the position.
+//	bool - Whether the position is within the argument list of a call.
+func (d *Document) CallAt(position lsp.Position) (string, int, bool) {
+	root := d.Root()
+	if root == nil {
+		return "", 0, false
+	}
+	source := d.Source()
+	point := d.PointAt(position)
+	lines := lineStarts(d.Text)
+	cursor := offsetOfPoint(lines, point)
+	for node := root.NamedDescendantForPointRange(point, point); node != nil; node = node.Parent() {
+		if node.Type() != kamailio_cfg.ArgumentListNodeType {
+			continue
+		}
+		call := node.Parent()
+		if call == nil || call.Type() != kamailio_cfg.CallExpressionNodeType || !withinArguments(node, cursor) {
+			continue
+		}
+		function := call.ChildByFieldName("function")
+		if function == nil {
+			return "", 0, false
+		}
+		return strings.TrimSpace(function.Content(source)), countArguments(source[node.StartByte()+1 : cursor]), true
+	}
+	// the parser gives up on some calls that are still being written, e.g. when a comma
+	// is followed by the end of the block, so the line is read up to the position instead
+	return openCall(source[offsetOfPoint(lines, sitter.Point{Row: point.Row}):cursor])
+}
+
+// openCall returns the function whose argument list is left open at the end of the text,
+// and the index of the argument the end of the text is in.
+func openCall(text []byte) (string, int, bool) {
+	var open []int
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			open = append(open, i)
+		case c == ')' && len(open) > 0:
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) == 0 {
+		return "", 0, false
+	}
+	start := open[len(open)-1]
+	name := start
+	for name > 0 && isIdentifierByte(text[name-1]) {
+		name--
+	}
+	if name == start {
+		return "", 0, false
+	}
+	return string(text[name:start]), countArguments(text[start+1:]), true
+}
+
+// isIdentifierByte reports whether the byte may be part of the name of a function.
+func isIdentifierByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// withinArguments reports whether the offset is between the parentheses of the argument list.
+// An argument list whose closing parenthesis is still missing extends to its end.
+func withinArguments(arguments *sitter.Node, offset uint32) bool {
+	if offset <= arguments.StartByte() {
+		return false
+	}
+	end := arguments.EndByte()
+	if last := arguments.Child(int(arguments.ChildCount()) - 1); last != nil && last.Type() == ")" && !last.IsMissing() {
+		end = last.StartByte()
+	}
+	return offset <= end
+}
+
+// countArguments returns the number of commas that separate the arguments written so far,
+// leaving out the commas in strings and in nested parentheses, brackets and braces.
+func countArguments(arguments []byte) int {
+	count, depth := 0, 0
+	var quote byte
+	for i := 0; i < len(arguments); i++ {
+		c := arguments[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth > 0 {
+				depth--
+			}
+		case c == ',' && depth == 0:
+			count++
+		}
+	}
+	return count
+}
+
+// LoadedModules returns the names of the modules loaded by the document with loadmodule.
+//
+// Returns:
+//
+//	[]string - The names of the modules, e.g. tm for "tm.so".
+func (d *Document) LoadedModules() []string {
+	root := d.Root()
+	if root == nil {
+		return nil
+	}
+	source := d.Source()
+	var modules []string
+	var visit func(node *sitter.Node)
+	visit = func(node *sitter.Node) {
+		if node.Type() == kamailio_cfg.LoadmoduleNodeType {
+			if name := node.ChildByFieldName("module_name"); name != nil {
+				modules = append(modules, moduleName(name.Content(source)))
+			}
+			return
+		}
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			visit(node.NamedChild(i))
+		}
+	}
+	visit(root)
+	return modules
+}
+
+// signatures returns the signatures of a function, one for each module exporting it.
+// When several modules export the function, only those that are loaded are kept,
+// unless none of them is.
+//
+// Parameters:
+//
+//	name string - The name of the function.
+//	loaded map[string]bool - The modules loaded by the configuration.
+//
+// Returns:
+//
+//	[]lsp.SignatureInformation - The signatures, sorted by module name.
+func signatures(name string, loaded map[string]bool) []lsp.SignatureInformation {
+	docs := document_manager.GetFunctionDocsByName(name)
+	var modules []string
+	for module := range docs {
+		if module == document_manager.CORE_MODULE || loaded[module] {
+			modules = append(modules, module)
+		}
+	}
+	if len(modules) == 0 {
+		for module := range docs {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	format := lsp.GetClientCapabilities().SignatureDocumentationFormat()
+	result := make([]lsp.SignatureInformation, 0, len(modules))
+	for _, module := range modules {
+		doc := docs[module]
+		signature := lsp.SignatureInformation{Parameters: []lsp.ParameterInformation{}}
+		var labels []string
+		for _, parameter := range doc.ParameterList() {
+			label := parameter.Name
+			if parameter.Optional {
+				label = "[" + label + "]"
+			}
+			labels = append(labels, label)
+			signature.Parameters = append(signature.Parameters, lsp.ParameterInformation{Label: label})
+		}
+		signature.Label = name + "(" + strings.Join(labels, ", ") + ")"
+		documentation := lsp.NewMarkupContent(format, "# Module: "+module+"\n\n"+strings.TrimSpace(doc.Description))
+		signature.Documentation = &documentation
+		result = append(result, signature)
+	}
+	return result
+}
+
+// SignatureHelp returns the signatures of the function called at the given position,
+// with the parameter the position is in as the active one.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the signatureHelp request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.SignatureHelpResponse - The signatures, with a null result if no known function is called at the position.
+func (s *Snapshot) SignatureHelp(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.SignatureHelpResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("SignatureHelp request for document that is not open: ", uri)
+		return lsp.NewSignatureHelpResponse(id, nil)
+	}
+	name, parameter, ok := document.CallAt(position)
+	if !ok {
+		return lsp.NewSignatureHelpResponse(id, nil)
+	}
+	loaded := make(map[string]bool)
+	for _, document := range s.workspace(uri) {
+		for _, module := range document.LoadedModules() {
+			loaded[module] = true
+		}
+	}
+	result := signatures(name, loaded)
+	if len(result) == 0 {
+		return lsp.NewSignatureHelpResponse(id, nil)
+	}
+	return lsp.NewSignatureHelpResponse(id, &lsp.SignatureHelp{
+		Signatures:      result,
+		ActiveParameter: parameter,
+	})
+}
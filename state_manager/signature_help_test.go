@@ -0,0 +1,127 @@
+package state_manager_test
+
+import (
+	"KamaiZen/document_manager"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/settings"
+	"KamaiZen/state_manager"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const signature_help_cfg = `request_route {
+	append_branch("sip:a@b", "0.5");
+	log(1, "a, b (c)");
+	setbflag(
+	forward($(ru{s.select,1,:}), 5060);
+}
+`
+
+func TestCallAt(t *testing.T) {
+	document := state_manager.NewDocument("file:///kamailio.cfg", signature_help_cfg, 1)
+	tests := []struct {
+		name     string
+		position lsp.Position
+		function string
+		argument int
+		ok       bool
+	}{
+		{"first argument", lsp.Position{Line: 1, Character: 15}, "append_branch", 0, true},
+		{"second argument", lsp.Position{Line: 1, Character: 27}, "append_branch", 1, true},
+		{"before the parenthesis", lsp.Position{Line: 1, Character: 5}, "", 0, false},
+		{"after the parenthesis", lsp.Position{Line: 1, Character: 33}, "", 0, false},
+		{"comma in a string", lsp.Position{Line: 2, Character: 17}, "log", 1, true},
+		{"open call", lsp.Position{Line: 3, Character: 10}, "setbflag", 0, true},
+		{"comma in a transformation", lsp.Position{Line: 4, Character: 24}, "forward", 0, true},
+		{"after a transformation", lsp.Position{Line: 4, Character: 30}, "forward", 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			function, argument, ok := document.CallAt(test.position)
+			if function != test.function || argument != test.argument || ok != test.ok {
+				t.Errorf("Expected: %s %d %v,\ngot: %s %d %v", test.function, test.argument, test.ok, function, argument, ok)
+			}
+		})
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	uri := lsp.DocumentURI("file:///signature_help.cfg")
+	state := state_manager.NewState()
+	state.OpenDocument(uri, signature_help_cfg, 1)
+	help := state.Snapshot().SignatureHelp(rpc.NewIntID(1), uri, lsp.Position{Line: 1, Character: 27}).Result
+	if help == nil || len(help.Signatures) != 1 {
+		t.Fatalf("Expected a signature, got: %v", help)
+	}
+	signature := help.Signatures[0]
+	if signature.Label != "append_branch([uri], [q])" {
+		t.Errorf("Unexpected label: %s", signature.Label)
+	}
+	if len(signature.Parameters) != 2 || signature.Parameters[1].Label != "[q]" || help.ActiveParameter != 1 {
+		t.Errorf("Unexpected parameters: %v, active: %d", signature.Parameters, help.ActiveParameter)
+	}
+	if signature.Documentation == nil || signature.Documentation.Value == "" {
+		t.Errorf("Expected the description of the function")
+	}
+
+	help = state.Snapshot().SignatureHelp(rpc.NewIntID(2), uri, lsp.Position{Line: 2, Character: 5}).Result
+	if help == nil || help.Signatures[0].Label != "log([level], message)" {
+		t.Errorf("Unexpected signature: %v", help)
+	}
+
+	state.OpenDocument(uri, "request_route {\n\tno_such_function(\n}\n", 2)
+	if help = state.Snapshot().SignatureHelp(rpc.NewIntID(3), uri, lsp.Position{Line: 1, Character: 18}).Result; help != nil {
+		t.Errorf("Expected no signature help for an unknown function, got: %v", help)
+	}
+}
+
+// loadModuleDocs loads the documentation of modules from READMEs written in a temporary
+// Kamailio source tree, until the end of the test.
+func loadModuleDocs(t *testing.T, readmes map[string]string) {
+	t.Helper()
+	path := t.TempDir()
+	for module, readme := range readmes {
+		directory := filepath.Join(path, "src", "modules", module)
+		if err := os.MkdirAll(directory, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(directory, "README"), []byte(readme), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := document_manager.Initialise(context.Background(), settings.LSPSettings{KamailioSourcePath: path}, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(document_manager.Reset)
+}
+
+func TestSignatureHelpPicksLoadedModule(t *testing.T) {
+	readme := func(module string, parameters string) string {
+		return "4.1.  do_it(" + parameters + ")\n\n   Does it in " + module + ".\n\n   Example 1.1. do_it usage\n...\ndo_it(\"x\");\n...\n"
+	}
+	loadModuleDocs(t, map[string]string{"alpha": readme("alpha", "key"), "beta": readme("beta", "key[, value]")})
+
+	uri := lsp.DocumentURI("file:///modules.cfg")
+	state := state_manager.NewState()
+	state.OpenDocument(uri, "loadmodule \"beta.so\"\nrequest_route {\n\tdo_it(\"x\", \n}\n", 1)
+	help := state.Snapshot().SignatureHelp(rpc.NewIntID(1), uri, lsp.Position{Line: 2, Character: 12}).Result
+	if help == nil || len(help.Signatures) != 1 {
+		t.Fatalf("Expected the signature of the loaded module, got: %v", help)
+	}
+	if help.Signatures[0].Label != "do_it(key, [value])" || help.ActiveParameter != 1 {
+		t.Errorf("Unexpected signature: %s, active: %d", help.Signatures[0].Label, help.ActiveParameter)
+	}
+	if !strings.Contains(help.Signatures[0].Documentation.Value, "Does it in beta.") {
+		t.Errorf("Expected the description of the function, got: %s", help.Signatures[0].Documentation.Value)
+	}
+
+	state.OpenDocument(uri, "request_route {\n\tdo_it(\n}\n", 2)
+	help = state.Snapshot().SignatureHelp(rpc.NewIntID(2), uri, lsp.Position{Line: 1, Character: 7}).Result
+	if help == nil || len(help.Signatures) != 2 {
+		t.Errorf("Expected the signatures of both modules, got: %v", help)
+	}
+}
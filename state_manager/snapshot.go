@@ -0,0 +1,227 @@
+package state_manager
+
+import (
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+)
+
+// Snapshot is an immutable view of the state at a given version.
+// Language features read documents from a snapshot, never from the State directly.
+type Snapshot struct {
+	Version   int                           // Incremented every time a document changes.
+	Documents map[lsp.DocumentURI]*Document // A map of document URIs to their corresponding documents.
+}
+
+// GetDocument returns the document with the given URI.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document.
+//
+// Returns:
+//
+//	*Document - The document, or nil if it is not open.
+func (s *Snapshot) GetDocument(uri lsp.DocumentURI) *Document {
+	return s.Documents[uri]
+}
+
+// Hover returns the hover information for the given document URI and position.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the hover request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.HoverResponse - The hover response.
+func (s *Snapshot) Hover(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.HoverResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("Hover request for document that is not open: ", uri)
+		return lsp.NewHoverResponse(id, "")
+	}
+	return lsp.NewHoverResponse(id, GetNodeDocsAtPosition(document, position))
+}
+
+// Definition returns the location of the declaration of the symbol at the given position,
+// e.g. route[RELAY] for route(RELAY) or failure_route[X] for t_on_failure("X").
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the definition request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.DefinitionProviderResponse - The definition response, with a null result if nothing matches.
+func (s *Snapshot) Definition(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.DefinitionProviderResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("Definition request for document that is not open: ", uri)
+		return lsp.NewDefintionProviderResponse(id, nil)
+	}
+	symbol, ok := document.SymbolAt(position)
+	if !ok {
+		return lsp.NewDefintionProviderResponse(id, nil)
+	}
+	return lsp.NewDefintionProviderResponse(id, s.declaration(uri, symbol))
+}
+
+// References returns the locations of the symbol at the given position: routes, defines,
+// AVPs, script variables, XAVPs and htables.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the references request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//	includeDeclaration bool - Whether the declaration of the symbol is included.
+//
+// Returns:
+//
+//	lsp.ReferencesResponse - The references response, empty if there is no symbol at the position.
+func (s *Snapshot) References(id rpc.ID, uri lsp.DocumentURI, position lsp.Position, includeDeclaration bool) lsp.ReferencesResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("References request for document that is not open: ", uri)
+		return lsp.NewReferencesResponse(id, nil)
+	}
+	symbol, ok := document.SymbolAt(position)
+	if !ok {
+		return lsp.NewReferencesResponse(id, nil)
+	}
+	return lsp.NewReferencesResponse(id, s.references(uri, symbol, includeDeclaration))
+}
+
+// PrepareRename returns the name at the given position if it can be renamed.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the prepareRename request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.PrepareRenameResponse - The range and text of the name, with a null result if it can't be renamed.
+func (s *Snapshot) PrepareRename(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.PrepareRenameResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("PrepareRename request for document that is not open: ", uri)
+		return lsp.NewPrepareRenameResponse(id, nil)
+	}
+	symbol, ok := document.SymbolAt(position)
+	if !ok || !s.renamable(uri, symbol) {
+		return lsp.NewPrepareRenameResponse(id, nil)
+	}
+	return lsp.NewPrepareRenameResponse(id, &lsp.PrepareRenameResult{
+		Range:       document.RangeOfSymbol(symbol),
+		Placeholder: symbol.Name,
+	})
+}
+
+// Rename returns the edits that rename the symbol at the given position: its declaration,
+// every use and the route names passed as strings to the route callbacks.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the rename request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//	newName string - The new name of the symbol.
+//
+// Returns:
+//
+//	lsp.RenameResponse - The rename response.
+//	error - An error if there is nothing to rename, the new name is not a valid identifier
+//	or another symbol already has it.
+func (s *Snapshot) Rename(id rpc.ID, uri lsp.DocumentURI, position lsp.Position, newName string) (lsp.RenameResponse, error) {
+	document := s.GetDocument(uri)
+	if document == nil {
+		return lsp.RenameResponse{}, rpc.Errorf(rpc.InvalidParams, "Document is not open: %s", uri)
+	}
+	symbol, ok := document.SymbolAt(position)
+	if !ok || !s.renamable(uri, symbol) {
+		return lsp.RenameResponse{}, rpc.NewError(rpc.RequestFailed, "There is nothing to rename at this position")
+	}
+	if !identifier_pattern.MatchString(newName) {
+		return lsp.RenameResponse{}, rpc.Errorf(rpc.RequestFailed, "%q is not a valid name", newName)
+	}
+	if newName != symbol.Name && s.collides(uri, symbol, newName) {
+		return lsp.RenameResponse{}, rpc.Errorf(rpc.RequestFailed, "A %s named %s already exists", symbol.Kind, newName)
+	}
+	edit := lsp.WorkspaceEdit{Changes: make(map[lsp.DocumentURI][]lsp.TextEdit)}
+	for _, location := range s.references(uri, symbol, true) {
+		edit.Changes[location.URI] = append(edit.Changes[location.URI], lsp.TextEdit{Range: location.Range, NewText: newName})
+	}
+	return lsp.NewRenameResponse(id, &edit), nil
+}
+
+// DocumentSymbol returns the outline of the document with the given URI.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the documentSymbol request.
+//	uri lsp.DocumentURI - The URI of the document.
+//
+// Returns:
+//
+//	lsp.DocumentSymbolResponse - The symbols of the document.
+func (s *Snapshot) DocumentSymbol(id rpc.ID, uri lsp.DocumentURI) lsp.DocumentSymbolResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("DocumentSymbol request for document that is not open: ", uri)
+		return lsp.NewDocumentSymbolResponse(id, uri, nil)
+	}
+	return lsp.NewDocumentSymbolResponse(id, uri, document.Outline())
+}
+
+// FoldingRange returns the folding ranges of the document with the given URI.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the foldingRange request.
+//	uri lsp.DocumentURI - The URI of the document.
+//
+// Returns:
+//
+//	lsp.FoldingRangeResponse - The folding ranges of the document.
+func (s *Snapshot) FoldingRange(id rpc.ID, uri lsp.DocumentURI) lsp.FoldingRangeResponse {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Error("FoldingRange request for document that is not open: ", uri)
+		return lsp.NewFoldingRangeResponse(id, nil)
+	}
+	return lsp.NewFoldingRangeResponse(id, document.FoldingRanges())
+}
+
+// TextDocumentCompletion returns the completion items for the given document URI and position.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the completion request.
+//	uri lsp.DocumentURI - The URI of the document.
+//	position lsp.Position - The position within the document.
+//
+// Returns:
+//
+//	lsp.CompletionResponse - The completion response.
+func (s *Snapshot) TextDocumentCompletion(id rpc.ID, uri lsp.DocumentURI, position lsp.Position) lsp.CompletionResponse {
+	logger.Debug("Completion request for document with URI: ", uri)
+	items := GetCompletionItems(s, uri, position)
+	return lsp.NewCompletionResponse(id, items)
+}
+
+func (s *Snapshot) Formatting(id rpc.ID, uri lsp.DocumentURI, options lsp.FormattingOptions) lsp.DocumentFormattingResponse {
+	// TODO: Implement formatting
+	// document := s.GetDocument(uri)
+	// visitor := kamailio_cfg.NewFormattingVisitor()
+	// document.Analyzer().GetAST().Accept(visitor, document.Analyzer())
+	// edits := visitor.GetEdits()
+	// return lsp.NewDocumentFormattingResponse(id, edits)
+	return lsp.NewDocumentFormattingResponse(id, []lsp.TextEdit{})
+}
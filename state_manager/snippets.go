@@ -0,0 +1,105 @@
+package state_manager
+
+import (
+	"KamaiZen/document_manager"
+	"KamaiZen/lsp"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// snippet is a construct inserted by completion, with tab stops such as $1 and placeholders such as ${1:NAME}.
+type snippet struct {
+	label  string
+	detail string
+	body   string
+}
+
+// snippet_prefix_pattern matches the part of a snippet typed before the position, including the #! of directives.
+var snippet_prefix_pattern = regexp.MustCompile(`(#!)?\w*$`)
+
+// ifdef_snippet is offered both at the top level and within routes.
+var ifdef_snippet = snippet{"#!ifdef", "#!ifdef ... #!endif block", "#!ifdef ${1:WITH_FEATURE}\n$0\n#!endif"}
+
+// top_level_snippets are offered outside of the routing blocks.
+var top_level_snippets = []snippet{
+	{"route", "route[NAME] block", "route[${1:NAME}] {\n\t$0\n}"},
+	{"failure_route", "failure_route[NAME] block", "failure_route[${1:NAME}] {\n\t$0\n}"},
+	{"onreply_route", "onreply_route[NAME] block", "onreply_route[${1:NAME}] {\n\t$0\n}"},
+	{"event_route", "event_route[module:event] block", "event_route[${1:tm}:${2:local-request}] {\n\t$0\n}"},
+	ifdef_snippet,
+}
+
+// code_snippets are offered within the routing blocks.
+var code_snippets = []snippet{
+	{"if", "if block", "if (${1:condition}) {\n\t$0\n}"},
+	{"if else", "if ... else block", "if (${1:condition}) {\n\t$2\n} else {\n\t$0\n}"},
+	{"switch", "switch block", "switch (${1:\\$var(x)}) {\n\tcase ${2:value}:\n\t\t$0\n\t\tbreak;\n\tdefault:\n\t\tbreak;\n}"},
+	{"while", "while loop", "while (${1:condition}) {\n\t$0\n}"},
+	ifdef_snippet,
+}
+
+// typedRange returns the range of the text typed on the line before the position, to be replaced by a completion.
+// The typed text is ASCII, so its length is the same in every encoding.
+func typedRange(position lsp.Position, typed string) lsp.Range {
+	start := position
+	start.Character -= len(typed)
+	return lsp.Range{Start: start, End: position}
+}
+
+// snippetCompletionItems returns the snippets as completion items replacing the typed part of their label,
+// or nothing if the client does not support snippets.
+//
+// Parameters:
+//
+//	snippets []snippet - The snippets to offer.
+//	position lsp.Position - The position of the completion request.
+//	line string - The text of the line before the position.
+//
+// Returns:
+//
+//	[]lsp.CompletionItem - The snippets, as completion items.
+func snippetCompletionItems(snippets []snippet, position lsp.Position, line string) []lsp.CompletionItem {
+	if !lsp.GetClientCapabilities().SnippetSupport() {
+		return nil
+	}
+	replace := typedRange(position, snippet_prefix_pattern.FindString(line))
+	var items []lsp.CompletionItem
+	for _, snippet := range snippets {
+		items = append(items, lsp.CompletionItem{
+			Detail:           snippet.detail,
+			Label:            snippet.label,
+			Documentation:    lsp.NewCompletionDocumentation("```\n" + snippet.body + "\n```"),
+			Kind:             lsp.SNIPPET_COMPLETION,
+			InsertTextFormat: lsp.SNIPPET_FORMAT,
+			TextEdit:         &lsp.TextEdit{Range: replace, NewText: snippet.body},
+		})
+	}
+	return items
+}
+
+// functionSnippet returns the call of the function with a placeholder for each of its mandatory parameters,
+// e.g. t_on_failure(${1:failure_route}). The cursor is left within the parentheses of functions
+// that only have optional parameters.
+func functionSnippet(function document_manager.FunctionDocumentation) string {
+	parameters := function.ParameterList()
+	var placeholders []string
+	for _, parameter := range parameters {
+		if parameter.Optional {
+			continue
+		}
+		placeholders = append(placeholders, "${"+strconv.Itoa(len(placeholders)+1)+":"+escapeSnippet(parameter.Name)+"}")
+	}
+	switch {
+	case len(placeholders) > 0:
+		return function.Name + "(" + strings.Join(placeholders, ", ") + ")"
+	case len(parameters) > 0:
+		return function.Name + "($0)"
+	}
+	return function.Name + "()"
+}
+
+// escapeSnippet escapes the characters that have a meaning in the text of a placeholder.
+func escapeSnippet(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(text)
+}
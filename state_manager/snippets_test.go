@@ -0,0 +1,76 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"testing"
+)
+
+const snippets_cfg = `loadmodule "tm.so"
+rou
+request_route {
+	t_on
+	t_rel
+	wh
+}
+#!if
+`
+
+func TestSnippets(t *testing.T) {
+	loadModuleDocs(t, map[string]string{"tm": "4.1.  t_on_failure(failure_route)\n\n   Sets the failure route.\n\n4.2.  t_relay([host, port])\n\n   Relays the request.\n"})
+	uri := lsp.DocumentURI("file:///kamailio.cfg")
+	state := state_manager.NewState()
+	state.OpenDocument(uri, snippets_cfg, 1)
+
+	completion := func(position lsp.Position) map[string]lsp.CompletionItem {
+		items := make(map[string]lsp.CompletionItem)
+		for _, item := range state.Snapshot().TextDocumentCompletion(rpc.NewIntID(1), uri, position).Result {
+			items[item.Label] = item
+		}
+		return items
+	}
+	if items := completion(lsp.Position{Line: 1, Character: 3}); len(items) != 0 {
+		t.Fatalf("Expected no snippets without client support, got: %v", items)
+	}
+	if item := completion(lsp.Position{Line: 3, Character: 5})["t_on_failure()"]; item.TextEdit != nil || item.InsertTextFormat != 0 {
+		t.Fatalf("Expected a plain function without client support, got: %v", item)
+	}
+
+	capabilities := lsp.ClientCapabilities{}
+	capabilities.TextDocument.Completion.CompletionItem.SnippetSupport = true
+	lsp.SetClientCapabilities(capabilities)
+	t.Cleanup(func() { lsp.SetClientCapabilities(lsp.ClientCapabilities{}) })
+
+	line := func(line int, start int, end int) lsp.Range {
+		return lsp.Range{Start: lsp.Position{Line: line, Character: start}, End: lsp.Position{Line: line, Character: end}}
+	}
+	tests := []struct {
+		name     string
+		position lsp.Position
+		label    string
+		absent   string
+		edit     lsp.TextEdit
+	}{
+		{"route", lsp.Position{Line: 1, Character: 3}, "route", "t_relay()", lsp.TextEdit{Range: line(1, 0, 3), NewText: "route[${1:NAME}] {\n\t$0\n}"}},
+		{"mandatory parameter", lsp.Position{Line: 3, Character: 5}, "t_on_failure()", "route", lsp.TextEdit{Range: line(3, 1, 5), NewText: "t_on_failure(${1:failure_route})"}},
+		{"optional parameters", lsp.Position{Line: 4, Character: 6}, "t_relay()", "route", lsp.TextEdit{Range: line(4, 1, 6), NewText: "t_relay($0)"}},
+		{"while", lsp.Position{Line: 5, Character: 3}, "while", "event_route", lsp.TextEdit{Range: line(5, 1, 3), NewText: "while (${1:condition}) {\n\t$0\n}"}},
+		{"directive", lsp.Position{Line: 7, Character: 4}, "#!ifdef", "if", lsp.TextEdit{Range: line(7, 0, 4), NewText: "#!ifdef ${1:WITH_FEATURE}\n$0\n#!endif"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := completion(test.position)
+			item, found := items[test.label]
+			if !found {
+				t.Fatalf("Expected %s, got: %v", test.label, items)
+			}
+			if _, found := items[test.absent]; found {
+				t.Errorf("Unexpected item: %s", test.absent)
+			}
+			if item.InsertTextFormat != lsp.SNIPPET_FORMAT || item.TextEdit == nil || *item.TextEdit != test.edit {
+				t.Errorf("Expected the snippet %v, got: %v", test.edit, item.TextEdit)
+			}
+		})
+	}
+}
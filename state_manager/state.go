@@ -1,18 +1,22 @@
 package state_manager
 
 import (
-	"KamaiZen/kamailio_cfg"
 	"KamaiZen/logger"
 	"KamaiZen/lsp"
-	"fmt"
+	"sync"
 )
 
+// State holds the documents known to the server.
+// It is safe for concurrent use: writers publish a new immutable Snapshot for every change,
+// and readers work against the Snapshot they took, so a slow reader never blocks a writer.
 type State struct {
-	Documents map[lsp.DocumentURI]string // A map of document URIs to their corresponding text content.
-	Analyzer  *kamailio_cfg.Analyzer     // The analyzer used for parsing and analyzing the documents.
+	mu       sync.RWMutex
+	snapshot *Snapshot
+	index    *WorkspaceIndex
+	tokens   *semanticTokensCache
 }
 
-var state State
+var state = NewState()
 
 // GetState returns the current state.
 //
@@ -20,58 +24,68 @@ var state State
 //
 //	*State - The current state.
 func GetState() *State {
-	return &state
+	return state
 }
 
 // SetState sets the current state to the given state.
 //
 // Parameters:
 //
-//	s State - The new state to be set.
-func SetState(s State) {
+//	s *State - The new state to be set.
+func SetState(s *State) {
 	state = s
 }
 
-// updateState updates the state with the given document URI and text.
-// It also reinitializes the analyzer.
-//
-// Parameters:
-//
-//	DocumentURI lsp.DocumentURI - The URI of the document to be updated.
-//	text string - The new text content of the document.
-func (s *State) updateState(DocumentURI lsp.DocumentURI, text string) {
-	state.Documents[DocumentURI] = text
-	s.Analyzer = kamailio_cfg.NewAnalyzer()
-}
-
 // InitializeState initializes and returns a new state.
-// It creates a new state, initializes the analyzer, and logs the initialization.
+// It creates a new state and logs the initialization.
 //
 // Returns:
 //
-//	State - The initialized state.
-func InitializeState() State {
+//	*State - The initialized state.
+func InitializeState() *State {
 	state = NewState()
-	state.Analyzer = kamailio_cfg.NewAnalyzer()
-	logger.Debugf("Parser: %v", state.Analyzer.GetParser())
 	logger.Debug("State initialized")
-	kamailio_cfg.InitialiseVariables()
 	return state
 }
 
 // NewState creates and returns a new instance of State.
-// It initializes the Documents map.
+// It starts with an empty snapshot.
 //
 // Returns:
 //
-//	State - The initialized state.
-func NewState() State {
-	return State{
-		Documents: make(map[lsp.DocumentURI]string),
+//	*State - The initialized state.
+func NewState() *State {
+	return &State{
+		snapshot: &Snapshot{
+			Documents: make(map[lsp.DocumentURI]*Document),
+		},
+		index:  NewWorkspaceIndex(),
+		tokens: newSemanticTokensCache(),
 	}
 }
 
-// GetDocument returns the text content of the document with the given URI.
+// Index returns the index of the configurations in the workspace folders.
+//
+// Returns:
+//
+//	*WorkspaceIndex - The workspace index.
+func (s *State) Index() *WorkspaceIndex {
+	return s.index
+}
+
+// Snapshot returns the current snapshot of the state.
+// The snapshot is immutable and may be used for as long as the caller needs it.
+//
+// Returns:
+//
+//	*Snapshot - The current snapshot.
+func (s *State) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+// GetDocument returns the latest version of the document with the given URI.
 //
 // Parameters:
 //
@@ -79,19 +93,40 @@ func NewState() State {
 //
 // Returns:
 //
-//	string - The text content of the document.
-func (s *State) GetDocument(uri lsp.DocumentURI) string {
-	return s.Documents[uri]
+//	*Document - The document, or nil if it is not open.
+func (s *State) GetDocument(uri lsp.DocumentURI) *Document {
+	return s.Snapshot().GetDocument(uri)
 }
 
-// SetDocument sets the text content of the document with the given URI.
+// publish stores the given document in a new snapshot and makes it the current one.
 //
 // Parameters:
 //
-//	uri lsp.DocumentURI - The URI of the document.
-//	text string - The new text content of the document.
-func (s *State) SetDocument(uri lsp.DocumentURI, text string) {
-	s.Documents[uri] = text
+//	document *Document - The new version of the document.
+func (s *State) publish(document *Document) {
+	s.update(func(documents map[lsp.DocumentURI]*Document) {
+		documents[document.URI] = document
+	})
+}
+
+// update copies the documents of the current snapshot, lets f modify the copy
+// and makes the result the current snapshot.
+//
+// Parameters:
+//
+//	f func(map[lsp.DocumentURI]*Document) - The modification to apply.
+func (s *State) update(f func(map[lsp.DocumentURI]*Document)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	documents := make(map[lsp.DocumentURI]*Document, len(s.snapshot.Documents)+1)
+	for uri, d := range s.snapshot.Documents {
+		documents[uri] = d
+	}
+	f(documents)
+	s.snapshot = &Snapshot{
+		Version:   s.snapshot.Version + 1,
+		Documents: documents,
+	}
 }
 
 func (s *State) RegisterSubscribers() {
@@ -113,96 +148,101 @@ func (s *State) NotifySubsrcibers(uri lsp.DocumentURI, changes []lsp.TextDocumen
 //
 //	uri lsp.DocumentURI - The URI of the document.
 //	text string - The text content of the document.
+//	version int - The version of the document.
 //
 // Returns:
 //
 //	[]lsp.Diagnostic - The list of diagnostics.
-func (s *State) OpenDocument(uri lsp.DocumentURI, text string) []lsp.Diagnostic {
-	s.Documents[uri] = text
-	s.Analyzer.Build([]byte(text))
-	visitor := kamailio_cfg.NewDiagnosticVisitor()
-	s.Analyzer.GetAST().Accept(visitor, s.Analyzer)
-	kamailio_cfg.ExtractGlobalVariables(s.Analyzer, []byte(text))
-	visitor.GetQueryDiagnostics(s.Analyzer.GetAST(), s.Analyzer)
-	return visitor.GetDiagnostics()
+func (s *State) OpenDocument(uri lsp.DocumentURI, text string, version int) []lsp.Diagnostic {
+	document := NewDocument(uri, text, version)
+	s.publish(document)
+	return document.Diagnostics
 }
 
-// UpdateDocument updates the document with the given URI and text, and returns the diagnostics.
+// ChangeDocument applies the given content changes to the document with the given URI,
+// reparses it incrementally and returns the diagnostics.
+// Changes to a document that is not open are applied to an empty document.
+// Changes to the same document must be applied in order, the server's scheduler
+// serialises them; changes to different documents may be applied concurrently.
 //
 // Parameters:
 //
 //	uri lsp.DocumentURI - The URI of the document.
-//	text string - The new text content of the document.
+//	changes []lsp.TextDocumentContentChangeEvent - The changes, in the order they were made.
+//	version int - The version of the document after the changes.
 //
 // Returns:
 //
 //	[]lsp.Diagnostic - The list of diagnostics.
-func (s *State) UpdateDocument(uri lsp.DocumentURI, text string) []lsp.Diagnostic {
-	s.updateState(uri, text)
-	// for now we will parse the whole document
-	s.Analyzer.Build([]byte(text))
-	visitor := kamailio_cfg.NewDiagnosticVisitor()
-	s.Analyzer.GetAST().Accept(visitor, s.Analyzer)
-	kamailio_cfg.ExtractGlobalVariables(s.Analyzer, []byte(text))
-	visitor.GetQueryDiagnostics(s.Analyzer.GetAST(), s.Analyzer)
-	return visitor.GetDiagnostics()
+func (s *State) ChangeDocument(uri lsp.DocumentURI, changes []lsp.TextDocumentContentChangeEvent, version int) []lsp.Diagnostic {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Warn("Change for document that is not open: ", uri)
+		document = NewDocument(uri, "", version)
+	}
+	document = document.Apply(changes, version)
+	s.publish(document)
+	return document.Diagnostics
 }
 
-// Hover returns the hover information for the given document URI and position.
+// CloseDocument evicts the document with the given URI from the state.
 //
 // Parameters:
 //
-//	id int - The ID of the hover request.
 //	uri lsp.DocumentURI - The URI of the document.
-//	position lsp.Position - The position within the document.
-//
-// Returns:
-//
-//	lsp.HoverResponse - The hover response.
-func (s *State) Hover(id int, uri lsp.DocumentURI, position lsp.Position) lsp.HoverResponse {
-	return lsp.NewHoverResponse(id,
-		fmt.Sprintf("%s", GetNodeDocsAtPosition(uri, position, []byte(s.Documents[uri]))))
+func (s *State) CloseDocument(uri lsp.DocumentURI) {
+	s.update(func(documents map[lsp.DocumentURI]*Document) {
+		delete(documents, uri)
+	})
+	s.tokens.forget(uri)
 }
 
-// Definition returns the definition information for the given document URI and position.
+// SaveDocument analyses the saved document with the given URI again and returns the diagnostics.
+// If the client sent the saved content, it replaces the content of the document.
 //
 // Parameters:
 //
-//	id int - The ID of the definition request.
 //	uri lsp.DocumentURI - The URI of the document.
-//	position lsp.Position - The position within the document.
+//	text *string - The saved content, or nil if the client did not include it.
 //
 // Returns:
 //
-//	lsp.DefinitionProviderResponse - The definition response.
-func (s *State) Definition(id int, uri lsp.DocumentURI, position lsp.Position) lsp.DefinitionProviderResponse {
-	// TODO: Implement definition
-	// lookup the text content type from the analysis and return the definition
-	return lsp.NewDefintionProviderResponse(id, fmt.Sprintf("File: %s :: Definition at line %d, character %d", uri, position.Line, position.Character))
+//	[]lsp.Diagnostic - The list of diagnostics.
+func (s *State) SaveDocument(uri lsp.DocumentURI, text *string) []lsp.Diagnostic {
+	document := s.GetDocument(uri)
+	if document == nil {
+		logger.Warn("Save for document that is not open: ", uri)
+		return nil
+	}
+	var changes []lsp.TextDocumentContentChangeEvent
+	if text != nil {
+		changes = append(changes, lsp.TextDocumentContentChangeEvent{Text: *text})
+	}
+	document = document.Apply(changes, document.Version)
+	s.publish(document)
+	if path, ok := uri.Path(); ok && IsConfigFile(path) {
+		s.index.store(document)
+	}
+	return document.Diagnostics
 }
 
-// TextDocumentCompletion returns the completion items for the given document URI and position.
+// RefreshDocument analyses the document with the given URI again, e.g. after the settings
+// that decide which diagnostics are reported have changed.
 //
 // Parameters:
 //
-//	id int - The ID of the completion request.
 //	uri lsp.DocumentURI - The URI of the document.
-//	position lsp.Position - The position within the document.
 //
 // Returns:
 //
-//	lsp.CompletionResponse - The completion response.
-func (s *State) TextDocumentCompletion(id int, uri lsp.DocumentURI, position lsp.Position) lsp.CompletionResponse {
-	logger.Debug("Completion request for document with URI: ", uri)
-	items := GetCompletionItems(uri)
-	return lsp.NewCompletionResponse(id, items)
-}
-
-func (s *State) Formatting(id int, uri lsp.DocumentURI, options lsp.FormattingOptions) lsp.DocumentFormattingResponse {
-	// TODO: Implement formatting
-	// visitor := kamailio_cfg.NewFormattingVisitor()
-	// s.Analyzer.GetAST().Accept(visitor, s.Analyzer)
-	// edits := visitor.GetEdits()
-	// return lsp.NewDocumentFormattingResponse(id, edits)
-	return lsp.NewDocumentFormattingResponse(id, []lsp.TextEdit{})
+//	[]lsp.Diagnostic - The list of diagnostics.
+//	bool - Whether the document is open.
+func (s *State) RefreshDocument(uri lsp.DocumentURI) ([]lsp.Diagnostic, bool) {
+	document := s.GetDocument(uri)
+	if document == nil {
+		return nil, false
+	}
+	document = document.Apply(nil, document.Version)
+	s.publish(document)
+	return document.Diagnostics, true
 }
@@ -0,0 +1,131 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/lsp"
+	"regexp"
+	"sort"
+)
+
+// identifier_pattern matches the names a symbol may be renamed to.
+var identifier_pattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// documents returns the documents of the snapshot, starting with the document with the given URI.
+// The others follow in the order of their URIs, so that results do not depend on map order.
+//
+// Parameters:
+//
+//	first lsp.DocumentURI - The URI of the document to return first.
+//
+// Returns:
+//
+//	[]*Document - The documents of the snapshot.
+func (s *Snapshot) documents(first lsp.DocumentURI) []*Document {
+	documents := make([]*Document, 0, len(s.Documents))
+	for _, document := range s.Documents {
+		documents = append(documents, document)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].URI == first || documents[j].URI == first {
+			return documents[i].URI == first
+		}
+		return documents[i].URI < documents[j].URI
+	})
+	return documents
+}
+
+// declaration returns the location where the symbol is declared.
+// The document the symbol is used in is searched first, then the other documents and the files they include.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document the symbol is used in.
+//	symbol kamailio_cfg.Symbol - The symbol.
+//
+// Returns:
+//
+//	*lsp.Location - The location of the declaration, nil if it is not found.
+func (s *Snapshot) declaration(uri lsp.DocumentURI, symbol kamailio_cfg.Symbol) *lsp.Location {
+	for _, document := range s.workspace(uri) {
+		for _, candidate := range document.Symbols() {
+			if candidate.Declaration && candidate.Is(symbol) {
+				return &lsp.Location{URI: document.URI, Range: document.RangeOfSymbol(candidate)}
+			}
+		}
+	}
+	return nil
+}
+
+// references returns the locations of every occurrence of the symbol in the open documents
+// and the files they include.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document the symbol is used in.
+//	symbol kamailio_cfg.Symbol - The symbol.
+//	includeDeclaration bool - Whether the declarations of the symbol are included.
+//
+// Returns:
+//
+//	[]lsp.Location - The locations of the occurrences, those of the given document first.
+func (s *Snapshot) references(uri lsp.DocumentURI, symbol kamailio_cfg.Symbol, includeDeclaration bool) []lsp.Location {
+	var locations []lsp.Location
+	for _, document := range s.workspace(uri) {
+		for _, candidate := range document.Symbols() {
+			if candidate.Is(symbol) && (includeDeclaration || !candidate.Declaration) {
+				locations = append(locations, lsp.Location{URI: document.URI, Range: document.RangeOfSymbol(candidate)})
+			}
+		}
+	}
+	return locations
+}
+
+// isVariable reports whether the symbol is the name of a variable, which is never declared.
+func isVariable(symbol kamailio_cfg.Symbol) bool {
+	switch symbol.Kind {
+	case kamailio_cfg.SYMBOL_KIND_AVP, kamailio_cfg.SYMBOL_KIND_VAR, kamailio_cfg.SYMBOL_KIND_XAVP, kamailio_cfg.SYMBOL_KIND_HTABLE:
+		return true
+	}
+	return false
+}
+
+// renamable reports whether the symbol can be renamed.
+// Routes and defines can only be renamed if they are declared, an identifier
+// that is not declared as a define is not a symbol of the configuration.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document the symbol is used in.
+//	symbol kamailio_cfg.Symbol - The symbol.
+//
+// Returns:
+//
+//	bool - True if the symbol can be renamed.
+func (s *Snapshot) renamable(uri lsp.DocumentURI, symbol kamailio_cfg.Symbol) bool {
+	return isVariable(symbol) || s.declaration(uri, symbol) != nil
+}
+
+// collides reports whether renaming the symbol to the given name would merge it with another symbol:
+// a declared route of the same kind, a declared define or a variable of the same class.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the document the symbol is used in.
+//	symbol kamailio_cfg.Symbol - The symbol to rename.
+//	name string - The new name of the symbol.
+//
+// Returns:
+//
+//	bool - True if a symbol with the new name exists.
+func (s *Snapshot) collides(uri lsp.DocumentURI, symbol kamailio_cfg.Symbol, name string) bool {
+	renamed := symbol
+	renamed.Name = name
+	for _, document := range s.workspace(uri) {
+		for _, candidate := range document.Symbols() {
+			if candidate.Is(renamed) && (candidate.Declaration || isVariable(candidate)) {
+				return true
+			}
+		}
+	}
+	return false
+}
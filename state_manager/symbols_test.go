@@ -0,0 +1,142 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const routing_cfg = `request_route {
+	route(RELAY);
+	t_on_failure("MANAGE_FAILURE");
+	t_on_reply("MANAGE_FAILURE");
+}
+route[RELAY] {
+	exit;
+}
+failure_route[MANAGE_FAILURE] {
+	exit;
+}
+`
+
+func TestDefinition(t *testing.T) {
+	state := state_manager.NewState()
+	state.OpenDocument("file:///kamailio.cfg", routing_cfg, 1)
+	state.OpenDocument("file:///other.cfg", "route[OTHER] {\n\texit;\n}\n", 1)
+	state.OpenDocument("file:///main.cfg", "request_route {\n\troute(OTHER);\n}\n", 1)
+
+	for _, test := range []struct {
+		uri      lsp.DocumentURI
+		position lsp.Position
+		expected *lsp.Location
+	}{
+		{"file:///kamailio.cfg", lsp.Position{Line: 1, Character: 9}, &lsp.Location{URI: "file:///kamailio.cfg", Range: lsp.Range{Start: lsp.Position{Line: 5, Character: 6}, End: lsp.Position{Line: 5, Character: 11}}}},
+		{"file:///kamailio.cfg", lsp.Position{Line: 2, Character: 17}, &lsp.Location{URI: "file:///kamailio.cfg", Range: lsp.Range{Start: lsp.Position{Line: 8, Character: 14}, End: lsp.Position{Line: 8, Character: 28}}}},
+		// there is no onreply_route[MANAGE_FAILURE]
+		{"file:///kamailio.cfg", lsp.Position{Line: 3, Character: 17}, nil},
+		{"file:///kamailio.cfg", lsp.Position{Line: 6, Character: 2}, nil},
+		{"file:///main.cfg", lsp.Position{Line: 1, Character: 8}, &lsp.Location{URI: "file:///other.cfg", Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 6}, End: lsp.Position{Line: 0, Character: 11}}}},
+	} {
+		response := state.Snapshot().Definition(rpc.NewIntID(1), test.uri, test.position)
+		if (response.Result == nil) != (test.expected == nil) || (response.Result != nil && *response.Result != *test.expected) {
+			t.Fatalf("Definition at %v: expected: %v,\ngot: %v", test.position, test.expected, response.Result)
+		}
+	}
+}
+
+const references_cfg = `#!define FLT_NATS 5
+include_file "routing.cfg"
+modparam("htable", "htable", "users=>size=8;")
+request_route {
+	route(RELAY);
+	setflag(FLT_NATS);
+	$avp(s:caller) = $sht(users=>$fU);
+	xlog("caller: $avp(caller)\n");
+}
+`
+
+const included_cfg = `#!ifdef FLT_NATS
+#!endif
+route[RELAY] {
+	$var(caller) = $avp(caller);
+	route(RELAY);
+}
+`
+
+func TestReferences(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "routing.cfg"), []byte(included_cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	main := lsp.NewDocumentURI(filepath.Join(directory, "kamailio.cfg"))
+	included := lsp.NewDocumentURI(filepath.Join(directory, "routing.cfg"))
+	state := state_manager.NewState()
+	state.OpenDocument(main, references_cfg, 1)
+
+	at := func(uri lsp.DocumentURI, line int, character int, length int) lsp.Location {
+		return lsp.Location{URI: uri, Range: lsp.Range{
+			Start: lsp.Position{Line: line, Character: character},
+			End:   lsp.Position{Line: line, Character: character + length},
+		}}
+	}
+	for _, test := range []struct {
+		name               string
+		position           lsp.Position
+		includeDeclaration bool
+		expected           []lsp.Location
+	}{
+		{"route", lsp.Position{Line: 4, Character: 8}, true, []lsp.Location{at(main, 4, 7, 5), at(included, 2, 6, 5), at(included, 4, 7, 5)}},
+		{"route without declaration", lsp.Position{Line: 4, Character: 8}, false, []lsp.Location{at(main, 4, 7, 5), at(included, 4, 7, 5)}},
+		{"define", lsp.Position{Line: 5, Character: 10}, true, []lsp.Location{at(main, 0, 9, 8), at(main, 5, 9, 8), at(included, 0, 8, 8)}},
+		{"avp", lsp.Position{Line: 6, Character: 9}, false, []lsp.Location{at(main, 6, 8, 6), at(main, 7, 20, 6), at(included, 3, 21, 6)}},
+		{"htable", lsp.Position{Line: 6, Character: 24}, true, []lsp.Location{at(main, 2, 30, 5), at(main, 6, 23, 5)}},
+		{"nothing", lsp.Position{Line: 3, Character: 2}, true, []lsp.Location{}},
+	} {
+		response := state.Snapshot().References(rpc.NewIntID(1), main, test.position, test.includeDeclaration)
+		if !reflect.DeepEqual(response.Result, test.expected) {
+			t.Fatalf("%s: expected: %v,\ngot: %v", test.name, test.expected, response.Result)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	state := state_manager.NewState()
+	state.OpenDocument("file:///kamailio.cfg", routing_cfg+"route[FORWARD] {\n\texit;\n}\n", 1)
+	snapshot := state.Snapshot()
+
+	prepared := snapshot.PrepareRename(rpc.NewIntID(1), "file:///kamailio.cfg", lsp.Position{Line: 2, Character: 17})
+	if prepared.Result == nil || prepared.Result.Placeholder != "MANAGE_FAILURE" {
+		t.Fatalf("Expected the failure route to be renamable, got: %v", prepared.Result)
+	}
+	if prepared := snapshot.PrepareRename(rpc.NewIntID(2), "file:///kamailio.cfg", lsp.Position{Line: 6, Character: 2}); prepared.Result != nil {
+		t.Fatalf("Expected nothing to rename, got: %v", prepared.Result)
+	}
+
+	response, err := snapshot.Rename(rpc.NewIntID(3), "file:///kamailio.cfg", lsp.Position{Line: 8, Character: 20}, "ON_FAILURE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := response.Result.Changes["file:///kamailio.cfg"]
+	expected := []lsp.Range{
+		{Start: lsp.Position{Line: 2, Character: 15}, End: lsp.Position{Line: 2, Character: 29}},
+		{Start: lsp.Position{Line: 8, Character: 14}, End: lsp.Position{Line: 8, Character: 28}},
+	}
+	if len(edits) != len(expected) {
+		t.Fatalf("Expected %d edits, got: %v", len(expected), edits)
+	}
+	for i, edit := range edits {
+		if edit.Range != expected[i] || edit.NewText != "ON_FAILURE" {
+			t.Fatalf("Expected: %v,\ngot: %v", expected[i], edit)
+		}
+	}
+
+	for _, name := range []string{"FORWARD", "NOT-A-NAME", ""} {
+		if _, err := snapshot.Rename(rpc.NewIntID(4), "file:///kamailio.cfg", lsp.Position{Line: 1, Character: 9}, name); err == nil {
+			t.Fatalf("Expected renaming RELAY to %q to fail", name)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package state_manager
+
+import (
+	"KamaiZen/kamailio_cfg"
+	"KamaiZen/logger"
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// max_workspace_symbols bounds the number of symbols a workspace/symbol request returns.
+const max_workspace_symbols = 500
+
+// WorkspaceIndex keeps the symbols of every Kamailio configuration in the workspace folders,
+// so that symbols are found in files that are not open. Open documents are searched directly.
+type WorkspaceIndex struct {
+	mu    sync.RWMutex
+	files map[lsp.DocumentURI][]lsp.SymbolInformation
+	// the configurations stored or removed while Build walks the folders, nil when no build runs
+	changed map[lsp.DocumentURI]bool
+	builds  int
+}
+
+// NewWorkspaceIndex creates and returns a new empty WorkspaceIndex.
+func NewWorkspaceIndex() *WorkspaceIndex {
+	return &WorkspaceIndex{files: make(map[lsp.DocumentURI][]lsp.SymbolInformation)}
+}
+
+// IsConfigFile reports whether the file is a Kamailio configuration, going by its extension.
+//
+// Parameters:
+//
+//	path string - The path of the file.
+//
+// Returns:
+//
+//	bool - True for .cfg files.
+func IsConfigFile(path string) bool {
+	return filepath.Ext(path) == ".cfg"
+}
+
+// Build indexes every configuration in the given folders and their subfolders,
+// replacing what was indexed before. Hidden folders, such as .git, are skipped.
+// Configurations updated or removed while the folders are walked keep their newer state.
+//
+// Parameters:
+//
+//	ctx context.Context - The context of the indexing; it stops between two files once cancelled.
+//	folders []string - The paths of the workspace folders.
+//
+// Returns:
+//
+//	error - The error of the context if it was cancelled.
+func (w *WorkspaceIndex) Build(ctx context.Context, folders []string) error {
+	w.mu.Lock()
+	if w.builds == 0 {
+		w.changed = make(map[lsp.DocumentURI]bool)
+	}
+	w.builds++
+	w.mu.Unlock()
+
+	files, err := readConfigs(ctx, folders)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := w.changed
+	w.builds--
+	if w.builds == 0 {
+		w.changed = nil
+	}
+	if err != nil {
+		return err
+	}
+	for uri := range changed {
+		if symbols, found := w.files[uri]; found {
+			files[uri] = symbols
+		} else {
+			delete(files, uri)
+		}
+	}
+	w.files = files
+	logger.Infof("Indexed %d configuration files", len(files))
+	return nil
+}
+
+// readConfigs returns the symbols of every configuration in the given folders and their subfolders.
+func readConfigs(ctx context.Context, folders []string) (map[lsp.DocumentURI][]lsp.SymbolInformation, error) {
+	files := make(map[lsp.DocumentURI][]lsp.SymbolInformation)
+	for _, folder := range folders {
+		err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Debug("Skipping ", path, ": ", err)
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if entry.IsDir() {
+				if path != folder && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !IsConfigFile(path) {
+				return nil
+			}
+			text, err := os.ReadFile(path)
+			if err != nil {
+				logger.Error("Error reading configuration: ", err)
+				return nil
+			}
+			uri := lsp.NewDocumentURI(path)
+			files[uri] = workspaceSymbols(NewDocument(uri, string(text), 0))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Update indexes the configuration with the given URI from its text.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the configuration.
+//	text string - The text of the configuration.
+func (w *WorkspaceIndex) Update(uri lsp.DocumentURI, text string) {
+	w.store(NewDocument(uri, text, 0))
+}
+
+// store indexes the symbols of the document.
+func (w *WorkspaceIndex) store(document *Document) {
+	symbols := workspaceSymbols(document)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[document.URI] = symbols
+	if w.changed != nil {
+		w.changed[document.URI] = true
+	}
+}
+
+// Load indexes the configuration with the given URI from disk.
+// The configuration is removed from the index if it can't be read.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the configuration.
+func (w *WorkspaceIndex) Load(uri lsp.DocumentURI) {
+	path, ok := uri.Path()
+	if !ok {
+		return
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		logger.Debug("Removing unreadable configuration from the index: ", err)
+		w.Remove(uri)
+		return
+	}
+	w.Update(uri, string(text))
+}
+
+// Remove removes the configuration with the given URI from the index.
+//
+// Parameters:
+//
+//	uri lsp.DocumentURI - The URI of the configuration.
+func (w *WorkspaceIndex) Remove(uri lsp.DocumentURI) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.files, uri)
+	if w.changed != nil {
+		w.changed[uri] = true
+	}
+}
+
+// Search returns the symbols whose name matches the query, best matches first.
+// The open documents of the snapshot take the place of their indexed version.
+//
+// Parameters:
+//
+//	query string - The query, matched fuzzily, see fuzzyScore.
+//	snapshot *Snapshot - The snapshot holding the open documents.
+//
+// Returns:
+//
+//	[]lsp.SymbolInformation - The matching symbols, at most max_workspace_symbols.
+func (w *WorkspaceIndex) Search(query string, snapshot *Snapshot) []lsp.SymbolInformation {
+	type match struct {
+		symbol lsp.SymbolInformation
+		score  int
+	}
+	var matches []match
+	add := func(symbols []lsp.SymbolInformation) {
+		for _, symbol := range symbols {
+			if score, ok := fuzzyScore(query, symbol.Name); ok {
+				matches = append(matches, match{symbol, score})
+			}
+		}
+	}
+	for _, document := range snapshot.Documents {
+		add(workspaceSymbols(document))
+	}
+	w.mu.RLock()
+	for uri, symbols := range w.files {
+		if snapshot.GetDocument(uri) == nil {
+			add(symbols)
+		}
+	}
+	w.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.symbol.Name != b.symbol.Name {
+			return a.symbol.Name < b.symbol.Name
+		}
+		if a.symbol.Location.URI != b.symbol.Location.URI {
+			return a.symbol.Location.URI < b.symbol.Location.URI
+		}
+		return a.symbol.Location.Range.Start.Line < b.symbol.Location.Range.Start.Line
+	})
+	if len(matches) > max_workspace_symbols {
+		matches = matches[:max_workspace_symbols]
+	}
+	symbols := make([]lsp.SymbolInformation, len(matches))
+	for i, match := range matches {
+		symbols[i] = match.symbol
+	}
+	return symbols
+}
+
+// WorkspaceSymbol searches the symbols of the open documents and of the indexed configurations.
+//
+// Parameters:
+//
+//	id rpc.ID - The ID of the workspace/symbol request.
+//	query string - The query.
+//
+// Returns:
+//
+//	lsp.WorkspaceSymbolResponse - The symbols that match the query.
+func (s *State) WorkspaceSymbol(id rpc.ID, query string) lsp.WorkspaceSymbolResponse {
+	return lsp.NewWorkspaceSymbolResponse(id, s.index.Search(query, s.Snapshot()))
+}
+
+// workspaceSymbols returns the symbols of the document that workspace/symbol searches:
+// routes, defines, module parameters and htables.
+func workspaceSymbols(document *Document) []lsp.SymbolInformation {
+	var symbols []lsp.SymbolInformation
+	for _, symbol := range document.Outline() {
+		switch symbol.Kind {
+		case lsp.FUNCTION_SYMBOL, lsp.EVENT_SYMBOL, lsp.CONSTANT_SYMBOL:
+			symbols = append(symbols, lsp.SymbolInformation{
+				Name:     symbol.Name,
+				Kind:     symbol.Kind,
+				Location: lsp.Location{URI: document.URI, Range: symbol.SelectionRange},
+			})
+		case lsp.MODULE_SYMBOL:
+			for _, parameter := range symbol.Children {
+				symbols = append(symbols, lsp.SymbolInformation{
+					Name:          parameter.Name,
+					Kind:          parameter.Kind,
+					Location:      lsp.Location{URI: document.URI, Range: parameter.SelectionRange},
+					ContainerName: symbol.Name,
+				})
+			}
+		}
+	}
+	for _, symbol := range document.Symbols() {
+		if symbol.Declaration && symbol.Kind == kamailio_cfg.SYMBOL_KIND_HTABLE {
+			symbols = append(symbols, lsp.SymbolInformation{
+				Name:     symbol.Name,
+				Kind:     lsp.OBJECT_SYMBOL,
+				Location: lsp.Location{URI: document.URI, Range: document.RangeOfSymbol(symbol)},
+			})
+		}
+	}
+	return symbols
+}
+
+// fuzzyScore matches the query against a name, ignoring case.
+// A name matches if it contains the characters of the query in order;
+// names equal to, starting with or containing the query score higher.
+//
+// Parameters:
+//
+//	query string - The query, an empty query matches every name.
+//	name string - The name of a symbol.
+//
+// Returns:
+//
+//	int - The score of the match, higher is better.
+//	bool - False if the name does not match.
+func fuzzyScore(query string, name string) (int, bool) {
+	query = strings.ToLower(query)
+	name = strings.ToLower(name)
+	switch {
+	case query == name:
+		return 4, true
+	case strings.HasPrefix(name, query):
+		return 3, true
+	case strings.Contains(name, query):
+		return 2, true
+	}
+	rest := name
+	for _, r := range query {
+		i := strings.IndexRune(rest, r)
+		if i < 0 {
+			return 0, false
+		}
+		rest = rest[i+len(string(r)):]
+	}
+	return 1, true
+}
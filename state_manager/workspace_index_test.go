@@ -0,0 +1,68 @@
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceSymbol(t *testing.T) {
+	folder := t.TempDir()
+	files := map[string]string{
+		"kamailio.cfg":        "include_file \"defines.cfg\"\nmodparam(\"htable\", \"htable\", \"ipban=>size=8;\")\nmodparam(\"tm\", \"fr_timer\", 30000)\n",
+		"defines.cfg":         "#!define FLT_NATS 5\n",
+		"routing/relay.cfg":   "route[RELAY] {\n\texit;\n}\n",
+		".git/ignored.cfg":    "route[IGNORED] {\n\texit;\n}\n",
+		"routing/notes.txt":   "route[NOTES] {\n\texit;\n}\n",
+		"routing/failure.cfg": "failure_route[MANAGE_FAILURE] {\n\texit;\n}\n",
+	}
+	for name, text := range files {
+		path := filepath.Join(folder, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := state_manager.NewState()
+	if err := state.Index().Build(context.Background(), []string{folder}); err != nil {
+		t.Fatal(err)
+	}
+	search := func(query string) []string {
+		var names []string
+		for _, symbol := range state.WorkspaceSymbol(rpc.NewIntID(1), query).Result {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+	expect := func(query string, expected ...string) {
+		t.Helper()
+		actual := search(query)
+		if len(actual) != len(expected) {
+			t.Fatalf("%q: expected: %v,\ngot: %v", query, expected, actual)
+		}
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Fatalf("%q: expected: %v,\ngot: %v", query, expected, actual)
+			}
+		}
+	}
+
+	expect("", "FLT_NATS", "failure_route[MANAGE_FAILURE]", "fr_timer", "htable", "ipban", "route[RELAY]")
+	expect("rly", "route[RELAY]")
+	expect("fr", "fr_timer", "failure_route[MANAGE_FAILURE]")
+
+	// open documents replace their indexed version
+	relay := lsp.NewDocumentURI(filepath.Join(folder, "routing", "relay.cfg"))
+	state.OpenDocument(relay, "route[FORWARD] {\n\texit;\n}\n", 1)
+	expect("relay")
+	expect("forward", "route[FORWARD]")
+
+	state.Index().Remove(lsp.NewDocumentURI(filepath.Join(folder, "defines.cfg")))
+	expect("nats")
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package state_manager_test
+
+import (
+	"KamaiZen/lsp"
+	"KamaiZen/rpc"
+	"KamaiZen/state_manager"
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWorkspaceIndexKeepsChangesDuringBuild(t *testing.T) {
+	folder := t.TempDir()
+	for name, text := range map[string]string{
+		"b.cfg": "route[OLD] {\n\texit;\n}\n",
+		"c.cfg": "route[REMOVED] {\n\texit;\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the build blocks on reading the fifo, which comes first, until it is written
+	fifo := filepath.Join(folder, "a.cfg")
+	if err := syscall.Mkfifo(fifo, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state := state_manager.NewState()
+	built := make(chan error)
+	go func() { built <- state.Index().Build(context.Background(), []string{folder}) }()
+	writer, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state.Index().Update(lsp.NewDocumentURI(filepath.Join(folder, "b.cfg")), "route[NEW] {\n\texit;\n}\n")
+	state.Index().Remove(lsp.NewDocumentURI(filepath.Join(folder, "c.cfg")))
+	if _, err := writer.WriteString("route[FIFO] {\n\texit;\n}\n"); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	if err := <-built; err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, symbol := range state.WorkspaceSymbol(rpc.NewIntID(1), "").Result {
+		names = append(names, symbol.Name)
+	}
+	if len(names) != 2 || names[0] != "route[FIFO]" || names[1] != "route[NEW]" {
+		t.Fatalf("Expected: [route[FIFO] route[NEW]],\ngot: %v", names)
+	}
+}